@@ -2,13 +2,17 @@ package account
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/arosace/WellnessWaveApi/internal/account/domain"
 	"github.com/arosace/WellnessWaveApi/internal/account/handler"
 	"github.com/arosace/WellnessWaveApi/internal/account/repository"
 	"github.com/arosace/WellnessWaveApi/internal/account/service"
+	auditHandler "github.com/arosace/WellnessWaveApi/internal/audit/handler"
+	auditService "github.com/arosace/WellnessWaveApi/internal/audit/service"
 	eventDomain "github.com/arosace/WellnessWaveApi/internal/event/domain"
+	organisationRepository "github.com/arosace/WellnessWaveApi/internal/organisation/repository"
 	"github.com/arosace/WellnessWaveApi/pkg/utils"
 	encryption "github.com/arosace/WellnessWaveApi/pkg/utils"
 	"github.com/labstack/echo/v5"
@@ -22,18 +26,29 @@ import (
 
 type AccountService struct {
 	App                  *pocketbase.PocketBase
-	Encryptor            *encryption.Encryptor
+	Encryptor            encryption.Encryption
+	PasswordHasher       utils.PasswordHasher
 	ServiceHandler       *handler.AccountHandler
 	RepositoryInteractor *repository.AccountRepo
 	Mailer               mailer.Mailer
 	Dao                  *daos.Dao
+	Policies             *handler.AccountPolicies
+	Auditor              auditService.AuditService
+	AuditTrail           *auditHandler.AuditTrail
+	// RequireSpecialistTwoFactor makes two factor authentication mandatory for health specialists
+	RequireSpecialistTwoFactor bool
 }
 
 func (s AccountService) Init() {
 	// Initialize all repositories
-	accountRepo := repository.NewAccountRepository(s.Dao)
+	accountRepo := repository.NewAccountRepository(s.Dao, s.Encryptor)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(s.Dao)
+	accountTokenRepo := repository.NewAccountTokenRepository(s.Dao)
+	careTeamRepo := repository.NewCareTeamRepository(s.Dao)
+	attachRequestRepo := repository.NewAttachRequestRepository(s.Dao)
+	organisationRepo := organisationRepository.NewOrganisationRepository(s.Dao)
 	// Initialize all services with their respective repositories
-	accountService := service.NewAccountService(accountRepo, s.Encryptor)
+	accountService := service.NewAccountService(accountRepo, refreshTokenRepo, accountTokenRepo, careTeamRepo, attachRequestRepo, organisationRepo, s.Encryptor, s.PasswordHasher, s.Mailer, s.Auditor, s.RequireSpecialistTwoFactor)
 	// Initialize all handlers with their respective services
 	accountServiceHandler := handler.NewAccountHandler(accountService)
 	s.ServiceHandler = accountServiceHandler
@@ -43,60 +58,236 @@ func (s AccountService) Init() {
 }
 
 func (s AccountService) RegisterEndpoints() {
+	// audit targets, public routes do not know which account they act on until the handler has run
+	accounts := auditHandler.Target{Collection: domain.TableName}
+	account := func(id utils.ParamExtractor) auditHandler.Target {
+		return auditHandler.Target{Collection: domain.TableName, RecordID: id, PatientID: s.Policies.PatientAccount(id)}
+	}
+	attachRequests := auditHandler.Target{Collection: domain.AttachRequestsTableName}
+	attachRequest := auditHandler.Target{Collection: domain.AttachRequestsTableName, RecordID: utils.PathParam("requestId")}
+
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/accounts", s.ServiceHandler.HandleGetAccounts, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.list", accounts),
+			utils.Authorize(utils.HasRole(domain.HealthSpecialistRole)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/register", s.ServiceHandler.HandleAddAccount, utils.EchoMiddleware,
+			s.AuditTrail.Audit("accounts.register", accounts))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.PUT("/v1/accounts/verify", s.ServiceHandler.HandleVerifyAccount, utils.EchoMiddleware,
+			s.AuditTrail.Audit("accounts.verify", accounts))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/accounts/:id", s.ServiceHandler.HandleGetAccountsById, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.read", account(utils.PathParam("id"))),
+			utils.Authorize(utils.AnyOf(
+				s.Policies.SelfOrCareTeamMember(utils.PathParam("id")),
+				s.Policies.CareTeamSpecialist(utils.PathParam("id")),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/attach", s.ServiceHandler.HandleAttachAccount, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.attach", accounts),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(domain.HealthSpecialistRole),
+				utils.IsSelf(utils.BodyField("parent_id")),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/accounts/:id/care-team", s.ServiceHandler.HandleGetCareTeam, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.read_care_team", account(utils.PathParam("id"))),
+			utils.Authorize(s.Policies.SelfOrCareTeamMember(utils.PathParam("id"))))
+		return nil
+	})
+	// specialists join an existing care team when the patient or their primary specialist adds them
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/:id/care-team", s.ServiceHandler.HandleAddCareTeamMember, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.add_care_team_member", account(utils.PathParam("id"))),
+			utils.Authorize(s.Policies.SelfOrCareTeamMember(utils.PathParam("id"), domain.PrimaryCareRole)))
+		return nil
+	})
+	// patients ask a health specialist, usually found in the directory, to follow them
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/:id/attach-requests", s.ServiceHandler.HandleRequestAttach, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.request_attach", account(utils.PathParam("id"))),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(domain.PatientRole),
+				utils.IsSelf(utils.PathParam("id")),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/accounts/:id/attach-requests", s.ServiceHandler.HandleGetPendingAttachRequests, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.list_attach_requests", attachRequests),
+			utils.Authorize(utils.IsSelf(utils.PathParam("id"))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/:id/attach-requests/:requestId/accept", s.ServiceHandler.HandleAcceptAttachRequest, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.accept_attach_request", attachRequest),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(domain.HealthSpecialistRole),
+				utils.IsSelf(utils.PathParam("id")),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/:id/attach-requests/:requestId/decline", s.ServiceHandler.HandleDeclineAttachRequest, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.decline_attach_request", attachRequest),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(domain.HealthSpecialistRole),
+				utils.IsSelf(utils.PathParam("id")),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/:id/attach-requests/:requestId/cancel", s.ServiceHandler.HandleCancelAttachRequest, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.cancel_attach_request", attachRequest),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(domain.PatientRole),
+				utils.IsSelf(utils.PathParam("id")),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/accounts/attached/:parent_id", s.ServiceHandler.HandleGetAttachedAccounts, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.list_attached", account(utils.PathParam("parent_id"))),
+			utils.Authorize(utils.IsSelf(utils.PathParam("parent_id"))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/accounts/attached/:parent_id/pending-deletion", s.ServiceHandler.HandleGetPatientsPendingDeletion, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.list_pending_deletion", account(utils.PathParam("parent_id"))),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(domain.HealthSpecialistRole),
+				utils.IsSelf(utils.PathParam("parent_id")),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.DELETE("/v1/accounts/:id", s.ServiceHandler.HandleDeleteAccount, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.delete", account(utils.PathParam("id"))),
+			utils.Authorize(utils.IsSelf(utils.PathParam("id"))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/deletion/cancel", s.ServiceHandler.HandleCancelDeletion, utils.EchoMiddleware,
+			s.AuditTrail.Audit("accounts.cancel_deletion", accounts))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.PUT("/v1/accounts/update", s.ServiceHandler.HandleUpdateAccount, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.update", account(utils.BodyField("id"))),
+			utils.Authorize(utils.IsSelf(utils.BodyField("id"))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/login", s.ServiceHandler.HandleLogIn, utils.EchoMiddleware,
+			s.AuditTrail.Audit("accounts.login", accounts))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/token/refresh", s.ServiceHandler.HandleRefreshToken, utils.EchoMiddleware,
+			s.AuditTrail.Audit("accounts.refresh_session", accounts))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/logout", s.ServiceHandler.HandleLogout, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.logout", account(utils.AuthAccount())))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/logout/all", s.ServiceHandler.HandleLogoutAllDevices, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.logout_all", account(utils.AuthAccount())))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/password/forgot", s.ServiceHandler.HandleForgotPassword, utils.EchoMiddleware,
+			s.AuditTrail.Audit("accounts.forgot_password", accounts))
+		return nil
+	})
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.GET("/v1/accounts", s.ServiceHandler.HandleGetAccounts, utils.EchoMiddleware)
+		e.Router.POST("/v1/accounts/password/reset", s.ServiceHandler.HandleResetPassword, utils.EchoMiddleware,
+			s.AuditTrail.Audit("accounts.reset_password", accounts))
 		return nil
 	})
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.POST("/v1/accounts/register", s.ServiceHandler.HandleAddAccount, utils.EchoMiddleware)
+		e.Router.POST("/v1/accounts/invite/accept", s.ServiceHandler.HandleAcceptInvite, utils.EchoMiddleware,
+			s.AuditTrail.Audit("accounts.accept_invite", accounts))
 		return nil
 	})
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.PUT("/v1/accounts/verify", s.ServiceHandler.HandleVerifyAccount, utils.EchoMiddleware)
+		e.Router.POST("/v1/accounts/unlock", s.ServiceHandler.HandleUnlockAccount, utils.EchoMiddleware,
+			s.AuditTrail.Audit("accounts.unlock", accounts))
 		return nil
 	})
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.GET("/v1/accounts/:id", s.ServiceHandler.HandleGetAccountsById, utils.EchoMiddleware)
+		e.Router.POST("/v1/accounts/login/2fa", s.ServiceHandler.HandleTwoFactorLogIn, utils.EchoMiddleware,
+			s.AuditTrail.Audit("accounts.login_2fa", accounts))
 		return nil
 	})
+	// enrollment also accepts the enrollment token of accounts required to enable two factor authentication at log in
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.POST("/v1/accounts/attach", s.ServiceHandler.HandleAttachAccount, utils.EchoMiddleware)
+		e.Router.POST("/v1/accounts/2fa/enroll", s.ServiceHandler.HandleEnrollTwoFactor, utils.EchoMiddleware, utils.EnrollmentAuthMiddleware,
+			s.AuditTrail.Audit("accounts.enroll_2fa", account(utils.AuthAccount())))
 		return nil
 	})
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.GET("/v1/accounts/attached/:parent_id", s.ServiceHandler.HandleGetAttachedAccounts, utils.EchoMiddleware)
+		e.Router.POST("/v1/accounts/2fa/confirm", s.ServiceHandler.HandleConfirmTwoFactor, utils.EchoMiddleware, utils.EnrollmentAuthMiddleware,
+			s.AuditTrail.Audit("accounts.confirm_2fa", account(utils.AuthAccount())))
 		return nil
 	})
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.PUT("/v1/accounts/update", s.ServiceHandler.HandleUpdateAccount, utils.EchoMiddleware)
+		e.Router.POST("/v1/accounts/2fa/recovery-codes", s.ServiceHandler.HandleRegenerateRecoveryCodes, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.regenerate_recovery_codes", account(utils.AuthAccount())))
 		return nil
 	})
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.POST("/v1/accounts/login", s.ServiceHandler.HandleLogIn, utils.EchoMiddleware)
+		e.Router.POST("/v1/accounts/2fa/disable", s.ServiceHandler.HandleDisableTwoFactor, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.disable_2fa", account(utils.AuthAccount())))
 		return nil
 	})
 }
 
+// ReencryptAccounts rewrites the encrypted account fields still written with a retired key.
+func (s AccountService) ReencryptAccounts() (int, error) {
+	reencryptionService := service.NewReencryptionService(repository.NewAccountRepository(s.Dao, s.Encryptor), s.Encryptor)
+	return reencryptionService.ReencryptAccounts(nil)
+}
+
 func (s AccountService) RegisterHooks() {
+	// accounts attached before care teams existed hold their specialist in parent_id, it becomes their primary care team member
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		migrated, err := repository.NewCareTeamRepository(s.Dao).MigrateParentLinks(nil)
+		if err != nil {
+			return fmt.Errorf("Failed to migrate parent links to care teams after %d accounts: %w", migrated, err)
+		}
+		if migrated > 0 {
+			log.Printf("Migrated the parent link of %d accounts to care teams", migrated)
+		}
+		return nil
+	})
+
 	// listens for changes to the "accounts" table and acts accordingly (sends an email to the newly created account)
 	s.App.OnModelAfterCreate(domain.TableName).Add(func(e *core.ModelEvent) error {
 		record := e.Model.(*models.Record)
 		switch record.GetString("role") {
-		case domain.HealthSpecialistRole:
-			if err := utils.SendVerifyAccountHealthSpecialistEmail(
-				s.Mailer,
-				record.GetString("username"),
-				record.GetString("email"),
-			); err != nil {
-				return apis.NewApiError(http.StatusBadRequest, fmt.Sprintf("Failed to send email:%s", err.Error()), err)
+		case domain.HealthSpecialistRole, domain.PatientRole:
+			// patients invited by a health specialist receive an invitation from the account service instead
+			if record.GetString("invited_by") != "" {
+				return nil
 			}
-		case domain.PatientRole:
-			if err := utils.SendVerifyAccountPatientEmail(
+			if err := utils.SendVerifyAccountEmail(
 				s.Mailer,
 				record.GetString("username"),
 				record.GetString("email"),
-				record.GetString("encrypted_password"),
-				record.Id,
 			); err != nil {
 				return apis.NewApiError(http.StatusBadRequest, fmt.Sprintf("Failed to send email:%s", err.Error()), err)
 			}
@@ -127,9 +318,13 @@ func (s AccountService) RegisterHooks() {
 		return nil
 	})
 
-	// listens for changes to the "events" table and acts accordingly (sends reminder email to patient about call)
+	// listens for changes to the "events" table and acts accordingly (emails the participant who did not create the event)
 	s.App.OnModelAfterCreate(eventDomain.TABLENAME).Add(func(e *core.ModelEvent) error {
 		event := e.Model.(*models.Record)
+		// occurrences detached or split from a series were already announced, their change is emailed once made
+		if event.GetString("series_id") != "" {
+			return nil
+		}
 		ctx := &echo.DefaultContext{}
 		patient, err := s.RepositoryInteractor.FindByID(ctx, event.GetString("patient_id"))
 		if err != nil {
@@ -139,6 +334,26 @@ func (s AccountService) RegisterHooks() {
 			return apis.NewApiError(http.StatusBadRequest, fmt.Sprintf("there was an error verifyin that patient id exists:%s", err.Error()), err)
 		}
 
+		// events booked by the patient are for the health specialist to know about, or to approve
+		if event.GetString("status_changed_by") == patient.Id {
+			specialist, err := s.RepositoryInteractor.FindByID(ctx, event.GetString("health_specialist_id"))
+			if err != nil {
+				return apis.NewApiError(http.StatusBadRequest, fmt.Sprintf("there was an error finding the health specialist of the event:%s", err.Error()), err)
+			}
+			err = utils.SendEventBookedEmail(
+				s.Mailer,
+				specialist.GetString("username"),
+				specialist.Email(),
+				patient.GetString("username"),
+				event,
+				event.GetString("status") == eventDomain.RequestedStatus,
+			)
+			if err != nil {
+				return apis.NewApiError(http.StatusBadRequest, fmt.Sprintf("Failed to send email:%s", err.Error()), err)
+			}
+			return nil
+		}
+
 		err = utils.SendEventEmailToPatient(
 			s.Mailer,
 			patient.GetString("username"),
@@ -151,9 +366,20 @@ func (s AccountService) RegisterHooks() {
 		return nil
 	})
 
-	// listens for updates to the "events" table and acts accordingly (sends reminder email to patient about call)
+	// listens for updates to the "events" table and acts accordingly (emails the change of status or date of the event)
 	s.App.OnModelAfterUpdate(eventDomain.TABLENAME).Add(func(e *core.ModelEvent) error {
 		event := e.Model.(*models.Record)
+		original := event.OriginalCopy()
+		statusChanged := event.GetString("status") != original.GetString("status")
+		rescheduled := !event.GetDateTime("event_date").Time().Equal(original.GetDateTime("event_date").Time())
+		if !statusChanged && !rescheduled {
+			return nil
+		}
+		// completing the event changes nothing for either participant
+		if statusChanged && event.GetString("status") == eventDomain.CompletedStatus {
+			return nil
+		}
+
 		ctx := &echo.DefaultContext{}
 		patient, err := s.RepositoryInteractor.FindByID(ctx, event.GetString("patient_id"))
 		if err != nil {
@@ -163,12 +389,37 @@ func (s AccountService) RegisterHooks() {
 			return apis.NewApiError(http.StatusBadRequest, fmt.Sprintf("there was an error verifyin that patient id exists:%s", err.Error()), err)
 		}
 
-		err = utils.SendRescheduleEventEmailToPatient(
-			s.Mailer,
-			patient.GetString("username"),
-			patient.Email(),
-			event,
-		)
+		switch {
+		case statusChanged && event.GetString("status_changed_by") == patient.Id:
+			// patients can only cancel their events
+			specialist, err := s.RepositoryInteractor.FindByID(ctx, event.GetString("health_specialist_id"))
+			if err != nil {
+				return apis.NewApiError(http.StatusBadRequest, fmt.Sprintf("there was an error finding the health specialist of the event:%s", err.Error()), err)
+			}
+			err = utils.SendEventCancelledByPatientEmail(
+				s.Mailer,
+				specialist.GetString("username"),
+				specialist.Email(),
+				patient.GetString("username"),
+				event,
+				event.GetBool("late_cancellation"),
+			)
+		case statusChanged:
+			err = utils.SendEventStatusEmail(
+				s.Mailer,
+				patient.GetString("username"),
+				patient.Email(),
+				event,
+				event.GetString("status"),
+			)
+		default:
+			err = utils.SendRescheduleEventEmailToPatient(
+				s.Mailer,
+				patient.GetString("username"),
+				patient.Email(),
+				event,
+			)
+		}
 		if err != nil {
 			return apis.NewApiError(http.StatusBadRequest, fmt.Sprintf("Failed to send email:%s", err.Error()), err)
 		}
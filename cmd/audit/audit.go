@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"errors"
+
+	"github.com/arosace/WellnessWaveApi/internal/audit/domain"
+	"github.com/arosace/WellnessWaveApi/internal/audit/handler"
+	"github.com/arosace/WellnessWaveApi/internal/audit/service"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+type AuditService struct {
+	App            *pocketbase.PocketBase
+	Dao            *daos.Dao
+	Auditor        service.AuditService
+	ServiceHandler *handler.AuditHandler
+}
+
+func (s AuditService) Init() {
+	s.ServiceHandler = handler.NewAuditHandler(s.Auditor)
+	s.RegisterEndpoints()
+	s.RegisterHooks()
+}
+
+// RegisterEndpoints exposes the audit log to PocketBase admins only.
+func (s AuditService) RegisterEndpoints() {
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/audit", s.ServiceHandler.HandleGetAuditLog, utils.EchoMiddleware, apis.RequireAdminAuth())
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/audit/verify", s.ServiceHandler.HandleVerifyAuditLog, utils.EchoMiddleware, apis.RequireAdminAuth())
+		return nil
+	})
+}
+
+func (s AuditService) RegisterHooks() {
+	// the audit log is append only, entries cannot be changed or removed, not even from the admin dashboard
+	s.App.OnModelBeforeUpdate(domain.TableName).Add(func(e *core.ModelEvent) error {
+		return errors.New("audit entries cannot be updated")
+	})
+	s.App.OnModelBeforeDelete(domain.TableName).Add(func(e *core.ModelEvent) error {
+		return errors.New("audit entries cannot be deleted")
+	})
+}
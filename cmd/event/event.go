@@ -1,6 +1,13 @@
 package event
 
 import (
+	"fmt"
+	"log"
+
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	accountHandler "github.com/arosace/WellnessWaveApi/internal/account/handler"
+	auditHandler "github.com/arosace/WellnessWaveApi/internal/audit/handler"
+	"github.com/arosace/WellnessWaveApi/internal/event/domain"
 	"github.com/arosace/WellnessWaveApi/internal/event/handler"
 	"github.com/arosace/WellnessWaveApi/internal/event/repository"
 	"github.com/arosace/WellnessWaveApi/internal/event/service"
@@ -13,33 +20,194 @@ import (
 type EventService struct {
 	App            *pocketbase.PocketBase
 	Dao            *daos.Dao
+	Encryptor      utils.Encryption
 	ServiceHandler *handler.EventHandler
+	Policies       *accountHandler.AccountPolicies
+	EventPolicies  *handler.EventPolicies
+	AuditTrail     *auditHandler.AuditTrail
 }
 
 func (s EventService) Init() {
-	eventRepo := repository.NewEventRepository(s.Dao)
-	eventService := service.NewEventService(eventRepo)
+	eventRepo := repository.NewEventRepository(s.Dao, s.Encryptor)
+	availabilityRepo := repository.NewAvailabilityRepository(s.Dao)
+	eventService := service.NewEventService(eventRepo, availabilityRepo)
 	accountServiceHandler := handler.NewEventHandler(eventService)
 	s.ServiceHandler = accountServiceHandler
+	s.EventPolicies = handler.NewEventPolicies(eventService)
 	s.RegisterEndpoints()
 	s.RegisterHooks()
 }
 
+// ReencryptEvents rewrites the encrypted event fields with the active encryption key.
+func (s EventService) ReencryptEvents() (int, error) {
+	return repository.NewEventRepository(s.Dao, s.Encryptor).Reencrypt(nil)
+}
+
 func (s EventService) RegisterEndpoints() {
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.POST("/v1/events/schedule", s.ServiceHandler.HandleScheduleEvent, utils.EchoMiddleware)
+		e.Router.POST("/v1/events/schedule", s.ServiceHandler.HandleScheduleEvent, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.schedule", auditHandler.Target{Collection: domain.TABLENAME, PatientID: utils.BodyField("patient_id")}),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(accountDomain.HealthSpecialistRole),
+				utils.IsSelf(utils.BodyField("health_specialist_id")),
+				s.Policies.CareTeamMemberOf(utils.BodyField("patient_id")),
+			)))
 		return nil
 	})
 
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.PUT("/v1/events/reschedule", s.ServiceHandler.HandleRescheduleEvent, utils.EchoMiddleware)
+		e.Router.PUT("/v1/events/reschedule", s.ServiceHandler.HandleRescheduleEvent, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.reschedule", auditHandler.Target{Collection: domain.TABLENAME, RecordID: utils.BodyField("event_id"), PatientID: s.EventPolicies.Patient(utils.BodyField("event_id"))}),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(accountDomain.HealthSpecialistRole),
+				utils.IsSelf(s.EventPolicies.Organiser(utils.BodyField("event_id"))),
+			)))
 		return nil
 	})
 
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.GET("/v1/events", s.ServiceHandler.HandleGetEvents, utils.EchoMiddleware)
+		e.Router.GET("/v1/events", s.ServiceHandler.HandleGetEvents, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.list", auditHandler.Target{Collection: domain.TABLENAME, PatientID: utils.QueryParam("patientId")}),
+			utils.Authorize(utils.AllOf(
+				// organisation admins see the schedule of every health specialist of their organisation
+				utils.IfPresent(utils.QueryParam("healthSpecialistId"), utils.AnyOf(
+					utils.IsSelf(utils.QueryParam("healthSpecialistId")),
+					s.Policies.OrganisationAdminOf(utils.QueryParam("healthSpecialistId")),
+				)),
+				utils.IfPresent(utils.QueryParam("patientId"), s.Policies.SelfOrCareTeamMember(utils.QueryParam("patientId"))),
+			)))
+		return nil
+	})
+	// patients book the free slots of the specialists of their care team, who confirm or decline the bookings needing approval
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/events/book", s.ServiceHandler.HandleBookEvent, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.book", auditHandler.Target{Collection: domain.TABLENAME, PatientID: utils.AuthAccount()}),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(accountDomain.PatientRole),
+				s.Policies.CareTeamSpecialist(utils.BodyField("health_specialist_id")),
+			)))
+		return nil
+	})
+	booked := auditHandler.Target{Collection: domain.TABLENAME, RecordID: utils.PathParam("id"), PatientID: s.EventPolicies.Patient(utils.PathParam("id"))}
+	organiser := utils.AllOf(
+		utils.HasRole(accountDomain.HealthSpecialistRole),
+		utils.IsSelf(s.EventPolicies.Organiser(utils.PathParam("id"))),
+	)
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/events/:id/confirm", s.ServiceHandler.HandleConfirmEvent, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.confirm", booked),
+			utils.Authorize(organiser))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/events/:id/decline", s.ServiceHandler.HandleDeclineEvent, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.decline", booked),
+			utils.Authorize(organiser))
+		return nil
+	})
+	// either participant can cancel the event
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/events/:id/cancel", s.ServiceHandler.HandleCancelEvent, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.cancel", booked),
+			utils.Authorize(utils.AnyOf(
+				utils.IsSelf(s.EventPolicies.Organiser(utils.PathParam("id"))),
+				utils.IsSelf(s.EventPolicies.Patient(utils.PathParam("id"))),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/events/:id/complete", s.ServiceHandler.HandleCompleteEvent, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.complete", booked),
+			utils.Authorize(organiser))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/events/:id/no-show", s.ServiceHandler.HandleNoShowEvent, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.no_show", booked),
+			utils.Authorize(organiser))
+		return nil
+	})
+	availability := auditHandler.Target{Collection: domain.AvailabilityTableName, RecordID: utils.PathParam("healthSpecialistId")}
+	exceptions := auditHandler.Target{Collection: domain.AvailabilityExceptionsTableName}
+	// patients see the free slots of the specialists of their care team, colleagues and admins the ones of their organisation
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/events/availability", s.ServiceHandler.HandleGetFreeSlots, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.list_free_slots", auditHandler.Target{Collection: domain.AvailabilityTableName}),
+			utils.Authorize(utils.AnyOf(
+				utils.IsSelf(utils.QueryParam("healthSpecialistId")),
+				s.Policies.CareTeamSpecialist(utils.QueryParam("healthSpecialistId")),
+				s.Policies.OrganisationColleague(utils.QueryParam("healthSpecialistId")),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/events/availability/:healthSpecialistId", s.ServiceHandler.HandleGetAvailability, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.read_availability", availability),
+			utils.Authorize(utils.AnyOf(
+				utils.IsSelf(utils.PathParam("healthSpecialistId")),
+				s.Policies.OrganisationAdminOf(utils.PathParam("healthSpecialistId")),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.PUT("/v1/events/availability/:healthSpecialistId", s.ServiceHandler.HandleSetAvailability, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.set_availability", availability),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(accountDomain.HealthSpecialistRole),
+				utils.IsSelf(utils.PathParam("healthSpecialistId")),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/events/availability/:healthSpecialistId/exceptions", s.ServiceHandler.HandleGetAvailabilityExceptions, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.list_availability_exceptions", exceptions),
+			utils.Authorize(utils.AnyOf(
+				utils.IsSelf(utils.PathParam("healthSpecialistId")),
+				s.Policies.OrganisationAdminOf(utils.PathParam("healthSpecialistId")),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/events/availability/:healthSpecialistId/exceptions", s.ServiceHandler.HandleAddAvailabilityException, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.add_availability_exception", exceptions),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(accountDomain.HealthSpecialistRole),
+				utils.IsSelf(utils.PathParam("healthSpecialistId")),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.DELETE("/v1/events/availability/:healthSpecialistId/exceptions/:exceptionId", s.ServiceHandler.HandleDeleteAvailabilityException, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("events.delete_availability_exception", auditHandler.Target{Collection: domain.AvailabilityExceptionsTableName, RecordID: utils.PathParam("exceptionId")}),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(accountDomain.HealthSpecialistRole),
+				utils.IsSelf(utils.PathParam("healthSpecialistId")),
+			)))
 		return nil
 	})
 }
 
-func (s EventService) RegisterHooks() {}
+func (s EventService) RegisterHooks() {
+	// events scheduled before events had a duration last the default slot duration
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		backfilled, err := repository.NewEventRepository(s.Dao, s.Encryptor).BackfillEndDates(nil, domain.DefaultSlotDuration)
+		if err != nil {
+			return fmt.Errorf("Failed to backfill the end date of events after %d events: %w", backfilled, err)
+		}
+		if backfilled > 0 {
+			log.Printf("Backfilled the end date of %d events", backfilled)
+		}
+		return nil
+	})
+	// events scheduled before patients could book them were confirmed by their specialist
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		backfilled, err := repository.NewEventRepository(s.Dao, s.Encryptor).BackfillStatuses(nil, domain.ConfirmedStatus)
+		if err != nil {
+			return fmt.Errorf("Failed to backfill the status of events: %w", err)
+		}
+		if backfilled > 0 {
+			log.Printf("Backfilled the status of %d events", backfilled)
+		}
+		return nil
+	})
+}
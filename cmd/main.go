@@ -1,15 +1,37 @@
 package main
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/arosace/WellnessWaveApi/cmd/account"
+	"github.com/arosace/WellnessWaveApi/cmd/audit"
 	"github.com/arosace/WellnessWaveApi/cmd/event"
+	"github.com/arosace/WellnessWaveApi/cmd/organisation"
 	"github.com/arosace/WellnessWaveApi/cmd/planner"
+	"github.com/arosace/WellnessWaveApi/cmd/privacy"
+	"github.com/arosace/WellnessWaveApi/cmd/specialist"
+	"github.com/arosace/WellnessWaveApi/cmd/transfer"
+	"github.com/arosace/WellnessWaveApi/config"
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	accountHandler "github.com/arosace/WellnessWaveApi/internal/account/handler"
+	accountModel "github.com/arosace/WellnessWaveApi/internal/account/model"
+	accountRepository "github.com/arosace/WellnessWaveApi/internal/account/repository"
+	auditHandler "github.com/arosace/WellnessWaveApi/internal/audit/handler"
+	auditRepository "github.com/arosace/WellnessWaveApi/internal/audit/repository"
+	auditService "github.com/arosace/WellnessWaveApi/internal/audit/service"
+	eventDomain "github.com/arosace/WellnessWaveApi/internal/event/domain"
+	eventModel "github.com/arosace/WellnessWaveApi/internal/event/model"
+	plannerDomain "github.com/arosace/WellnessWaveApi/internal/planner/domain"
+	plannerModel "github.com/arosace/WellnessWaveApi/internal/planner/model"
+	privacyDomain "github.com/arosace/WellnessWaveApi/internal/privacy/domain"
+	privacyModel "github.com/arosace/WellnessWaveApi/internal/privacy/model"
 	encryption "github.com/arosace/WellnessWaveApi/pkg/utils"
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/spf13/cobra"
 )
 
 type Service interface {
@@ -20,19 +42,52 @@ type Service interface {
 
 // ServiceSetup holds all the services and their handlers for the application.
 type ServiceSetup struct {
-	AccountService *account.AccountService
-	EventService   *event.EventService
-	PlannerService *planner.PlannerService
+	AuditService        *audit.AuditService
+	AccountService      *account.AccountService
+	EventService        *event.EventService
+	PlannerService      *planner.PlannerService
+	PrivacyService      *privacy.PrivacyService
+	TransferService     *transfer.TransferService
+	OrganisationService *organisation.OrganisationService
+	SpecialistService   *specialist.SpecialistService
 }
 
 func main() {
 	app := pocketbase.New()
 
+	var services *ServiceSetup
 	app.OnAfterBootstrap().Add(func(e *core.BootstrapEvent) error {
-		initializeServices(app)
+		services = initializeServices(app)
 		return nil
 	})
 
+	// commands are registered before start, pocketbase skips the bootstrap of unknown commands
+	app.RootCmd.AddCommand(&cobra.Command{
+		Use:   "reencrypt",
+		Short: "Re-encrypts the encrypted fields of accounts, events, meals, exercises and data exports with the active encryption key",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := backfillSealedFields(services.AccountService.Dao, services.AccountService.Encryptor); err != nil {
+				log.Fatalf("Failed to mark the sealed fields: %v", err)
+			}
+			jobs := []struct {
+				name string
+				run  func() (int, error)
+			}{
+				{"accounts", services.AccountService.ReencryptAccounts},
+				{"events", services.EventService.ReencryptEvents},
+				{"meals and exercises", services.PlannerService.ReencryptPlanner},
+				{"data exports", services.PrivacyService.ReencryptExports},
+			}
+			for _, job := range jobs {
+				count, err := job.run()
+				if err != nil {
+					log.Fatalf("Failed to re-encrypt %s after %d updates: %v", job.name, count, err)
+				}
+				log.Printf("Re-encrypted %d %s", count, job.name)
+			}
+		},
+	})
+
 	// Start the HTTP server
 	log.Println("Starting server on port 8090...")
 	if err := app.Start(); err != nil {
@@ -41,7 +96,7 @@ func main() {
 }
 
 // initializeServices sets up all the services, repositories, and handlers.
-func initializeServices(app *pocketbase.PocketBase) {
+func initializeServices(app *pocketbase.PocketBase) *ServiceSetup {
 	// initialize mailer
 	mailer := app.NewMailClient()
 	// initialize dao
@@ -54,17 +109,65 @@ func initializeServices(app *pocketbase.PocketBase) {
 		log.Fatal("dao was not initiated properly")
 	}
 
-	//initialize encryptor
-	encryptor := &encryption.Encryptor{
-		Passphrase: "randompassphraseof32bytes1234567",
+	//load and validate configuration, the app refuses to start with missing or weak secrets
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	log.Printf("Loaded %s configuration", cfg.Environment)
+	// the login backoff and the audit log key on the address of the client, only trusting the X-Forwarded-For header of the given proxies
+	encryption.SetTrustedProxies(cfg.TrustedProxies)
+
+	jwtKeys, err := encryption.NewKeyring(cfg.JWTKeys.Active, cfg.JWTKeys.Keys)
+	if err != nil {
+		log.Fatalf("Failed to load jwt keys: %v", err)
+	}
+	encryption.SetJWTKeys(jwtKeys)
+	encryption.SetMailSettings(encryption.MailSettings{
+		FrontendURL:   cfg.FrontendURL,
+		SenderAddress: cfg.MailSenderAddress,
+		SenderName:    cfg.MailSenderName,
+	})
+	// accounts outside of any organisation are scoped to the accounts sharing a care team with them
+	encryption.SetTenantResolver(accountRepository.NewTenantRepository(dao))
+
+	//initialize encryptor, it encrypts with the active key and still decrypts with the retired ones and legacy values
+	encryptionKeys, err := encryption.NewKeyring(cfg.EncryptionKeys.Active, cfg.EncryptionKeys.Keys)
+	if err != nil {
+		log.Fatalf("Failed to load encryption keys: %v", err)
+	}
+	encryptor := encryption.NewAEADEncryptor(encryptionKeys)
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		return backfillSealedFields(dao, encryptor)
+	})
+
+	//initialize the account based authorization policies shared by all services
+	policies := accountHandler.NewAccountPolicies(accountRepository.NewAccountRepository(dao, encryptor), accountRepository.NewCareTeamRepository(dao))
+
+	//initialize audit service, a single instance keeps the hash chain of the audit log in order
+	auditor := auditService.NewAuditService(auditRepository.NewAuditRepository(dao))
+	auditTrail := auditHandler.NewAuditTrail(auditor)
+	auditServ := audit.AuditService{
+		App:     app,
+		Dao:     dao,
+		Auditor: auditor,
 	}
+	auditServ.Init()
+
+	log.Println("Audit service is up")
 
 	//initialize account service
 	accServ := account.AccountService{
-		App:       app,
-		Mailer:    mailer,
-		Dao:       dao,
-		Encryptor: encryptor,
+		App:            app,
+		Mailer:         mailer,
+		Dao:            dao,
+		Encryptor:      encryptor,
+		PasswordHasher: encryption.NewPasswordHasher(),
+		Policies:       policies,
+		Auditor:        auditor,
+		AuditTrail:     auditTrail,
+
+		RequireSpecialistTwoFactor: cfg.RequireSpecialistTwoFactor,
 	}
 	accServ.Init()
 
@@ -72,8 +175,11 @@ func initializeServices(app *pocketbase.PocketBase) {
 
 	//initialize event service
 	eventServ := event.EventService{
-		App: app,
-		Dao: dao,
+		App:        app,
+		Dao:        dao,
+		Encryptor:  encryptor,
+		Policies:   policies,
+		AuditTrail: auditTrail,
 	}
 	eventServ.Init()
 
@@ -81,10 +187,98 @@ func initializeServices(app *pocketbase.PocketBase) {
 
 	//initialize planner service
 	plannerServ := planner.PlannerService{
-		App: app,
-		Dao: dao,
+		App:        app,
+		Dao:        dao,
+		Encryptor:  encryptor,
+		Policies:   policies,
+		AuditTrail: auditTrail,
 	}
 	plannerServ.Init()
 
 	log.Println("Planner service is up")
+
+	//initialize privacy service
+	privacyServ := privacy.PrivacyService{
+		App:        app,
+		Dao:        dao,
+		Encryptor:  encryptor,
+		Policies:   policies,
+		Auditor:    auditor,
+		AuditTrail: auditTrail,
+	}
+	privacyServ.Init()
+
+	log.Println("Privacy service is up")
+
+	//initialize transfer service
+	transferServ := transfer.TransferService{
+		App:        app,
+		Dao:        dao,
+		Encryptor:  encryptor,
+		Mailer:     mailer,
+		Policies:   policies,
+		AuditTrail: auditTrail,
+	}
+	transferServ.Init()
+
+	log.Println("Transfer service is up")
+
+	//initialize organisation service
+	organisationServ := organisation.OrganisationService{
+		App:        app,
+		Dao:        dao,
+		Mailer:     mailer,
+		Policies:   policies,
+		AuditTrail: auditTrail,
+	}
+	organisationServ.Init()
+
+	log.Println("Organisation service is up")
+
+	//initialize specialist service
+	specialistServ := specialist.SpecialistService{
+		App:        app,
+		Dao:        dao,
+		AuditTrail: auditTrail,
+	}
+	specialistServ.Init()
+
+	log.Println("Specialist service is up")
+
+	return &ServiceSetup{
+		AuditService:        &auditServ,
+		AccountService:      &accServ,
+		EventService:        &eventServ,
+		PlannerService:      &plannerServ,
+		PrivacyService:      &privacyServ,
+		TransferService:     &transferServ,
+		OrganisationService: &organisationServ,
+		SpecialistService:   &specialistServ,
+	}
+}
+
+// backfillSealedFields lists the values encrypted before records kept track of their sealed fields, which
+// would otherwise be read as plain text. Records already tracking them are skipped.
+func backfillSealedFields(dao *daos.Dao, encryptor encryption.Encryption) error {
+	cipher := encryption.NewFieldCipher(encryptor)
+	collections := []struct {
+		name  string
+		model interface{}
+	}{
+		{accountDomain.TableName, accountModel.Account{}},
+		{eventDomain.TABLENAME, eventModel.Event{}},
+		{plannerDomain.MEALS_TABLENAME, plannerModel.Meal{}},
+		{plannerDomain.EXERCISE_TABLENAME, plannerModel.Exercise{}},
+		{privacyDomain.ExportsTableName, privacyModel.DataExport{}},
+	}
+	for _, collection := range collections {
+		marked, err := cipher.BackfillSealedFields(dao, collection.name, collection.model)
+		if err != nil {
+			return fmt.Errorf("Failed to mark the sealed fields of %s after %d records: %w", collection.name, marked, err)
+		}
+		if marked > 0 {
+			log.Printf("Marked the sealed fields of %d %s", marked, collection.name)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,91 @@
+package organisation
+
+import (
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	accountHandler "github.com/arosace/WellnessWaveApi/internal/account/handler"
+	auditHandler "github.com/arosace/WellnessWaveApi/internal/audit/handler"
+	"github.com/arosace/WellnessWaveApi/internal/organisation/domain"
+	"github.com/arosace/WellnessWaveApi/internal/organisation/handler"
+	"github.com/arosace/WellnessWaveApi/internal/organisation/repository"
+	"github.com/arosace/WellnessWaveApi/internal/organisation/service"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+)
+
+type OrganisationService struct {
+	App            *pocketbase.PocketBase
+	Dao            *daos.Dao
+	Mailer         mailer.Mailer
+	ServiceHandler *handler.OrganisationHandler
+	Policies       *accountHandler.AccountPolicies
+	AuditTrail     *auditHandler.AuditTrail
+}
+
+func (s OrganisationService) Init() {
+	organisationService := service.NewOrganisationService(
+		repository.NewOrganisationRepository(s.Dao),
+		repository.NewInvitationRepository(s.Dao),
+		s.Mailer,
+	)
+	s.ServiceHandler = handler.NewOrganisationHandler(organisationService)
+	s.RegisterEndpoints()
+}
+
+func (s OrganisationService) RegisterEndpoints() {
+	organisations := auditHandler.Target{Collection: domain.TableName}
+	organisation := auditHandler.Target{Collection: domain.TableName, RecordID: utils.PathParam("id")}
+	invitation := auditHandler.Target{Collection: domain.InvitationsTableName, RecordID: utils.PathParam("invitationId")}
+
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/organisations", s.ServiceHandler.HandleCreateOrganisation, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("organisations.create", organisations),
+			utils.Authorize(utils.HasRole(accountDomain.HealthSpecialistRole)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/organisations/:id", s.ServiceHandler.HandleGetOrganisation, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("organisations.read", organisation),
+			utils.Authorize(utils.InOrganisation(utils.PathParam("id"))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.PUT("/v1/organisations/:id", s.ServiceHandler.HandleUpdateOrganisation, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("organisations.update", organisation),
+			utils.Authorize(utils.InOrganisation(utils.PathParam("id"), domain.AdminRole)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/organisations/:id/members", s.ServiceHandler.HandleGetMembers, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("organisations.list_members", organisation),
+			utils.Authorize(utils.InOrganisation(utils.PathParam("id"))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/organisations/:id/invitations", s.ServiceHandler.HandleInvite, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("organisations.invite", organisation),
+			utils.Authorize(utils.InOrganisation(utils.PathParam("id"), domain.AdminRole)))
+		return nil
+	})
+	// only the invited health specialist can join the organisation, or refuse to
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/accounts/:id/organisation-invitations", s.ServiceHandler.HandleGetPendingInvitations, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("organisations.list_invitations", auditHandler.Target{Collection: domain.InvitationsTableName}),
+			utils.Authorize(utils.IsSelf(utils.PathParam("id"))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/:id/organisation-invitations/:invitationId/accept", s.ServiceHandler.HandleAcceptInvitation, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("organisations.accept_invitation", invitation),
+			utils.Authorize(utils.IsSelf(utils.PathParam("id"))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/:id/organisation-invitations/:invitationId/decline", s.ServiceHandler.HandleDeclineInvitation, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("organisations.decline_invitation", invitation),
+			utils.Authorize(utils.IsSelf(utils.PathParam("id"))))
+		return nil
+	})
+}
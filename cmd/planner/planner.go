@@ -1,6 +1,10 @@
 package planner
 
 import (
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	accountHandler "github.com/arosace/WellnessWaveApi/internal/account/handler"
+	auditHandler "github.com/arosace/WellnessWaveApi/internal/audit/handler"
+	"github.com/arosace/WellnessWaveApi/internal/planner/domain"
 	"github.com/arosace/WellnessWaveApi/internal/planner/handler"
 	"github.com/arosace/WellnessWaveApi/internal/planner/repository"
 	"github.com/arosace/WellnessWaveApi/internal/planner/service"
@@ -11,34 +15,80 @@ import (
 )
 
 type PlannerService struct {
-	App            *pocketbase.PocketBase
-	Dao            *daos.Dao
-	ServiceHandler *handler.PlannerHandler
+	App             *pocketbase.PocketBase
+	Dao             *daos.Dao
+	Encryptor       utils.Encryption
+	ServiceHandler  *handler.PlannerHandler
+	Policies        *accountHandler.AccountPolicies
+	PlannerPolicies *handler.PlannerPolicies
+	AuditTrail      *auditHandler.AuditTrail
 }
 
 func (s PlannerService) Init() {
-	plannerRepo := repository.NewPlannerRepository(s.Dao)
+	plannerRepo := repository.NewPlannerRepository(s.Dao, s.Encryptor)
 	plannerService := service.NewEventService(plannerRepo)
 	plannerServiceHandler := handler.NewPlannerHandler(plannerService)
 	s.ServiceHandler = plannerServiceHandler
+	s.PlannerPolicies = handler.NewPlannerPolicies(plannerService)
 	s.RegisterEndpoints()
 }
 
+// ReencryptPlanner rewrites the encrypted meal and exercise fields with the active encryption key.
+func (s PlannerService) ReencryptPlanner() (int, error) {
+	return repository.NewPlannerRepository(s.Dao, s.Encryptor).Reencrypt(nil)
+}
+
 func (s PlannerService) RegisterEndpoints() {
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.POST("/v1/planner/addMeal", s.ServiceHandler.HandleAddMeal, utils.EchoMiddleware)
+		e.Router.POST("/v1/planner/addMeal", s.ServiceHandler.HandleAddMeal, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("meals.add", auditHandler.Target{Collection: domain.MEALS_TABLENAME}),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(accountDomain.HealthSpecialistRole),
+				utils.IsSelf(utils.BodyField("health_specialist_id")),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/planner/addMealPlan", s.ServiceHandler.HandleAddMealPlan, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("meal_plans.add", auditHandler.Target{Collection: domain.PLANS_TABLENAME, PatientID: utils.BodyField("patient_id")}),
+			utils.Authorize(utils.AllOf(
+				utils.HasRole(accountDomain.HealthSpecialistRole),
+				utils.IsSelf(utils.BodyField("health_specialist_id")),
+				s.Policies.CareTeamMemberOf(utils.BodyField("patient_id"), accountDomain.MealPlanCareRoles...),
+			)))
 		return nil
 	})
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.POST("/v1/planner/addMealPlan", s.ServiceHandler.HandleAddMealPlan, utils.EchoMiddleware)
+		e.Router.GET("/v1/planner/getMeal", s.ServiceHandler.HandleGetMeal, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("meals.read", auditHandler.Target{Collection: domain.MEALS_TABLENAME, RecordID: utils.QueryParam("mealId")}),
+			utils.Authorize(utils.AllOf(
+				utils.IfPresent(utils.QueryParam("healthSpecialistId"), utils.AnyOf(
+					utils.IsSelf(utils.QueryParam("healthSpecialistId")),
+					s.Policies.CareTeamSpecialist(utils.QueryParam("healthSpecialistId")),
+					s.Policies.OrganisationColleague(utils.QueryParam("healthSpecialistId")),
+				)),
+				utils.IfPresent(utils.QueryParam("mealId"), utils.AnyOf(
+					utils.IsSelf(s.PlannerPolicies.MealAuthor(utils.QueryParam("mealId"))),
+					s.Policies.CareTeamSpecialist(s.PlannerPolicies.MealAuthor(utils.QueryParam("mealId"))),
+					s.Policies.OrganisationColleague(s.PlannerPolicies.MealAuthor(utils.QueryParam("mealId"))),
+				)),
+			)))
 		return nil
 	})
+	// the meals and exercises shared within the organisation of the caller
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.GET("/v1/planner/getMeal", s.ServiceHandler.HandleGetMeal, utils.EchoMiddleware)
+		e.Router.GET("/v1/planner/library", s.ServiceHandler.HandleGetLibrary, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("planner.library", auditHandler.Target{Collection: domain.MEALS_TABLENAME}),
+			utils.Authorize(utils.HasRole(accountDomain.HealthSpecialistRole)))
 		return nil
 	})
 	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		e.Router.GET("/v1/planner/getMealPlan", s.ServiceHandler.HandleGetMealPlan, utils.EchoMiddleware)
+		e.Router.GET("/v1/planner/getMealPlan", s.ServiceHandler.HandleGetMealPlan, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("meal_plans.read", auditHandler.Target{Collection: domain.PLANS_TABLENAME, PatientID: utils.QueryParam("patientId")}),
+			utils.Authorize(utils.AllOf(
+				utils.IfPresent(utils.QueryParam("healthSpecialistId"), utils.IsSelf(utils.QueryParam("healthSpecialistId"))),
+				utils.IfPresent(utils.QueryParam("patientId"), s.Policies.SelfOrCareTeamMember(utils.QueryParam("patientId"), accountDomain.MealPlanCareRoles...)),
+			)))
 		return nil
 	})
 }
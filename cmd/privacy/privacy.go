@@ -0,0 +1,111 @@
+package privacy
+
+import (
+	"log"
+
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	accountHandler "github.com/arosace/WellnessWaveApi/internal/account/handler"
+	accountRepository "github.com/arosace/WellnessWaveApi/internal/account/repository"
+	auditHandler "github.com/arosace/WellnessWaveApi/internal/audit/handler"
+	auditService "github.com/arosace/WellnessWaveApi/internal/audit/service"
+	eventRepository "github.com/arosace/WellnessWaveApi/internal/event/repository"
+	plannerRepository "github.com/arosace/WellnessWaveApi/internal/planner/repository"
+	"github.com/arosace/WellnessWaveApi/internal/privacy/domain"
+	"github.com/arosace/WellnessWaveApi/internal/privacy/handler"
+	"github.com/arosace/WellnessWaveApi/internal/privacy/repository"
+	"github.com/arosace/WellnessWaveApi/internal/privacy/service"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/tools/cron"
+)
+
+type PrivacyService struct {
+	App            *pocketbase.PocketBase
+	Dao            *daos.Dao
+	Encryptor      utils.Encryption
+	ServiceHandler *handler.PrivacyHandler
+	Policies       *accountHandler.AccountPolicies
+	Auditor        auditService.AuditService
+	AuditTrail     *auditHandler.AuditTrail
+}
+
+func (s PrivacyService) Init() {
+	privacyService := service.NewPrivacyService(
+		accountRepository.NewAccountRepository(s.Dao, s.Encryptor),
+		accountRepository.NewCareTeamRepository(s.Dao),
+		eventRepository.NewEventRepository(s.Dao, s.Encryptor),
+		plannerRepository.NewPlannerRepository(s.Dao, s.Encryptor),
+		repository.NewExportRepository(s.Dao, s.Encryptor),
+		s.Auditor,
+	)
+	s.ServiceHandler = handler.NewPrivacyHandler(privacyService)
+	s.RegisterEndpoints()
+	s.RegisterHooks()
+	s.RegisterJobs(privacyService)
+}
+
+// ReencryptExports rewrites the encrypted export documents with the active encryption key.
+func (s PrivacyService) ReencryptExports() (int, error) {
+	return repository.NewExportRepository(s.Dao, s.Encryptor).Reencrypt(nil)
+}
+
+func (s PrivacyService) RegisterEndpoints() {
+	// the exported data belongs to the patient, only they can request and download it
+	patient := auditHandler.Target{
+		Collection: accountDomain.TableName,
+		RecordID:   utils.PathParam("id"),
+		PatientID:  s.Policies.PatientAccount(utils.PathParam("id")),
+	}
+
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/:id/export", s.ServiceHandler.HandleRequestExport, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.export", patient),
+			utils.Authorize(utils.IsSelf(utils.PathParam("id"))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/accounts/:id/export/:exportId", s.ServiceHandler.HandleGetExport, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.export_status", patient),
+			utils.Authorize(utils.IsSelf(utils.PathParam("id"))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/accounts/:id/export/:exportId/download", s.ServiceHandler.HandleDownloadExport, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.export_download", patient),
+			utils.Authorize(utils.IsSelf(utils.PathParam("id"))))
+		return nil
+	})
+}
+
+func (s PrivacyService) RegisterHooks() {
+	// accounts deleted from the admin dashboard are erased with their data too, the cascade runs
+	// in the transaction deleting the account so no orphan record is left behind if it fails
+	s.App.OnModelBeforeDelete(accountDomain.TableName).Add(func(e *core.ModelEvent) error {
+		return repository.NewErasureRepository(e.Dao).Cascade(e.Model.GetId())
+	})
+}
+
+// RegisterJobs schedules the erasure of the accounts whose deletion grace period is over, while the app is serving.
+func (s PrivacyService) RegisterJobs(privacyService service.PrivacyService) {
+	scheduler := cron.New()
+	scheduler.MustAdd("erase_deleted_accounts", domain.ErasureSchedule, func() {
+		erased, err := privacyService.EraseDueAccounts(nil)
+		if err != nil {
+			log.Printf("Failed to erase deleted accounts: %v", err)
+		}
+		if erased > 0 {
+			log.Printf("Erased %d deleted accounts", erased)
+		}
+	})
+
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		scheduler.Start()
+		return nil
+	})
+	s.App.OnTerminate().Add(func(e *core.TerminateEvent) error {
+		scheduler.Stop()
+		return nil
+	})
+}
@@ -0,0 +1,62 @@
+package specialist
+
+import (
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	auditHandler "github.com/arosace/WellnessWaveApi/internal/audit/handler"
+	"github.com/arosace/WellnessWaveApi/internal/specialist/domain"
+	"github.com/arosace/WellnessWaveApi/internal/specialist/handler"
+	"github.com/arosace/WellnessWaveApi/internal/specialist/repository"
+	"github.com/arosace/WellnessWaveApi/internal/specialist/service"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+type SpecialistService struct {
+	App            *pocketbase.PocketBase
+	Dao            *daos.Dao
+	ServiceHandler *handler.ProfileHandler
+	AuditTrail     *auditHandler.AuditTrail
+}
+
+func (s SpecialistService) Init() {
+	profileService := service.NewProfileService(repository.NewProfileRepository(s.App, s.Dao))
+	s.ServiceHandler = handler.NewProfileHandler(profileService)
+	s.RegisterEndpoints()
+}
+
+func (s SpecialistService) RegisterEndpoints() {
+	profiles := auditHandler.Target{Collection: domain.ProfileTableName}
+	profile := auditHandler.Target{Collection: domain.ProfileTableName, RecordID: utils.PathParam("id")}
+
+	// the directory is public so patients can find a specialist before they have an account
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/specialists", s.ServiceHandler.HandleSearch, utils.EchoMiddleware,
+			s.AuditTrail.Audit("specialists.search", profiles))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/specialists/:id", s.ServiceHandler.HandleGetProfile, utils.EchoMiddleware,
+			s.AuditTrail.Audit("specialists.read", profile))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/specialists/:id/profile", s.ServiceHandler.HandleGetOwnProfile, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("specialists.read_profile", profile),
+			utils.Authorize(utils.AllOf(utils.HasRole(accountDomain.HealthSpecialistRole), utils.IsSelf(utils.PathParam("id")))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.PUT("/v1/specialists/:id/profile", s.ServiceHandler.HandleUpdateProfile, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("specialists.update_profile", profile),
+			utils.Authorize(utils.AllOf(utils.HasRole(accountDomain.HealthSpecialistRole), utils.IsSelf(utils.PathParam("id")))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.PUT("/v1/specialists/:id/photo", s.ServiceHandler.HandleSetPhoto, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("specialists.set_photo", profile),
+			utils.Authorize(utils.AllOf(utils.HasRole(accountDomain.HealthSpecialistRole), utils.IsSelf(utils.PathParam("id")))))
+		return nil
+	})
+}
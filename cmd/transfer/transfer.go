@@ -0,0 +1,90 @@
+package transfer
+
+import (
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	accountHandler "github.com/arosace/WellnessWaveApi/internal/account/handler"
+	accountRepository "github.com/arosace/WellnessWaveApi/internal/account/repository"
+	auditHandler "github.com/arosace/WellnessWaveApi/internal/audit/handler"
+	"github.com/arosace/WellnessWaveApi/internal/transfer/domain"
+	"github.com/arosace/WellnessWaveApi/internal/transfer/handler"
+	"github.com/arosace/WellnessWaveApi/internal/transfer/repository"
+	"github.com/arosace/WellnessWaveApi/internal/transfer/service"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+)
+
+type TransferService struct {
+	App            *pocketbase.PocketBase
+	Dao            *daos.Dao
+	Encryptor      utils.Encryption
+	Mailer         mailer.Mailer
+	ServiceHandler *handler.TransferHandler
+	Policies       *accountHandler.AccountPolicies
+	AuditTrail     *auditHandler.AuditTrail
+}
+
+func (s TransferService) Init() {
+	transferService := service.NewTransferService(
+		accountRepository.NewAccountRepository(s.Dao, s.Encryptor),
+		accountRepository.NewCareTeamRepository(s.Dao),
+		repository.NewTransferRepository(s.Dao, s.Encryptor),
+		s.Mailer,
+	)
+	s.ServiceHandler = handler.NewTransferHandler(transferService)
+	s.RegisterEndpoints()
+}
+
+func (s TransferService) RegisterEndpoints() {
+	patient := auditHandler.Target{
+		Collection: accountDomain.TableName,
+		RecordID:   utils.PathParam("id"),
+		PatientID:  s.Policies.PatientAccount(utils.PathParam("id")),
+	}
+	transfer := auditHandler.Target{
+		Collection: domain.TableName,
+		RecordID:   utils.PathParam("transferId"),
+		PatientID:  s.Policies.PatientAccount(utils.PathParam("id")),
+	}
+
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/:id/detach", s.ServiceHandler.HandleDetach, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.detach", patient),
+			// patients can remove any specialist of their care team, specialists only themselves
+			utils.Authorize(utils.AnyOf(
+				utils.IsSelf(utils.PathParam("id")),
+				utils.AllOf(
+					s.Policies.CareTeamMemberOf(utils.PathParam("id")),
+					utils.IfPresent(utils.QueryParam("specialistId"), utils.IsSelf(utils.QueryParam("specialistId"))),
+				),
+			)))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/:id/transfers", s.ServiceHandler.HandleRequestTransfer, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.request_transfer", patient),
+			utils.Authorize(s.Policies.CareTeamMemberOf(utils.PathParam("id"))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/v1/accounts/:id/transfers", s.ServiceHandler.HandleGetPendingTransfers, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.list_transfers", patient),
+			utils.Authorize(s.Policies.SelfOrCareTeamMember(utils.PathParam("id"))))
+		return nil
+	})
+	// only the patient can consent to, or refuse, being transferred
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/:id/transfers/:transferId/accept", s.ServiceHandler.HandleAcceptTransfer, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.accept_transfer", transfer),
+			utils.Authorize(utils.IsSelf(utils.PathParam("id"))))
+		return nil
+	})
+	s.App.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/v1/accounts/:id/transfers/:transferId/decline", s.ServiceHandler.HandleDeclineTransfer, utils.EchoMiddleware, utils.AuthMiddleware,
+			s.AuditTrail.Audit("accounts.decline_transfer", transfer),
+			utils.Authorize(utils.IsSelf(utils.PathParam("id"))))
+		return nil
+	})
+}
@@ -0,0 +1,283 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	Development = "dev"
+	Staging     = "staging"
+	Production  = "prod"
+
+	minJWTSecretLength     = 32
+	minDistinctSecretChars = 10
+	encryptionKeyLength    = 32
+
+	// defaultKeyID is the id of the key given through the single secret variables,
+	// it must match the id the keyring falls back to for data written without a key id.
+	defaultKeyID = "default"
+)
+
+// KeySet is a list of keys identified by key id, of which the active one is used to sign or encrypt.
+type KeySet struct {
+	Active string
+	Keys   map[string]string
+}
+
+// Config holds the settings the services need at boot. Values are read from the
+// env file of the active profile and overridden by the process environment.
+type Config struct {
+	Environment       string
+	JWTKeys           KeySet
+	EncryptionKeys    KeySet
+	FrontendURL       string
+	MailSenderAddress string
+	MailSenderName    string
+	// RequireSpecialistTwoFactor makes two factor authentication mandatory for health specialists.
+	RequireSpecialistTwoFactor bool
+	// TrustedProxies are the address ranges of the reverse proxies whose X-Forwarded-For header is
+	// trusted to tell the address of the client, without any the address of the peer is used.
+	TrustedProxies []*net.IPNet
+}
+
+// Load reads the profile selected by APP_ENV (dev by default) from <CONFIG_DIR>/<profile>.env.
+// CONFIG_DIR defaults to ./config, or ../config when the app is started from the cmd directory.
+func Load() (*Config, error) {
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = Development
+	}
+	if env != Development && env != Staging && env != Production {
+		return nil, fmt.Errorf("unknown APP_ENV %q, expected one of %s, %s, %s", env, Development, Staging, Production)
+	}
+
+	values, err := readEnvFile(filepath.Join(configDir(), env+".env"))
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := func(key string) string {
+		if value, ok := os.LookupEnv(key); ok {
+			return value
+		}
+		return values[key]
+	}
+
+	jwtKeys, err := loadKeySet(lookup, "JWT_KEYS", "JWT_ACTIVE_KEY", "JWT_SECRET")
+	if err != nil {
+		return nil, err
+	}
+	encryptionKeys, err := loadKeySet(lookup, "ENCRYPTION_KEYS", "ENCRYPTION_ACTIVE_KEY", "ENCRYPTION_PASSPHRASE")
+	if err != nil {
+		return nil, err
+	}
+
+	requireSpecialistTwoFactor := false
+	if value := lookup("REQUIRE_SPECIALIST_2FA"); value != "" {
+		requireSpecialistTwoFactor, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("REQUIRE_SPECIALIST_2FA must be true or false, got %q", value)
+		}
+	}
+
+	trustedProxies, err := parseIPRanges(lookup("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		Environment:       env,
+		JWTKeys:           jwtKeys,
+		EncryptionKeys:    encryptionKeys,
+		FrontendURL:       strings.TrimSuffix(lookup("FRONTEND_URL"), "/"),
+		MailSenderAddress: lookup("MAIL_SENDER_ADDRESS"),
+		MailSenderName:    lookup("MAIL_SENDER_NAME"),
+
+		RequireSpecialistTwoFactor: requireSpecialistTwoFactor,
+		TrustedProxies:             trustedProxies,
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s configuration: %w", env, err)
+	}
+	return cfg, nil
+}
+
+// Validate reports every missing or unsafe setting at once.
+func (c *Config) Validate() error {
+	var errorStrings []string
+
+	errorStrings = append(errorStrings, c.JWTKeys.validate("JWT_SECRET or JWT_KEYS", "JWT_ACTIVE_KEY", func(id string, secret string) string {
+		if len(secret) < minJWTSecretLength || isWeakSecret(secret) {
+			return fmt.Sprintf("jwt key %q is too weak, use at least %d random characters", id, minJWTSecretLength)
+		}
+		return ""
+	})...)
+
+	errorStrings = append(errorStrings, c.EncryptionKeys.validate("ENCRYPTION_PASSPHRASE or ENCRYPTION_KEYS", "ENCRYPTION_ACTIVE_KEY", func(id string, secret string) string {
+		if len(secret) != encryptionKeyLength {
+			return fmt.Sprintf("encryption key %q must be exactly %d bytes long", id, encryptionKeyLength)
+		}
+		if isWeakSecret(secret) {
+			return fmt.Sprintf("encryption key %q is too weak, use random characters", id)
+		}
+		return ""
+	})...)
+
+	if c.FrontendURL == "" {
+		errorStrings = append(errorStrings, "FRONTEND_URL is required")
+	} else if u, err := url.Parse(c.FrontendURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		errorStrings = append(errorStrings, "FRONTEND_URL must be an absolute http(s) url")
+	} else if c.Environment != Development && u.Scheme != "https" {
+		errorStrings = append(errorStrings, "FRONTEND_URL must use https outside of dev")
+	}
+
+	if c.MailSenderAddress == "" {
+		errorStrings = append(errorStrings, "MAIL_SENDER_ADDRESS is required")
+	} else if _, err := mail.ParseAddress(c.MailSenderAddress); err != nil {
+		errorStrings = append(errorStrings, "MAIL_SENDER_ADDRESS is not a valid email address")
+	}
+
+	if len(errorStrings) > 0 {
+		return errors.New(strings.Join(errorStrings, "; "))
+	}
+	return nil
+}
+
+// validate checks the active key is part of the set and every key passes checkKey,
+// which returns a description of the problem or an empty string.
+func (k KeySet) validate(required string, activeName string, checkKey func(id string, secret string) string) []string {
+	if len(k.Keys) == 0 {
+		return []string{required + " is required"}
+	}
+
+	var errorStrings []string
+	if _, ok := k.Keys[k.Active]; !ok {
+		errorStrings = append(errorStrings, fmt.Sprintf("%s %q is not one of the configured keys", activeName, k.Active))
+	}
+
+	ids := make([]string, 0, len(k.Keys))
+	for id := range k.Keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if problem := checkKey(id, k.Keys[id]); problem != "" {
+			errorStrings = append(errorStrings, problem)
+		}
+	}
+	return errorStrings
+}
+
+// loadKeySet reads a comma separated list of id:secret pairs, e.g. JWT_KEYS=2024-01:<secret>,2024-06:<secret>.
+// The active key defaults to the only key of the list. When the list is not set the single secret
+// variable is used as the key with the default id, so secrets set before key rotation keep working.
+func loadKeySet(lookup func(string) string, keysName string, activeName string, secretName string) (KeySet, error) {
+	keySet := KeySet{Keys: map[string]string{}}
+
+	list := lookup(keysName)
+	if list == "" {
+		if secret := lookup(secretName); secret != "" {
+			keySet.Keys[defaultKeyID] = secret
+			keySet.Active = defaultKeyID
+		}
+		return keySet, nil
+	}
+
+	for _, pair := range strings.Split(list, ",") {
+		id, secret, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || id == "" || secret == "" {
+			return keySet, fmt.Errorf("%s: expected a comma separated list of id:secret pairs", keysName)
+		}
+		if _, ok := keySet.Keys[id]; ok {
+			return keySet, fmt.Errorf("%s: key id %q is listed twice", keysName, id)
+		}
+		keySet.Keys[id] = secret
+	}
+
+	keySet.Active = lookup(activeName)
+	if keySet.Active == "" && len(keySet.Keys) == 1 {
+		for id := range keySet.Keys {
+			keySet.Active = id
+		}
+	}
+	return keySet, nil
+}
+
+// parseIPRanges reads a comma separated list of CIDR ranges, e.g. TRUSTED_PROXIES=10.0.0.0/8,192.168.1.10/32.
+func parseIPRanges(list string) ([]*net.IPNet, error) {
+	ranges := []*net.IPNet{}
+	if list == "" {
+		return ranges, nil
+	}
+	for _, cidr := range strings.Split(list, ",") {
+		_, ipRange, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %q is not a CIDR range", cidr)
+		}
+		ranges = append(ranges, ipRange)
+	}
+	return ranges, nil
+}
+
+func configDir() string {
+	if dir := os.Getenv("CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	if _, err := os.Stat("config"); err == nil {
+		return "config"
+	}
+	return filepath.Join("..", "config")
+}
+
+// readEnvFile parses KEY=VALUE lines, ignoring blank lines and # comments.
+// A missing file is not an error, every value can come from the environment.
+func readEnvFile(path string) (map[string]string, error) {
+	values := map[string]string{}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return values, nil
+		}
+		return nil, fmt.Errorf("could not read config file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNumber)
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read config file %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// isWeakSecret rejects secrets built from too few distinct characters, e.g. "aaaa..." or "12341234...".
+func isWeakSecret(secret string) bool {
+	distinct := map[rune]struct{}{}
+	for _, r := range secret {
+		distinct[r] = struct{}{}
+	}
+	return len(distinct) < minDistinctSecretChars
+}
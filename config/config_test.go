@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validConfig() *Config {
+	return &Config{
+		Environment:       Production,
+		JWTKeys:           KeySet{Active: "default", Keys: map[string]string{"default": "k8Jd2nVq9ZpL4xWc7RtY1bHm6FsA3eGu"}},
+		EncryptionKeys:    KeySet{Active: "default", Keys: map[string]string{"default": "Qw7Er9Ty2Ui4Op6As8Df1Gh3Jk5Lz0Xc"}},
+		FrontendURL:       "https://wellnesswave.app",
+		MailSenderAddress: "hello@noreply.com",
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	t.Run("valid configuration", func(t *testing.T) {
+		assert.Nil(t, validConfig().Validate())
+	})
+
+	t.Run("missing secrets are reported together", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.JWTKeys = KeySet{}
+		cfg.EncryptionKeys = KeySet{}
+		err := cfg.Validate()
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "JWT_SECRET or JWT_KEYS is required")
+		assert.Contains(t, err.Error(), "ENCRYPTION_PASSPHRASE or ENCRYPTION_KEYS is required")
+	})
+
+	t.Run("weak secrets are rejected", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.JWTKeys.Keys["default"] = "your_secret_key"
+		cfg.EncryptionKeys.Keys["default"] = "11111111111111111111111111111111"
+		err := cfg.Validate()
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), `jwt key "default" is too weak`)
+		assert.Contains(t, err.Error(), `encryption key "default" is too weak`)
+	})
+
+	t.Run("active key must be configured", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.JWTKeys.Active = "2024-06"
+		err := cfg.Validate()
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), `JWT_ACTIVE_KEY "2024-06" is not one of the configured keys`)
+	})
+
+	t.Run("plain http frontend is only allowed in dev", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.FrontendURL = "http://localhost:3000"
+		assert.NotNil(t, cfg.Validate())
+
+		cfg.Environment = Development
+		assert.Nil(t, cfg.Validate())
+	})
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "staging.env"), []byte(`
+# comment
+FRONTEND_URL=https://staging.wellnesswave.app/
+MAIL_SENDER_ADDRESS="hello@noreply.com"
+JWT_SECRET=from-the-file-but-overridden-0123
+`), 0o600)
+	assert.Nil(t, err)
+
+	t.Setenv("CONFIG_DIR", dir)
+	t.Setenv("APP_ENV", Staging)
+	t.Setenv("JWT_SECRET", "k8Jd2nVq9ZpL4xWc7RtY1bHm6FsA3eGu")
+	t.Setenv("ENCRYPTION_PASSPHRASE", "Qw7Er9Ty2Ui4Op6As8Df1Gh3Jk5Lz0Xc")
+
+	cfg, err := Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://staging.wellnesswave.app", cfg.FrontendURL)
+	assert.Equal(t, "hello@noreply.com", cfg.MailSenderAddress)
+	assert.Equal(t, "k8Jd2nVq9ZpL4xWc7RtY1bHm6FsA3eGu", cfg.JWTKeys.Keys["default"], "environment should override the file")
+	assert.Equal(t, "default", cfg.JWTKeys.Active)
+
+	t.Setenv("JWT_KEYS", "default:k8Jd2nVq9ZpL4xWc7RtY1bHm6FsA3eGu,2024-06:Zx8Cv6Bn4Mm2Lk0Jh9Gf7Ds5Aa3Pp1Oo")
+	t.Setenv("JWT_ACTIVE_KEY", "2024-06")
+	cfg, err = Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-06", cfg.JWTKeys.Active)
+	assert.Len(t, cfg.JWTKeys.Keys, 2)
+
+	t.Setenv("REQUIRE_SPECIALIST_2FA", "maybe")
+	_, err = Load()
+	assert.NotNil(t, err, "REQUIRE_SPECIALIST_2FA must be a boolean")
+	t.Setenv("REQUIRE_SPECIALIST_2FA", "true")
+	cfg, err = Load()
+	assert.Nil(t, err)
+	assert.True(t, cfg.RequireSpecialistTwoFactor)
+
+	assert.Empty(t, cfg.TrustedProxies)
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.10/32")
+	cfg, err = Load()
+	assert.Nil(t, err)
+	assert.Len(t, cfg.TrustedProxies, 2)
+	assert.Equal(t, "10.0.0.0/8", cfg.TrustedProxies[0].String())
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1")
+	_, err = Load()
+	assert.NotNil(t, err, "TRUSTED_PROXIES must be CIDR ranges")
+	t.Setenv("TRUSTED_PROXIES", "")
+
+	t.Setenv("ENCRYPTION_PASSPHRASE", "")
+	_, err = Load()
+	assert.NotNil(t, err)
+}
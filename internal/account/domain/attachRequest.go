@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+const (
+	// AttachRequestsTableName stores the requests of patients to be followed by a health specialist.
+	AttachRequestsTableName = "attach_requests"
+
+	AttachRequestPending   = "pending"
+	AttachRequestAccepted  = "accepted"
+	AttachRequestDeclined  = "declined"
+	AttachRequestCancelled = "cancelled"
+
+	// AttachRequestDuration is how long the health specialist has to answer an attach request.
+	AttachRequestDuration = 14 * 24 * time.Hour
+)
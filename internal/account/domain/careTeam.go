@@ -0,0 +1,26 @@
+package domain
+
+// CareTeamTableName links patients to the health specialists of their care team, each with a care role.
+const CareTeamTableName = "care_team_members"
+
+// Care roles of the members of a care team, a patient has at most one member per role.
+const (
+	// PrimaryCareRole follows the patient as a whole and manages every plan type.
+	PrimaryCareRole   = "primary"
+	NutritionCareRole = "nutrition"
+	ExerciseCareRole  = "exercise"
+)
+
+var CareRoles = []string{PrimaryCareRole, NutritionCareRole, ExerciseCareRole}
+
+// MealPlanCareRoles are the care roles allowed to see and manage meal plans.
+var MealPlanCareRoles = []string{PrimaryCareRole, NutritionCareRole}
+
+func IsCareRole(role string) bool {
+	for _, careRole := range CareRoles {
+		if role == careRole {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+const (
+	// Deleted accounts are erased for good once DeletionGracePeriod has passed, until then their owner
+	// can cancel the deletion with the link emailed to them.
+	DeletionGracePeriod = 30 * 24 * time.Hour
+
+	// CancelDeletionPurpose marks the single use tokens emailed to the owner of a deleted account.
+	CancelDeletionPurpose = "cancel_deletion"
+)
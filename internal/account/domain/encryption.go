@@ -0,0 +1,5 @@
+package domain
+
+// EncryptedFields are the accounts fields stored encrypted with the Encryptor,
+// they are rewritten with the active key by the re-encryption job.
+var EncryptedFields = []string{"encrypted_password"}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+const (
+	// An account is locked once it reaches LoginFailureThreshold consecutive failed logins. The lock lasts
+	// LockoutBaseDuration and doubles with every further failure, up to LockoutMaxDuration.
+	LoginFailureThreshold = 5
+	LockoutBaseDuration   = 15 * time.Minute
+	LockoutMaxDuration    = 24 * time.Hour
+
+	// An ip address is slowed down once it reaches IPFailureThreshold failed logins, whichever
+	// accounts they targeted, with the same doubling from IPBackoffBaseDuration to IPBackoffMaxDuration.
+	IPFailureThreshold    = 20
+	IPBackoffBaseDuration = time.Second
+	IPBackoffMaxDuration  = 15 * time.Minute
+
+	// UnlockPurpose marks the single use tokens emailed to the owner of a locked account.
+	UnlockPurpose       = "unlock"
+	UnlockTokenDuration = 24 * time.Hour
+)
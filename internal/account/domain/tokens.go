@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+const (
+	RefreshTokensTableName = "refresh_tokens"
+	RefreshTokenDuration   = 30 * 24 * time.Hour
+
+	// AccountTokensTableName stores single use tokens emailed to account owners, hashed.
+	AccountTokensTableName     = "account_tokens"
+	PasswordResetPurpose       = "password_reset"
+	PasswordResetTokenDuration = time.Hour
+	PasswordResetRequestLimit  = 3
+	PasswordResetRequestWindow = time.Hour
+	InvitePurpose              = "invite"
+	InviteTokenDuration        = 7 * 24 * time.Hour
+)
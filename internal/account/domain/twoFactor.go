@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+const (
+	// TOTPIssuer is the name shown next to the account in authenticator apps.
+	TOTPIssuer = "WellnessWave"
+
+	// RecoveryCodePurpose marks the hashed recovery codes stored in the account tokens collection.
+	RecoveryCodePurpose = "recovery_code"
+	RecoveryCodeCount   = 10
+
+	TwoFactorAttemptLimit  = 5
+	TwoFactorAttemptWindow = 5 * time.Minute
+)
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+func (h *AccountHandler) HandleDeleteAccount(ctx echo.Context) error {
+	if err := h.accountService.DeleteAccount(ctx, ctx.PathParam("id")); err != nil {
+		if err.Error() == "already_deleted" {
+			return apis.NewApiError(http.StatusConflict, err.Error(), nil)
+		}
+		return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("Failed to delete account: %v", err), nil)
+	}
+	return ctx.NoContent(http.StatusAccepted)
+}
+
+func (h *AccountHandler) HandleCancelDeletion(ctx echo.Context) error {
+	var body model.TokenBody
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("invalid_data_format", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	if err := h.accountService.CancelDeletion(ctx, body.Token); err != nil {
+		if err.Error() == "invalid_token" || err.Error() == "token_expired" {
+			return apis.NewBadRequestError(err.Error(), nil)
+		}
+		return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("Failed to cancel account deletion: %v", err), nil)
+	}
+	return ctx.NoContent(http.StatusOK)
+}
+
+func (h *AccountHandler) HandleGetPatientsPendingDeletion(ctx echo.Context) error {
+	res := model.AccountResponse{}
+	parentId := ctx.PathParam("parent_id")
+
+	patients, err := h.accountService.GetPatientsPendingDeletion(ctx, parentId)
+	if err != nil {
+		res.Error = fmt.Sprintf("Failed to get patients pending deletion of account (%s): %v", parentId, err)
+		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+	}
+
+	hideCredentials(patients...)
+	res.Data = patients
+	return ctx.JSON(http.StatusOK, res)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/arosace/WellnessWaveApi/pkg/utils"
 	"github.com/labstack/echo/v5"
 	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/models"
 )
 
 // AccountHandler handles HTTP requests for account operations.
@@ -24,30 +25,28 @@ func NewAccountHandler(accountService service.AccountService) *AccountHandler {
 // HandleAddAccount handles the POST request to add a new account.
 func (h *AccountHandler) HandleAddAccount(c echo.Context) error {
 	res := model.AccountResponse{}
-	var account model.Account
-	if err := c.Bind(&account); err != nil {
+	var registration model.RegisterAccountBody
+	if err := c.Bind(&registration); err != nil {
 		return apis.NewBadRequestError("wrong_data_type", err)
 	}
 
-	if err := account.ValidateModel(); err != nil {
+	if err := registration.ValidateModel(); err != nil {
 		res.Error = fmt.Sprintf("inavlid_data_format: %v", err)
 		return apis.NewBadRequestError(res.Error, res)
 	}
 
-	if alreadyExists := h.accountService.CheckAccountExists(c, account.Email); alreadyExists {
+	if alreadyExists := h.accountService.CheckAccountExists(c, registration.Email); alreadyExists {
 		res.Error = "email_already_in_use"
 		return apis.NewApiError(http.StatusConflict, res.Error, res)
 	}
 
-	account.Username = account.FirstName + " " + account.LastName
-
-	newlyCreatedAccount, err := h.accountService.AddAccount(c, account)
+	newlyCreatedAccount, err := h.accountService.AddAccount(c, registration)
 	if err != nil {
 		res.Error = fmt.Sprintf("failed_to_add_account: %v", err)
 		return apis.NewApiError(http.StatusInternalServerError, res.Error, res)
 	}
 
-	newlyCreatedAccount.Set("encrypted_password", "")
+	hideCredentials(newlyCreatedAccount)
 	res.Data = newlyCreatedAccount
 
 	return c.JSON(http.StatusCreated, res)
@@ -90,6 +89,7 @@ func (h *AccountHandler) HandleGetAccounts(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, res)
 	}
 
+	hideCredentials(accounts...)
 	res.Data = accounts
 	return c.JSON(http.StatusOK, res)
 }
@@ -109,6 +109,7 @@ func (h *AccountHandler) HandleGetAccountsById(c echo.Context) error {
 		return apis.NewApiError(http.StatusInternalServerError, res.Error, res)
 	}
 
+	hideCredentials(account)
 	res.Data = account
 	return c.JSON(http.StatusOK, res)
 }
@@ -132,6 +133,7 @@ func (h *AccountHandler) HandleAttachAccount(ctx echo.Context) error {
 	if account == nil {
 		return ctx.JSON(http.StatusOK, account)
 	} else {
+		hideCredentials(account)
 		return ctx.JSON(http.StatusCreated, account)
 	}
 }
@@ -150,6 +152,7 @@ func (h *AccountHandler) HandleGetAttachedAccounts(ctx echo.Context) error {
 		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
 	}
 
+	hideCredentials(attachedAccounts...)
 	res.Data = attachedAccounts
 	return ctx.JSON(http.StatusOK, res)
 }
@@ -185,7 +188,7 @@ func (h *AccountHandler) HandleUpdateAccount(ctx echo.Context) error {
 }
 
 func (h *AccountHandler) HandleLogIn(ctx echo.Context) error {
-	res := model.AccountResponse{}
+	res := model.LogInResponse{}
 
 	var params model.LogInCredentials
 	if err := ctx.Bind(&params); err != nil {
@@ -198,12 +201,185 @@ func (h *AccountHandler) HandleLogIn(ctx echo.Context) error {
 	}
 
 	authorizedAccount, err := h.accountService.Authorize(ctx, params)
+	if err != nil {
+		switch err.Error() {
+		case "invalid_credentials":
+			res.Error = err.Error()
+			return apis.NewUnauthorizedError(res.Error, nil)
+		case "too_many_attempts":
+			res.Error = err.Error()
+			return apis.NewApiError(http.StatusTooManyRequests, res.Error, nil)
+		}
+		res.Error = "failed_to_log_in"
+		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+	}
+
+	// accounts with two factor authentication get a challenge to complete instead of a session
+	challenge, err := h.accountService.StartTwoFactorChallenge(ctx, authorizedAccount)
+	if err != nil {
+		res.Error = fmt.Sprintf("failed_to_start_two_factor_challenge: %v", err)
+		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+	}
+	if challenge != nil {
+		res.TwoFactorChallenge = challenge
+		return ctx.JSON(http.StatusOK, res)
+	}
+
+	session, err := h.accountService.IssueSession(ctx, authorizedAccount)
+	if err != nil {
+		res.Error = fmt.Sprintf("failed_to_issue_session: %v", err)
+		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+	}
+
+	hideCredentials(authorizedAccount)
+	res.Data = authorizedAccount
+	res.Session = session
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *AccountHandler) HandleRefreshToken(ctx echo.Context) error {
+	res := model.LogInResponse{}
+
+	var body model.RefreshTokenBody
+	if err := ctx.Bind(&body); err != nil {
+		res.Error = "invalid_data_format"
+		return apis.NewBadRequestError(res.Error, nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	account, session, err := h.accountService.RefreshSession(ctx, body.RefreshToken)
 	if err != nil {
 		res.Error = fmt.Sprintf("unauthorized: %s", err.Error())
 		return apis.NewUnauthorizedError(res.Error, nil)
 	}
 
-	authorizedAccount.Set("encrypted_password", "")
-	res.Data = authorizedAccount
+	hideCredentials(account)
+	res.Data = account
+	res.Session = session
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *AccountHandler) HandleLogout(ctx echo.Context) error {
+	var body model.RefreshTokenBody
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("invalid_data_format", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	if err := h.accountService.Logout(ctx, utils.GetAuthAccountId(ctx), body.RefreshToken); err != nil {
+		if err.Error() == "invalid_refresh_token" {
+			return apis.NewBadRequestError(err.Error(), nil)
+		}
+		return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("Failed to log out: %v", err), nil)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (h *AccountHandler) HandleLogoutAllDevices(ctx echo.Context) error {
+	if err := h.accountService.LogoutAllDevices(ctx, utils.GetAuthAccountId(ctx)); err != nil {
+		return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("Failed to log out of all devices: %v", err), nil)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (h *AccountHandler) HandleForgotPassword(ctx echo.Context) error {
+	var body model.ForgotPasswordBody
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("invalid_data_format", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	if err := h.accountService.RequestPasswordReset(ctx, body.Email); err != nil {
+		if err.Error() == "too_many_requests" {
+			return apis.NewApiError(http.StatusTooManyRequests, err.Error(), nil)
+		}
+		return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("Failed to request password reset: %v", err), nil)
+	}
+
+	// the response is the same whether the email belongs to an account or not
+	return ctx.NoContent(http.StatusAccepted)
+}
+
+func (h *AccountHandler) HandleResetPassword(ctx echo.Context) error {
+	var body model.ResetPasswordBody
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("invalid_data_format", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	if err := h.accountService.ResetPassword(ctx, body.Token, body.Password); err != nil {
+		if err.Error() == "invalid_token" || err.Error() == "token_expired" {
+			return apis.NewBadRequestError(err.Error(), nil)
+		}
+		return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("Failed to reset password: %v", err), nil)
+	}
+	return ctx.NoContent(http.StatusOK)
+}
+
+func (h *AccountHandler) HandleAcceptInvite(ctx echo.Context) error {
+	res := model.LogInResponse{}
+
+	var body model.ResetPasswordBody
+	if err := ctx.Bind(&body); err != nil {
+		res.Error = "invalid_data_format"
+		return apis.NewBadRequestError(res.Error, nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	account, err := h.accountService.AcceptInvite(ctx, body.Token, body.Password)
+	if err != nil {
+		if err.Error() == "invalid_token" || err.Error() == "token_expired" {
+			return apis.NewBadRequestError(err.Error(), nil)
+		}
+		res.Error = fmt.Sprintf("Failed to accept invitation: %v", err)
+		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+	}
+
+	session, err := h.accountService.IssueSession(ctx, account)
+	if err != nil {
+		res.Error = fmt.Sprintf("failed_to_issue_session: %v", err)
+		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+	}
+
+	hideCredentials(account)
+	res.Data = account
+	res.Session = session
 	return ctx.JSON(http.StatusOK, res)
 }
+
+func (h *AccountHandler) HandleUnlockAccount(ctx echo.Context) error {
+	var body model.TokenBody
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("invalid_data_format", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	if err := h.accountService.UnlockAccount(ctx, body.Token); err != nil {
+		if err.Error() == "invalid_token" || err.Error() == "token_expired" {
+			return apis.NewBadRequestError(err.Error(), nil)
+		}
+		return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("Failed to unlock account: %v", err), nil)
+	}
+	return ctx.NoContent(http.StatusOK)
+}
+
+// hideCredentials blanks the password fields of account records before they are returned to clients.
+func hideCredentials(accounts ...*models.Record) {
+	for _, account := range accounts {
+		account.Set("encrypted_password", "")
+		account.Set("password_hash", "")
+		account.Set("totp_secret", "")
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/repository"
+	organisationDomain "github.com/arosace/WellnessWaveApi/internal/organisation/domain"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+)
+
+// AccountPolicies builds authorization policies that depend on the relation between accounts.
+type AccountPolicies struct {
+	accountRepository  repository.AccountRepository
+	careTeamRepository repository.CareTeamRepository
+}
+
+// NewAccountPolicies creates a new instance of AccountPolicies.
+func NewAccountPolicies(accountRepo repository.AccountRepository, careTeamRepo repository.CareTeamRepository) *AccountPolicies {
+	return &AccountPolicies{accountRepository: accountRepo, careTeamRepository: careTeamRepo}
+}
+
+// CareTeamMemberOf allows the health specialists in the care team of the extracted patient.
+// When care roles are given, only the members holding one of them are allowed.
+func (p *AccountPolicies) CareTeamMemberOf(patientId utils.ParamExtractor, careRoles ...string) utils.Policy {
+	return func(c echo.Context) (bool, error) {
+		id := patientId(c)
+		if id == "" || utils.GetAuthRole(c) != domain.HealthSpecialistRole {
+			return false, nil
+		}
+		return p.isMember(c, id, utils.GetAuthAccountId(c), careRoles)
+	}
+}
+
+// CareTeamSpecialist allows patients to access the health specialists of their care team.
+func (p *AccountPolicies) CareTeamSpecialist(specialistId utils.ParamExtractor) utils.Policy {
+	return func(c echo.Context) (bool, error) {
+		id := specialistId(c)
+		if id == "" || utils.GetAuthRole(c) != domain.PatientRole {
+			return false, nil
+		}
+		return p.isMember(c, utils.GetAuthAccountId(c), id, nil)
+	}
+}
+
+// SelfOrCareTeamMember allows the patient themselves or the health specialists in their care team,
+// restricted to the given care roles if any.
+func (p *AccountPolicies) SelfOrCareTeamMember(patientId utils.ParamExtractor, careRoles ...string) utils.Policy {
+	return utils.AnyOf(utils.IsSelf(patientId), p.CareTeamMemberOf(patientId, careRoles...))
+}
+
+func (p *AccountPolicies) isMember(c echo.Context, patientId string, specialistId string, careRoles []string) (bool, error) {
+	member, err := p.careTeamRepository.FindMember(c, patientId, specialistId)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if len(careRoles) == 0 {
+		return true, nil
+	}
+	for _, careRole := range careRoles {
+		if member.GetString("role") == careRole {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// OrganisationAdminOf allows the admins of an organisation to access the health specialists of their
+// organisation, e.g. to see their schedule.
+func (p *AccountPolicies) OrganisationAdminOf(specialistId utils.ParamExtractor) utils.Policy {
+	return func(c echo.Context) (bool, error) {
+		id := specialistId(c)
+		if id == "" || utils.GetAuthOrganisationRole(c) != organisationDomain.AdminRole {
+			return false, nil
+		}
+		return p.inSameOrganisation(c, id, domain.HealthSpecialistRole)
+	}
+}
+
+// OrganisationColleague allows the health specialists of the organisation the extracted health
+// specialist belongs to, e.g. to share the meals and exercises of their library.
+func (p *AccountPolicies) OrganisationColleague(specialistId utils.ParamExtractor) utils.Policy {
+	return func(c echo.Context) (bool, error) {
+		id := specialistId(c)
+		if id == "" || utils.GetAuthRole(c) != domain.HealthSpecialistRole {
+			return false, nil
+		}
+		return p.inSameOrganisation(c, id, domain.HealthSpecialistRole)
+	}
+}
+
+// inSameOrganisation reports whether the account has the given role and belongs to the organisation
+// of the caller, callers outside of any organisation have no colleagues.
+func (p *AccountPolicies) inSameOrganisation(c echo.Context, accountId string, role string) (bool, error) {
+	organisationId := utils.GetAuthOrganisationId(c)
+	if organisationId == "" {
+		return false, nil
+	}
+	account, err := p.accountRepository.FindByID(c, accountId)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return account.GetString("role") == role && account.GetString("organisation_id") == organisationId, nil
+}
+
+// PatientAccount resolves to the extracted account id when it belongs to a patient, and to an
+// empty string otherwise, e.g. to tell whose health data a request touches.
+func (p *AccountPolicies) PatientAccount(accountId utils.ParamExtractor) utils.ParamExtractor {
+	return func(c echo.Context) string {
+		id := accountId(c)
+		if id == "" {
+			return ""
+		}
+		account, err := p.accountRepository.FindByID(c, id)
+		if err != nil || account.GetString("role") != domain.PatientRole {
+			return ""
+		}
+		return id
+	}
+}
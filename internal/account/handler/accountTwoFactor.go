@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+// HandleTwoFactorLogIn completes a log in challenge and returns a session.
+func (h *AccountHandler) HandleTwoFactorLogIn(ctx echo.Context) error {
+	res := model.LogInResponse{}
+
+	var body model.TwoFactorLogInBody
+	if err := ctx.Bind(&body); err != nil {
+		res.Error = "invalid_data_format"
+		return apis.NewBadRequestError(res.Error, nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	account, err := h.accountService.CompleteTwoFactorChallenge(ctx, body.ChallengeToken, body.Code, body.RecoveryCode)
+	if err != nil {
+		return twoFactorError(err)
+	}
+
+	session, err := h.accountService.IssueSession(ctx, account)
+	if err != nil {
+		res.Error = fmt.Sprintf("failed_to_issue_session: %v", err)
+		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+	}
+
+	hideCredentials(account)
+	res.Data = account
+	res.Session = session
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *AccountHandler) HandleEnrollTwoFactor(ctx echo.Context) error {
+	res := model.TwoFactorEnrollmentResponse{}
+
+	enrollment, err := h.accountService.EnrollTwoFactor(ctx, utils.GetAuthAccountId(ctx))
+	if err != nil {
+		return twoFactorError(err)
+	}
+
+	res.Data = enrollment
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// HandleConfirmTwoFactor enables two factor authentication and returns the recovery codes. When the
+// request was made with the enrollment token handed out at log in, the log in is completed as well.
+func (h *AccountHandler) HandleConfirmTwoFactor(ctx echo.Context) error {
+	res := model.RecoveryCodesResponse{}
+
+	var body model.TwoFactorCodeBody
+	if err := ctx.Bind(&body); err != nil {
+		res.Error = "invalid_data_format"
+		return apis.NewBadRequestError(res.Error, nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	accountId := utils.GetAuthAccountId(ctx)
+	recoveryCodes, err := h.accountService.ConfirmTwoFactor(ctx, accountId, body.Code)
+	if err != nil {
+		return twoFactorError(err)
+	}
+	res.RecoveryCodes = recoveryCodes
+
+	if utils.GetAuthTokenUse(ctx) == utils.MFAEnrollmentTokenUse {
+		account, err := h.accountService.GetAccountById(ctx, accountId)
+		if err != nil {
+			return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve account: %v", err), nil)
+		}
+		session, err := h.accountService.IssueSession(ctx, account)
+		if err != nil {
+			res.Error = fmt.Sprintf("failed_to_issue_session: %v", err)
+			return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+		}
+		res.Session = session
+	}
+
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *AccountHandler) HandleRegenerateRecoveryCodes(ctx echo.Context) error {
+	res := model.RecoveryCodesResponse{}
+
+	var body model.TwoFactorCodeBody
+	if err := ctx.Bind(&body); err != nil {
+		res.Error = "invalid_data_format"
+		return apis.NewBadRequestError(res.Error, nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	recoveryCodes, err := h.accountService.RegenerateRecoveryCodes(ctx, utils.GetAuthAccountId(ctx), body.Code)
+	if err != nil {
+		return twoFactorError(err)
+	}
+
+	res.RecoveryCodes = recoveryCodes
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *AccountHandler) HandleDisableTwoFactor(ctx echo.Context) error {
+	var body model.TwoFactorCodeBody
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("invalid_data_format", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	if err := h.accountService.DisableTwoFactor(ctx, utils.GetAuthAccountId(ctx), body.Code); err != nil {
+		return twoFactorError(err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// twoFactorError maps the errors of the two factor service methods to api errors.
+func twoFactorError(err error) error {
+	switch err.Error() {
+	case "invalid_code", "invalid_challenge", "challenge_expired":
+		return apis.NewUnauthorizedError(err.Error(), nil)
+	case "too_many_attempts":
+		return apis.NewApiError(http.StatusTooManyRequests, err.Error(), nil)
+	case "two_factor_already_enabled", "two_factor_not_enabled", "two_factor_not_enrolled":
+		return apis.NewApiError(http.StatusConflict, err.Error(), nil)
+	case "two_factor_required":
+		return apis.NewForbiddenError(err.Error(), nil)
+	default:
+		return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("Failed to process two factor request: %v", err), nil)
+	}
+}
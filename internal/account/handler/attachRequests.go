@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+func (h *AccountHandler) HandleRequestAttach(ctx echo.Context) error {
+	res := model.AccountResponse{}
+	var body model.AttachRequestBody
+
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("wrong_data_type", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	request, err := h.accountService.RequestAttach(ctx, ctx.PathParam("id"), body)
+	if err != nil {
+		return attachRequestError(err, "Failed to request specialist")
+	}
+
+	res.Data = request
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *AccountHandler) HandleGetPendingAttachRequests(ctx echo.Context) error {
+	res := model.AccountResponse{}
+
+	requests, err := h.accountService.GetPendingAttachRequests(ctx, ctx.PathParam("id"))
+	if err != nil {
+		res.Error = fmt.Sprintf("Failed to get pending attach requests: %v", err)
+		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+	}
+
+	res.Data = requests
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *AccountHandler) HandleAcceptAttachRequest(ctx echo.Context) error {
+	res := model.AccountResponse{}
+
+	request, err := h.accountService.AcceptAttachRequest(ctx, ctx.PathParam("id"), ctx.PathParam("requestId"))
+	if err != nil {
+		return attachRequestError(err, "Failed to accept attach request")
+	}
+
+	res.Data = request
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *AccountHandler) HandleDeclineAttachRequest(ctx echo.Context) error {
+	res := model.AccountResponse{}
+
+	request, err := h.accountService.DeclineAttachRequest(ctx, ctx.PathParam("id"), ctx.PathParam("requestId"))
+	if err != nil {
+		return attachRequestError(err, "Failed to decline attach request")
+	}
+
+	res.Data = request
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *AccountHandler) HandleCancelAttachRequest(ctx echo.Context) error {
+	res := model.AccountResponse{}
+
+	request, err := h.accountService.CancelAttachRequest(ctx, ctx.PathParam("id"), ctx.PathParam("requestId"))
+	if err != nil {
+		return attachRequestError(err, "Failed to cancel attach request")
+	}
+
+	res.Data = request
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// attachRequestError maps the errors of the attach request service methods to api errors.
+func attachRequestError(err error, message string) error {
+	switch err.Error() {
+	case "not_found":
+		return apis.NewNotFoundError(err.Error(), nil)
+	case "invalid_specialist", "not_a_patient":
+		return apis.NewBadRequestError(err.Error(), nil)
+	case "already_member", "has_care_team", "other_organisation", "request_not_pending", "request_outdated":
+		return apis.NewApiError(http.StatusConflict, err.Error(), nil)
+	case "request_expired":
+		return apis.NewApiError(http.StatusGone, err.Error(), nil)
+	}
+	return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("%s: %v", message, err), nil)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+func (h *AccountHandler) HandleGetCareTeam(ctx echo.Context) error {
+	res := model.AccountResponse{}
+	patientId := ctx.PathParam("id")
+
+	members, err := h.accountService.GetCareTeam(ctx, patientId)
+	if err != nil {
+		res.Error = fmt.Sprintf("Failed to get the care team of account (%s): %v", patientId, err)
+		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+	}
+
+	res.Data = members
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *AccountHandler) HandleAddCareTeamMember(ctx echo.Context) error {
+	res := model.AccountResponse{}
+	var body model.CareTeamMemberBody
+
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("wrong_data_type", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	member, err := h.accountService.AddCareTeamMember(ctx, ctx.PathParam("id"), body)
+	if err != nil {
+		switch err.Error() {
+		case "invalid_specialist", "not_a_patient":
+			return apis.NewBadRequestError(err.Error(), nil)
+		case "already_member", "care_role_taken":
+			return apis.NewApiError(http.StatusConflict, err.Error(), nil)
+		}
+		return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("Failed to add care team member: %v", err), nil)
+	}
+
+	res.Data = member
+	return ctx.JSON(http.StatusCreated, res)
+}
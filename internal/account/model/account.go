@@ -1,25 +1,21 @@
 package model
 
-import (
-	"errors"
-	"fmt"
-	"strings"
-
-	"github.com/arosace/WellnessWaveApi/internal/account/domain"
-	"github.com/arosace/WellnessWaveApi/pkg/utils"
-)
+import "errors"
 
 // Account represents a account in the system.
 type Account struct {
 	ID                string `json:"id,omitempty"`
-	ParentID          string `json:"parent_id"`
+	InvitedBy         string `json:"invited_by"`
 	Role              string `json:"role"`
 	FirstName         string `json:"first_name"`
 	LastName          string `json:"last_name"`
 	Email             string `json:"email"`
 	Password          string `json:"password"`
 	EncryptedPassword string `json:"encrypted_password"`
+	PasswordHash      string `json:"password_hash"`
 	AuthKey           string `json:"auth_key"`
+	TOTPSecret        string `json:"totp_secret" encrypted:"true"`
+	TOTPEnabled       bool   `json:"totp_enabled"`
 	Username          string `json:"username"`
 }
 
@@ -27,42 +23,8 @@ type VerifyAccount struct {
 	JWT string `json:"jwt"`
 }
 
-func (m *Account) ValidateModel() error {
-	var errorStrings []string
-
-	if m.FirstName == "" {
-		errorStrings = append(errorStrings, "first_name")
-	}
-	if m.LastName == "" {
-		errorStrings = append(errorStrings, "last_name")
-	}
-	if m.Email == "" {
-		errorStrings = append(errorStrings, "email")
-	}
-	if m.Role == "" {
-		errorStrings = append(errorStrings, "role")
-	}
-	if m.Password == "" {
-		errorStrings = append(errorStrings, "password")
-	}
-
-	if len(errorStrings) > 0 {
-		return fmt.Errorf("missing_data: %s", strings.Join(errorStrings, ", "))
-	}
-
-	if !utils.PasswordIsValid(m.Password) {
-		return errors.New("invalid_password")
-	}
-
-	if m.Role != domain.HealthSpecialistRole && m.Role != domain.PatientRole {
-		return errors.New("invalid_role")
-	}
-
-	return nil
-}
-
 func (m *Account) ValidateModelForInfoUpdate() error {
-	if m.FirstName == "" && m.LastName == "" && m.ParentID == "" {
+	if m.FirstName == "" && m.LastName == "" {
 		return errors.New("no data to update provided")
 	}
 	if m.ID == "" {
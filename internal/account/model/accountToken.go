@@ -0,0 +1,11 @@
+package model
+
+// AccountToken is a single use token sent to the owner of an account, e.g. to reset their password.
+type AccountToken struct {
+	ID        string `json:"id,omitempty"`
+	AccountID string `json:"account_id"`
+	Purpose   string `json:"purpose"`
+	TokenHash string `json:"token_hash"`
+	ExpiresAt string `json:"expires_at"`
+	Used      bool   `json:"used"`
+}
@@ -13,6 +13,8 @@ type AttachAccountBody struct {
 	Role      string `json:"role"`
 	Email     string `json:"email"`
 	ParentID  string `json:"parent_id"`
+	// CareRole is the role of the parent in the care team of the patient, primary by default.
+	CareRole string `json:"care_role"`
 }
 
 func (m *AttachAccountBody) ValidateModel() error {
@@ -42,5 +44,9 @@ func (m *AttachAccountBody) ValidateModel() error {
 		return fmt.Errorf("invalid_role")
 	}
 
+	if m.CareRole != "" && !domain.IsCareRole(m.CareRole) {
+		return fmt.Errorf("invalid_care_role")
+	}
+
 	return nil
 }
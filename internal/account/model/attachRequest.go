@@ -0,0 +1,25 @@
+package model
+
+import "errors"
+
+// AttachRequest is the request of a patient to be attached to a health specialist, typically one found
+// in the directory, which only takes effect once the specialist accepts it.
+type AttachRequest struct {
+	ID           string `json:"id,omitempty"`
+	PatientID    string `json:"patient_id"`
+	SpecialistID string `json:"specialist_id"`
+	Status       string `json:"status"`
+	ExpiresAt    string `json:"expires_at"`
+	DecidedAt    string `json:"decided_at"`
+}
+
+type AttachRequestBody struct {
+	SpecialistID string `json:"specialist_id"`
+}
+
+func (m *AttachRequestBody) ValidateModel() error {
+	if m.SpecialistID == "" {
+		return errors.New("missing_data: specialist_id")
+	}
+	return nil
+}
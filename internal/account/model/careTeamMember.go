@@ -0,0 +1,44 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+)
+
+// CareTeamMember is a health specialist following a patient with the given care role.
+type CareTeamMember struct {
+	ID           string `json:"id,omitempty"`
+	PatientID    string `json:"patient_id"`
+	SpecialistID string `json:"specialist_id"`
+	Role         string `json:"role"`
+}
+
+type CareTeamMemberBody struct {
+	SpecialistID string `json:"specialist_id"`
+	Role         string `json:"role"`
+}
+
+func (m *CareTeamMemberBody) ValidateModel() error {
+	var missingData []string
+
+	if m.SpecialistID == "" {
+		missingData = append(missingData, "specialist_id")
+	}
+
+	if m.Role == "" {
+		missingData = append(missingData, "role")
+	}
+
+	if len(missingData) > 0 {
+		return fmt.Errorf("missing_data: %s", strings.Join(missingData, ", "))
+	}
+
+	if !domain.IsCareRole(m.Role) {
+		return errors.New("invalid_care_role")
+	}
+
+	return nil
+}
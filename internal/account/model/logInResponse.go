@@ -0,0 +1,9 @@
+package model
+
+// LogInResponse holds either the session, or the two factor challenge to complete first.
+type LogInResponse struct {
+	Data interface{} `json:"data"`
+	*Session
+	*TwoFactorChallenge
+	Error string `json:"error_message"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+)
+
+type ForgotPasswordBody struct {
+	Email string `json:"email"`
+}
+
+func (b *ForgotPasswordBody) ValidateModel() error {
+	if b.Email == "" {
+		return errors.New("missing_data: email")
+	}
+	return nil
+}
+
+// ResetPasswordBody is used both to reset a password and to accept an invitation,
+// in both cases a token received by email is exchanged for a new password.
+type ResetPasswordBody struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (b *ResetPasswordBody) ValidateModel() error {
+	var missingData []string
+	if b.Token == "" {
+		missingData = append(missingData, "token")
+	}
+	if b.Password == "" {
+		missingData = append(missingData, "password")
+	}
+	if len(missingData) > 0 {
+		return fmt.Errorf("missing_data: %s", strings.Join(missingData, ", "))
+	}
+
+	if !utils.PasswordIsValid(b.Password) {
+		return errors.New("invalid_password")
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package model
+
+import "errors"
+
+// RefreshToken is a long lived token used to obtain new access tokens.
+// Tokens rotated from the same log in share a FamilyID.
+type RefreshToken struct {
+	ID         string `json:"id,omitempty"`
+	AccountID  string `json:"account_id"`
+	FamilyID   string `json:"family_id"`
+	TokenHash  string `json:"token_hash"`
+	ExpiresAt  string `json:"expires_at"`
+	Revoked    bool   `json:"revoked"`
+	ReplacedBy string `json:"replaced_by"`
+}
+
+type RefreshTokenBody struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (b *RefreshTokenBody) ValidateModel() error {
+	if b.RefreshToken == "" {
+		return errors.New("missing_data: refresh_token")
+	}
+	return nil
+}
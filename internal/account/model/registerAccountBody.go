@@ -0,0 +1,55 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+)
+
+// RegisterAccountBody is the body of the public registration, it only holds what a visitor may choose
+// about their own account: who invited an account, its credentials and its two factor setup are never
+// taken from it.
+type RegisterAccountBody struct {
+	Role      string `json:"role"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+}
+
+func (m *RegisterAccountBody) ValidateModel() error {
+	var errorStrings []string
+
+	if m.FirstName == "" {
+		errorStrings = append(errorStrings, "first_name")
+	}
+	if m.LastName == "" {
+		errorStrings = append(errorStrings, "last_name")
+	}
+	if m.Email == "" {
+		errorStrings = append(errorStrings, "email")
+	}
+	if m.Role == "" {
+		errorStrings = append(errorStrings, "role")
+	}
+	if m.Password == "" {
+		errorStrings = append(errorStrings, "password")
+	}
+
+	if len(errorStrings) > 0 {
+		return fmt.Errorf("missing_data: %s", strings.Join(errorStrings, ", "))
+	}
+
+	if !utils.PasswordIsValid(m.Password) {
+		return errors.New("invalid_password")
+	}
+
+	if m.Role != domain.HealthSpecialistRole && m.Role != domain.PatientRole {
+		return errors.New("invalid_role")
+	}
+
+	return nil
+}
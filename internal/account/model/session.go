@@ -0,0 +1,9 @@
+package model
+
+// Session holds the tokens handed to a client after a successful log in or refresh.
+type Session struct {
+	AccessToken           string `json:"access_token"`
+	ExpiresAt             int64  `json:"expires_at"`
+	RefreshToken          string `json:"refresh_token"`
+	RefreshTokenExpiresAt int64  `json:"refresh_token_expires_at"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "errors"
+
+// TokenBody carries a single use token received by email, e.g. to unlock an account or cancel its deletion.
+type TokenBody struct {
+	Token string `json:"token"`
+}
+
+func (b *TokenBody) ValidateModel() error {
+	if b.Token == "" {
+		return errors.New("missing_data: token")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// TwoFactorChallenge is returned at log in instead of a session when a second factor is needed.
+// EnrollmentRequired is set when the account must enable two factor authentication first.
+type TwoFactorChallenge struct {
+	MFARequired        bool   `json:"mfa_required"`
+	EnrollmentRequired bool   `json:"mfa_enrollment_required"`
+	ChallengeToken     string `json:"challenge_token"`
+	ChallengeExpiresAt int64  `json:"challenge_expires_at"`
+}
+
+// TwoFactorEnrollment holds the secret to be added to an authenticator app, either typed in
+// or scanned from a QR code of the provisioning uri.
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Data  *TwoFactorEnrollment `json:"data"`
+	Error string               `json:"error_message"`
+}
+
+// RecoveryCodesResponse hands out the recovery codes, they are only shown once. The session is
+// set when two factor authentication was enabled with an enrollment token during log in.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+	*Session
+	Error string `json:"error_message"`
+}
+
+type TwoFactorCodeBody struct {
+	Code string `json:"code"`
+}
+
+func (b *TwoFactorCodeBody) ValidateModel() error {
+	if strings.TrimSpace(b.Code) == "" {
+		return errors.New("missing_data: code")
+	}
+	return nil
+}
+
+// TwoFactorLogInBody completes a log in challenge with either a code or a recovery code.
+type TwoFactorLogInBody struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+func (b *TwoFactorLogInBody) ValidateModel() error {
+	if b.ChallengeToken == "" {
+		return errors.New("missing_data: challenge_token")
+	}
+	if (b.Code == "") == (b.RecoveryCode == "") {
+		return errors.New("invalid_data: either code or recovery_code is required")
+	}
+	return nil
+}
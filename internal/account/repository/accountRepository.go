@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/arosace/WellnessWaveApi/internal/account/domain"
 	"github.com/arosace/WellnessWaveApi/internal/account/model"
@@ -12,26 +13,35 @@ import (
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/daos"
 	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
 )
 
 // AccountRepository defines the interface for account data access.
 type AccountRepository interface {
 	Add(echo.Context, model.Account) (*models.Record, error)
 	Update(echo.Context, *models.Record) (*models.Record, error)
-	Attach(echo.Context, *models.Record, string) (*models.Record, error)
 	UpdateVerify(echo.Context, *models.Record) error
-	List(echo.Context) ([]*models.Record, error)
+	UseTOTPStep(echo.Context, *models.Record, int64) (bool, error)
 	FindByID(echo.Context, string) (*models.Record, error)
 	FindByEmail(echo.Context, string) (*models.Record, error)
 	FindByParentID(echo.Context, string) ([]*models.Record, error)
+	FindPendingDeletionByParentID(echo.Context, string) ([]*models.Record, error)
+	FindDueForErasure(echo.Context, string) ([]*models.Record, error)
+	Delete(echo.Context, *models.Record) error
+	FindWithEncryptedFields(echo.Context, []string) ([]*models.Record, error)
+	Reencrypt(echo.Context) (int, error)
 }
 
 type AccountRepo struct {
-	Dao *daos.Dao
+	Dao    *daos.Dao
+	Cipher *utils.FieldCipher
 }
 
-func NewAccountRepository(dao *daos.Dao) *AccountRepo {
-	return &AccountRepo{Dao: dao}
+func NewAccountRepository(dao *daos.Dao, encryptor utils.Encryption) *AccountRepo {
+	return &AccountRepo{
+		Dao:    dao,
+		Cipher: utils.NewFieldCipher(encryptor),
+	}
 }
 
 func (r *AccountRepo) Add(ctx echo.Context, account model.Account) (*models.Record, error) {
@@ -42,6 +52,7 @@ func (r *AccountRepo) Add(ctx echo.Context, account model.Account) (*models.Reco
 
 	record := models.NewRecord(collection)
 	utils.LoadFromStruct(record, &account)
+	utils.StampTenant(ctx, record)
 	record.SetPassword(account.Password)
 	if account.Role == domain.PatientRole {
 		err := record.SetEmailVisibility(true)
@@ -50,34 +61,15 @@ func (r *AccountRepo) Add(ctx echo.Context, account model.Account) (*models.Reco
 		}
 	}
 
-	if err := r.Dao.SaveRecord(record); err != nil {
+	if err := r.save(record); err != nil {
 		return nil, fmt.Errorf("Failed to save account: %w", err)
 	}
 
 	return record, nil
 }
 
-func (r *AccountRepo) List(ctx echo.Context) ([]*models.Record, error) {
-	query := r.Dao.RecordQuery(domain.TableName)
-
-	records := []*models.Record{}
-	if err := query.All(&records); err != nil {
-		return nil, err
-	}
-
-	return records, nil
-}
-
-func (r *AccountRepo) Attach(ctx echo.Context, account *models.Record, parentId string) (*models.Record, error) {
-	account.Set("parent_id", parentId)
-
-	if err := r.Dao.SaveRecord(account); err != nil {
-		return nil, err
-	}
-	return account, nil
-}
-
-// FindByID returns a account by their ID.
+// FindByID returns a account by their ID, within the tenant of the request. The account of the caller
+// is always visible to them, even with a token issued before they joined an organisation.
 func (r *AccountRepo) FindByID(ctx echo.Context, id string) (*models.Record, error) {
 	record, err := r.Dao.FindRecordById(domain.TableName, id)
 	if err != nil {
@@ -86,6 +78,14 @@ func (r *AccountRepo) FindByID(ctx echo.Context, id string) (*models.Record, err
 	if record.Id == "" {
 		return nil, errors.New("not_found")
 	}
+	if ctx == nil || id != utils.GetAuthAccountId(ctx) {
+		if err := utils.CheckTenant(ctx, record, "id"); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.Cipher.Open(record, model.Account{}); err != nil {
+		return nil, err
+	}
 	return record, nil
 }
 
@@ -99,12 +99,15 @@ func (r *AccountRepo) FindByEmail(ctx echo.Context, email string) (*models.Recor
 	if err != nil {
 		return nil, errors.New("not_found")
 	}
+	if err := r.Cipher.Open(record, model.Account{}); err != nil {
+		return nil, err
+	}
 
 	return record, nil
 }
 
 func (r *AccountRepo) Update(ctx echo.Context, account *models.Record) (*models.Record, error) {
-	if err := r.Dao.SaveRecord(account); err != nil {
+	if err := r.save(account); err != nil {
 		return nil, err
 	}
 	return account, nil
@@ -113,27 +116,131 @@ func (r *AccountRepo) Update(ctx echo.Context, account *models.Record) (*models.
 func (r *AccountRepo) UpdateVerify(ctx echo.Context, account *models.Record) error {
 	account.Set("verified", true)
 
-	if err := r.Dao.SaveRecord(account); err != nil {
+	if err := r.save(account); err != nil {
 		return err
 	}
 	return nil
 }
 
+// FindByParentID returns the patients whose care team the parent is a member of, that are not pending deletion.
 func (r *AccountRepo) FindByParentID(ctx echo.Context, parentId string) ([]*models.Record, error) {
+	records, err := r.findByCareTeamMember(ctx, parentId, dbx.HashExp{domain.TableName + ".deleted_at": ""}, "username DESC")
+	if err != nil {
+		return nil, fmt.Errorf("there was an error fetching attached accounts: %w", err)
+	}
+	return records, nil
+}
+
+// FindPendingDeletionByParentID returns the patients whose care team the parent is a member of, that their
+// owner deleted and that are waiting for the end of the grace period to be erased.
+func (r *AccountRepo) FindPendingDeletionByParentID(ctx echo.Context, parentId string) ([]*models.Record, error) {
+	records, err := r.findByCareTeamMember(ctx, parentId, dbx.Not(dbx.HashExp{domain.TableName + ".deleted_at": ""}), "erase_after ASC")
+	if err != nil {
+		return nil, fmt.Errorf("there was an error fetching attached accounts pending deletion: %w", err)
+	}
+	return records, nil
+}
+
+func (r *AccountRepo) findByCareTeamMember(ctx echo.Context, specialistId string, where dbx.Expression, orderBy string) ([]*models.Record, error) {
+	records := []*models.Record{}
+	err := r.Dao.RecordQuery(domain.TableName).
+		InnerJoin(domain.CareTeamTableName+" member", dbx.NewExp("member.patient_id = "+domain.TableName+".id")).
+		Where(dbx.HashExp{"member.specialist_id": specialistId}).
+		AndWhere(where).
+		AndWhere(utils.TenantExp(ctx, domain.TableName+"."+utils.TenantField, domain.TableName+".id")).
+		OrderBy(domain.TableName + "." + orderBy).
+		All(&records)
+	if err != nil {
+		return nil, err
+	}
+	return r.Cipher.OpenAll(records, model.Account{}), nil
+}
+
+// FindDueForErasure returns the deleted accounts whose grace period ended before the given date.
+func (r *AccountRepo) FindDueForErasure(ctx echo.Context, before string) ([]*models.Record, error) {
 	records, err := r.Dao.FindRecordsByFilter(
 		domain.TableName,
-		"parent_id = {:parent_id}",
-		"-username",
+		"deleted_at != '' && erase_after != '' && erase_after <= {:before}",
+		"erase_after",
 		-1,
 		0,
-		dbx.Params{"parent_id": parentId},
+		dbx.Params{"before": before},
 	)
 	if err != nil {
-		return nil, fmt.Errorf("there was an error fetching attached accounts: %w", err)
+		return nil, fmt.Errorf("there was an error fetching accounts due for erasure: %w", err)
 	}
 	return records, nil
 }
 
+// UseTOTPStep records the time step of the two factor code used by the account in a single conditional update,
+// so that of the requests presenting the same code at once only one uses it. It reports whether the step was
+// recorded, it was not if the account used the same or a later step in the meantime.
+func (r *AccountRepo) UseTOTPStep(ctx echo.Context, account *models.Record, step int64) (bool, error) {
+	result, err := r.Dao.DB().Update(
+		domain.TableName,
+		dbx.Params{"totp_last_step": step, "updated": types.NowDateTime().String()},
+		dbx.And(dbx.HashExp{"id": account.Id}, dbx.NewExp("totp_last_step < {:step}", dbx.Params{"step": step})),
+	).Execute()
+	if err != nil {
+		return false, fmt.Errorf("there was an error saving the two factor code use: %w", err)
+	}
+	used, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("there was an error saving the two factor code use: %w", err)
+	}
+	if used != 1 {
+		return false, nil
+	}
+
+	account.Set("totp_last_step", step)
+	return true, nil
+}
+
+// Delete removes the account for good, the records referencing it are cleaned up by the delete hooks.
+func (r *AccountRepo) Delete(ctx echo.Context, account *models.Record) error {
+	if err := r.Dao.DeleteRecord(account); err != nil {
+		return fmt.Errorf("there was an error deleting the account: %w", err)
+	}
+	return nil
+}
+
+// FindWithEncryptedFields returns the accounts holding a value in at least one of the given fields.
+func (r *AccountRepo) FindWithEncryptedFields(ctx echo.Context, fields []string) ([]*models.Record, error) {
+	conditions := make([]string, 0, len(fields))
+	for _, field := range fields {
+		conditions = append(conditions, fmt.Sprintf("%s != ''", field))
+	}
+
+	records, err := r.Dao.FindRecordsByFilter(
+		domain.TableName,
+		strings.Join(conditions, " || "),
+		"created",
+		-1,
+		0,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error fetching accounts with encrypted fields: %w", err)
+	}
+	return records, nil
+}
+
+// Reencrypt rewrites the fields of every account tagged as encrypted in model.Account with the active encryption key.
+func (r *AccountRepo) Reencrypt(ctx echo.Context) (int, error) {
+	return r.Cipher.ReencryptCollection(r.Dao, domain.TableName, model.Account{})
+}
+
+// save encrypts the encrypted fields of the account for storage and decrypts them again once saved,
+// so the returned record holds plain text.
+func (r *AccountRepo) save(record *models.Record) error {
+	if err := r.Cipher.Seal(record, model.Account{}); err != nil {
+		return err
+	}
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return err
+	}
+	return r.Cipher.Open(record, model.Account{})
+}
+
 func (r *AccountRepo) LoadFromAccount(record *models.Record, account *model.Account) error {
 	data, err := json.Marshal(account)
 	if err != nil {
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseTOTPStepOnce(t *testing.T) {
+	dao := testutils.NewTestApp(t).Dao()
+	accounts := testutils.NewCollection(t, dao, domain.TableName, testutils.NumberField("totp_last_step"))
+	account := testutils.NewRecord(t, dao, accounts, map[string]any{"totp_last_step": 100})
+
+	repository := NewAccountRepository(dao, testutils.NewEncryptor(t))
+	// both requests read the account before either uses the code
+	stale := account.CleanCopy()
+
+	used, err := repository.UseTOTPStep(nil, account, 101)
+	assert.Nil(t, err)
+	assert.True(t, used)
+	used, err = repository.UseTOTPStep(nil, stale, 101)
+	assert.Nil(t, err)
+	assert.False(t, used, "a code is only used once")
+	used, err = repository.UseTOTPStep(nil, stale, 100)
+	assert.Nil(t, err)
+	assert.False(t, used, "codes older than the last one used are refused")
+
+	stored, err := dao.FindRecordById(domain.TableName, account.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 101, stored.GetInt("totp_last_step"))
+	assert.Equal(t, 100, stale.GetInt("totp_last_step"))
+}
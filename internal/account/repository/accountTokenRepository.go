@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// AccountTokenRepository defines the interface for single use account token data access.
+type AccountTokenRepository interface {
+	Add(echo.Context, model.AccountToken) (*models.Record, error)
+	FindUnused(echo.Context, string, string) (*models.Record, error)
+	FindUnusedByAccountId(echo.Context, string, string) ([]*models.Record, error)
+	Consume(echo.Context, *models.Record) (bool, error)
+	InvalidateByAccountId(echo.Context, string, string) error
+}
+
+type AccountTokenRepo struct {
+	Dao *daos.Dao
+}
+
+func NewAccountTokenRepository(dao *daos.Dao) *AccountTokenRepo {
+	return &AccountTokenRepo{Dao: dao}
+}
+
+func (r *AccountTokenRepo) Add(ctx echo.Context, token model.AccountToken) (*models.Record, error) {
+	collection, err := r.Dao.FindCollectionByNameOrId(domain.AccountTokensTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.NewRecord(collection)
+	utils.LoadFromStruct(record, &token)
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("Failed to save account token: %w", err)
+	}
+
+	return record, nil
+}
+
+// FindUnused returns the not yet used token with the given purpose and hash.
+func (r *AccountTokenRepo) FindUnused(ctx echo.Context, purpose string, tokenHash string) (*models.Record, error) {
+	record, err := r.Dao.FindFirstRecordByFilter(
+		domain.AccountTokensTableName,
+		"purpose = {:purpose} && token_hash = {:token_hash} && used = false",
+		dbx.Params{
+			"purpose":    purpose,
+			"token_hash": tokenHash,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving %s token: %w", purpose, err)
+	}
+	return record, nil
+}
+
+// FindUnusedByAccountId returns the not yet used tokens with the given purpose of an account.
+func (r *AccountTokenRepo) FindUnusedByAccountId(ctx echo.Context, accountId string, purpose string) ([]*models.Record, error) {
+	records, err := r.Dao.FindRecordsByFilter(
+		domain.AccountTokensTableName,
+		"account_id = {:account_id} && purpose = {:purpose} && used = false",
+		"",
+		-1,
+		0,
+		dbx.Params{
+			"account_id": accountId,
+			"purpose":    purpose,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving %s tokens: %w", purpose, err)
+	}
+	return records, nil
+}
+
+// Consume marks the token as used with a single conditional update, so that of the requests presenting
+// the same token at once only one consumes it. It reports whether the token was consumed, it was not if
+// it had been used or invalidated in the meantime.
+func (r *AccountTokenRepo) Consume(ctx echo.Context, token *models.Record) (bool, error) {
+	consumed := false
+	err := r.Dao.RunInTransaction(func(txDao *daos.Dao) error {
+		result, err := txDao.DB().Update(
+			domain.AccountTokensTableName,
+			dbx.Params{"used": true, "updated": types.NowDateTime().String()},
+			dbx.HashExp{"id": token.Id, "used": false},
+		).Execute()
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		consumed = rows == 1
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("there was an error consuming %s token: %w", token.GetString("purpose"), err)
+	}
+
+	if consumed {
+		token.Set("used", true)
+	}
+	return consumed, nil
+}
+
+// InvalidateByAccountId marks every unused token with the given purpose of an account as used.
+func (r *AccountTokenRepo) InvalidateByAccountId(ctx echo.Context, accountId string, purpose string) error {
+	return r.Dao.RunInTransaction(func(txDao *daos.Dao) error {
+		records, err := txDao.FindRecordsByFilter(
+			domain.AccountTokensTableName,
+			"account_id = {:account_id} && purpose = {:purpose} && used = false",
+			"",
+			-1,
+			0,
+			dbx.Params{
+				"account_id": accountId,
+				"purpose":    purpose,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("there was an error retrieving %s tokens to invalidate: %w", purpose, err)
+		}
+
+		for _, record := range records {
+			record.Set("used", true)
+			if err := txDao.SaveRecord(record); err != nil {
+				return fmt.Errorf("there was an error invalidating %s token: %w", purpose, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/arosace/WellnessWaveApi/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsumeOnce(t *testing.T) {
+	dao := testutils.NewTestApp(t).Dao()
+
+	testutils.NewCollection(t, dao, domain.AccountTokensTableName, append(
+		testutils.TextFields("account_id", "purpose", "token_hash"),
+		testutils.DateField("expires_at"),
+		testutils.BoolField("used"),
+	)...)
+
+	repository := NewAccountTokenRepository(dao)
+	if _, err := repository.Add(nil, model.AccountToken{AccountID: "account", Purpose: domain.PasswordResetPurpose, TokenHash: "hash"}); err != nil {
+		t.Fatal(err)
+	}
+	// both requests read the token before either consumes it
+	token, err := repository.FindUnused(nil, domain.PasswordResetPurpose, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := repository.FindUnused(nil, domain.PasswordResetPurpose, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consumed, err := repository.Consume(nil, token)
+	assert.Nil(t, err)
+	assert.True(t, consumed)
+	consumed, err = repository.Consume(nil, stale)
+	assert.Nil(t, err)
+	assert.False(t, consumed, "a token is only consumed once")
+
+	_, err = repository.FindUnused(nil, domain.PasswordResetPurpose, "hash")
+	assert.NotNil(t, err)
+}
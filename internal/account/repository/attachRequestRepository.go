@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// AttachRequestRepository defines the interface for attach request data access. Requests are made
+// across organisations, from patients to any health specialist, so they are not scoped to a tenant.
+type AttachRequestRepository interface {
+	Add(echo.Context, model.AttachRequest) (*models.Record, error)
+	FindByID(echo.Context, string) (*models.Record, error)
+	FindPendingByAccountId(echo.Context, string) ([]*models.Record, error)
+	Update(echo.Context, *models.Record) (*models.Record, error)
+	CancelPending(echo.Context, string, string) error
+}
+
+type AttachRequestRepo struct {
+	Dao *daos.Dao
+}
+
+func NewAttachRequestRepository(dao *daos.Dao) *AttachRequestRepo {
+	return &AttachRequestRepo{Dao: dao}
+}
+
+func (r *AttachRequestRepo) Add(ctx echo.Context, request model.AttachRequest) (*models.Record, error) {
+	collection, err := r.Dao.FindCollectionByNameOrId(domain.AttachRequestsTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.NewRecord(collection)
+	utils.LoadFromStruct(record, &request)
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("Failed to save attach request: %w", err)
+	}
+
+	return record, nil
+}
+
+func (r *AttachRequestRepo) FindByID(ctx echo.Context, id string) (*models.Record, error) {
+	record, err := r.Dao.FindRecordById(domain.AttachRequestsTableName, id)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving attach request [%s]: %w", id, err)
+	}
+	return record, nil
+}
+
+// FindPendingByAccountId returns the pending requests the account made as a patient or received as a health specialist.
+func (r *AttachRequestRepo) FindPendingByAccountId(ctx echo.Context, accountId string) ([]*models.Record, error) {
+	records, err := r.Dao.FindRecordsByFilter(
+		domain.AttachRequestsTableName,
+		"(patient_id = {:account_id} || specialist_id = {:account_id}) && status = {:status}",
+		"-created",
+		-1,
+		0,
+		dbx.Params{"account_id": accountId, "status": domain.AttachRequestPending},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving pending attach requests of [%s]: %w", accountId, err)
+	}
+	return records, nil
+}
+
+func (r *AttachRequestRepo) Update(ctx echo.Context, record *models.Record) (*models.Record, error) {
+	record.MarkAsNotNew()
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("there was an error updating attach request: %w", err)
+	}
+	return record, nil
+}
+
+// CancelPending cancels the requests of the patient to the health specialist still waiting for an answer.
+func (r *AttachRequestRepo) CancelPending(ctx echo.Context, patientId string, specialistId string) error {
+	_, err := r.Dao.DB().Update(
+		domain.AttachRequestsTableName,
+		dbx.Params{"status": domain.AttachRequestCancelled},
+		dbx.HashExp{"patient_id": patientId, "specialist_id": specialistId, "status": domain.AttachRequestPending},
+	).Execute()
+	if err != nil {
+		return fmt.Errorf("there was an error cancelling pending attach requests of [%s]: %w", patientId, err)
+	}
+	return nil
+}
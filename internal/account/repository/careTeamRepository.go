@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// CareTeamRepository defines the interface for care team data access.
+type CareTeamRepository interface {
+	Add(echo.Context, model.CareTeamMember) (*models.Record, error)
+	FindByPatientId(echo.Context, string) ([]*models.Record, error)
+	FindMember(echo.Context, string, string) (*models.Record, error)
+	Update(echo.Context, *models.Record) (*models.Record, error)
+	Remove(echo.Context, *models.Record) error
+	MigrateParentLinks(echo.Context) (int, error)
+}
+
+type CareTeamRepo struct {
+	Dao *daos.Dao
+}
+
+func NewCareTeamRepository(dao *daos.Dao) *CareTeamRepo {
+	return &CareTeamRepo{Dao: dao}
+}
+
+func (r *CareTeamRepo) Add(ctx echo.Context, member model.CareTeamMember) (*models.Record, error) {
+	collection, err := r.Dao.FindCollectionByNameOrId(domain.CareTeamTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.NewRecord(collection)
+	utils.LoadFromStruct(record, &member)
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("Failed to save care team member: %w", err)
+	}
+
+	return record, nil
+}
+
+// FindByPatientId returns the members of the care team of the patient.
+func (r *CareTeamRepo) FindByPatientId(ctx echo.Context, patientId string) ([]*models.Record, error) {
+	records, err := r.Dao.FindRecordsByFilter(
+		domain.CareTeamTableName,
+		"patient_id = {:patient_id}",
+		"created",
+		-1,
+		0,
+		dbx.Params{"patient_id": patientId},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving the care team of patient [%s]: %w", patientId, err)
+	}
+	return records, nil
+}
+
+// FindMember returns the membership of the specialist in the care team of the patient.
+func (r *CareTeamRepo) FindMember(ctx echo.Context, patientId string, specialistId string) (*models.Record, error) {
+	record, err := r.Dao.FindFirstRecordByFilter(
+		domain.CareTeamTableName,
+		"patient_id = {:patient_id} && specialist_id = {:specialist_id}",
+		dbx.Params{
+			"patient_id":    patientId,
+			"specialist_id": specialistId,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving care team member: %w", err)
+	}
+	return record, nil
+}
+
+func (r *CareTeamRepo) Update(ctx echo.Context, record *models.Record) (*models.Record, error) {
+	record.MarkAsNotNew()
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("there was an error updating care team member: %w", err)
+	}
+	return record, nil
+}
+
+func (r *CareTeamRepo) Remove(ctx echo.Context, record *models.Record) error {
+	if err := r.Dao.DeleteRecord(record); err != nil {
+		return fmt.Errorf("there was an error removing care team member: %w", err)
+	}
+	return nil
+}
+
+// MigrateParentLinks turns the parent_id of the accounts attached before care teams existed into
+// primary memberships and clears it, so it can be run on every start.
+func (r *CareTeamRepo) MigrateParentLinks(ctx echo.Context) (int, error) {
+	accounts, err := r.Dao.FindRecordsByFilter(domain.TableName, "parent_id != ''", "", -1, 0)
+	if err != nil {
+		return 0, fmt.Errorf("there was an error fetching accounts with a parent: %w", err)
+	}
+
+	migrated := 0
+	for _, account := range accounts {
+		err := r.Dao.RunInTransaction(func(txDao *daos.Dao) error {
+			txRepo := NewCareTeamRepository(txDao)
+			parentId := account.GetString("parent_id")
+			if _, err := txRepo.FindMember(ctx, account.Id, parentId); err != nil {
+				if !utils.IsErrorNotFound(err) {
+					return err
+				}
+				member := model.CareTeamMember{PatientID: account.Id, SpecialistID: parentId, Role: domain.PrimaryCareRole}
+				if _, err := txRepo.Add(ctx, member); err != nil {
+					return err
+				}
+			}
+			account.Set("parent_id", "")
+			return txDao.SaveRecord(account)
+		})
+		if err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
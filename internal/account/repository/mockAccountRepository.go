@@ -103,17 +103,3 @@ func (r *MockAccountRepository) UpdateAuth(ctx echo.Context, user *model.Account
 
 	return user, nil
 }
-
-func (r *MockAccountRepository) FindByParentID(ctx echo.Context, parentId string) ([]*model.Account, error) {
-	r.mux.RLock()
-	defer r.mux.RUnlock()
-
-	accounts := make([]*model.Account, 0)
-	for _, a := range r.accounts {
-		if a.ParentID == parentId {
-			accounts = append(accounts, a)
-		}
-	}
-
-	return accounts, nil
-}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// RefreshTokenRepository defines the interface for refresh token data access.
+type RefreshTokenRepository interface {
+	Add(echo.Context, model.RefreshToken) (*models.Record, error)
+	FindByHash(echo.Context, string) (*models.Record, error)
+	Rotate(echo.Context, *models.Record, string) (bool, error)
+	RevokeFamily(echo.Context, string) error
+	RevokeByAccountId(echo.Context, string) error
+}
+
+type RefreshTokenRepo struct {
+	Dao *daos.Dao
+}
+
+func NewRefreshTokenRepository(dao *daos.Dao) *RefreshTokenRepo {
+	return &RefreshTokenRepo{Dao: dao}
+}
+
+func (r *RefreshTokenRepo) Add(ctx echo.Context, token model.RefreshToken) (*models.Record, error) {
+	collection, err := r.Dao.FindCollectionByNameOrId(domain.RefreshTokensTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.NewRecord(collection)
+	utils.LoadFromStruct(record, &token)
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("Failed to save refresh token: %w", err)
+	}
+
+	return record, nil
+}
+
+// FindByHash returns the refresh token stored with the given hash.
+func (r *RefreshTokenRepo) FindByHash(ctx echo.Context, tokenHash string) (*models.Record, error) {
+	record, err := r.Dao.FindFirstRecordByFilter(
+		domain.RefreshTokensTableName,
+		"token_hash = {:token_hash}",
+		dbx.Params{"token_hash": tokenHash},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving refresh token: %w", err)
+	}
+	return record, nil
+}
+
+// Rotate revokes the refresh token in favour of the one with the given hash in a single conditional update,
+// so that of the requests presenting the same token at once only one rotates it. It reports whether the
+// token was rotated, it was not if it had been revoked in the meantime.
+func (r *RefreshTokenRepo) Rotate(ctx echo.Context, token *models.Record, replacedBy string) (bool, error) {
+	result, err := r.Dao.DB().Update(
+		domain.RefreshTokensTableName,
+		dbx.Params{"revoked": true, "replaced_by": replacedBy, "updated": types.NowDateTime().String()},
+		dbx.HashExp{"id": token.Id, "revoked": false},
+	).Execute()
+	if err != nil {
+		return false, fmt.Errorf("there was an error rotating refresh token: %w", err)
+	}
+	rotated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("there was an error rotating refresh token: %w", err)
+	}
+	if rotated == 0 {
+		return false, nil
+	}
+
+	token.Set("revoked", true)
+	token.Set("replaced_by", replacedBy)
+	return true, nil
+}
+
+// RevokeFamily revokes every refresh token rotated from the same log in.
+func (r *RefreshTokenRepo) RevokeFamily(ctx echo.Context, familyId string) error {
+	return r.revokeByFilter("family_id = {:family_id}", dbx.Params{"family_id": familyId})
+}
+
+// RevokeByAccountId revokes every refresh token of an account, logging it out of all devices.
+func (r *RefreshTokenRepo) RevokeByAccountId(ctx echo.Context, accountId string) error {
+	return r.revokeByFilter("account_id = {:account_id}", dbx.Params{"account_id": accountId})
+}
+
+func (r *RefreshTokenRepo) revokeByFilter(filter string, params dbx.Params) error {
+	return r.Dao.RunInTransaction(func(txDao *daos.Dao) error {
+		records, err := txDao.FindRecordsByFilter(
+			domain.RefreshTokensTableName,
+			filter+" && revoked = false",
+			"",
+			-1,
+			0,
+			params,
+		)
+		if err != nil {
+			return fmt.Errorf("there was an error retrieving refresh tokens to revoke: %w", err)
+		}
+
+		for _, record := range records {
+			record.Set("revoked", true)
+			if err := txDao.SaveRecord(record); err != nil {
+				return fmt.Errorf("there was an error revoking refresh token: %w", err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/arosace/WellnessWaveApi/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotateOnce(t *testing.T) {
+	dao := testutils.NewTestApp(t).Dao()
+
+	testutils.NewCollection(t, dao, domain.RefreshTokensTableName, append(
+		testutils.TextFields("account_id", "family_id", "token_hash", "replaced_by"),
+		testutils.DateField("expires_at"),
+		testutils.BoolField("revoked"),
+	)...)
+
+	repository := NewRefreshTokenRepository(dao)
+	token, err := repository.Add(nil, model.RefreshToken{AccountID: "account", FamilyID: "family", TokenHash: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// both requests read the token before either rotates it
+	stale, err := repository.FindByHash(nil, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := repository.Rotate(nil, token, "second")
+	assert.Nil(t, err)
+	assert.True(t, rotated)
+	rotated, err = repository.Rotate(nil, stale, "third")
+	assert.Nil(t, err)
+	assert.False(t, rotated, "a token is only rotated once")
+
+	stored, err := repository.FindByHash(nil, "first")
+	assert.Nil(t, err)
+	assert.True(t, stored.GetBool("revoked"))
+	assert.Equal(t, "second", stored.GetString("replaced_by"))
+}
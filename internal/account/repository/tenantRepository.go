@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+// TenantRepo resolves the scoping of the requests of accounts, see utils.TenantResolver.
+type TenantRepo struct {
+	Dao *daos.Dao
+}
+
+func NewTenantRepository(dao *daos.Dao) *TenantRepo {
+	return &TenantRepo{Dao: dao}
+}
+
+// Tenant returns the organisation the account currently belongs to and its role in it.
+func (r *TenantRepo) Tenant(accountId string) (utils.Tenant, error) {
+	var tenant struct {
+		OrganisationID   string `db:"organisation_id"`
+		OrganisationRole string `db:"organisation_role"`
+	}
+	err := r.Dao.DB().
+		Select("organisation_id", "organisation_role").
+		From(domain.TableName).
+		Where(dbx.HashExp{"id": accountId}).
+		One(&tenant)
+	if err != nil {
+		return utils.Tenant{}, fmt.Errorf("there was an error retrieving the organisation of account [%s]: %w", accountId, err)
+	}
+	return utils.Tenant{OrganisationID: tenant.OrganisationID, OrganisationRole: tenant.OrganisationRole}, nil
+}
+
+// RelatedAccounts returns the members of the care team of the account, for patients, and the patients
+// whose care team the account is a member of with their other members, for health specialists.
+func (r *TenantRepo) RelatedAccounts(accountId string) ([]string, error) {
+	var members []struct {
+		PatientID    string `db:"patient_id"`
+		SpecialistID string `db:"specialist_id"`
+	}
+	err := r.Dao.DB().
+		Select("patient_id", "specialist_id").
+		From(domain.CareTeamTableName).
+		Where(dbx.Or(
+			dbx.HashExp{"patient_id": accountId},
+			dbx.NewExp("patient_id IN (SELECT patient_id FROM "+domain.CareTeamTableName+" WHERE specialist_id = {:account_id})", dbx.Params{"account_id": accountId}),
+		)).
+		All(&members)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving the care teams of account [%s]: %w", accountId, err)
+	}
+
+	seen := map[string]bool{accountId: true}
+	accountIds := []string{}
+	for _, member := range members {
+		for _, id := range []string{member.PatientID, member.SpecialistID} {
+			if !seen[id] {
+				seen[id] = true
+				accountIds = append(accountIds, id)
+			}
+		}
+	}
+	return accountIds, nil
+}
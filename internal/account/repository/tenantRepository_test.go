@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelatedAccounts(t *testing.T) {
+	dao := testutils.NewTestApp(t).Dao()
+
+	careTeams := testutils.NewCollection(t, dao, domain.CareTeamTableName, testutils.TextFields("patient_id", "specialist_id")...)
+	for _, member := range [][2]string{{"p1", "s1"}, {"p1", "s2"}, {"p2", "s1"}, {"p3", "s3"}} {
+		testutils.NewRecord(t, dao, careTeams, map[string]any{"patient_id": member[0], "specialist_id": member[1]})
+	}
+
+	repository := NewTenantRepository(dao)
+	related, err := repository.RelatedAccounts("s1")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"p1", "s2", "p2"}, related, "specialists are related to their patients and their other specialists")
+
+	related, err = repository.RelatedAccounts("p1")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"s1", "s2"}, related, "patients are related to their care team only")
+
+	related, err = repository.RelatedAccounts("stranger")
+	assert.Nil(t, err)
+	assert.Empty(t, related)
+}
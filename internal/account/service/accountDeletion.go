@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// DeleteAccount deletes the account softly: it is logged out of every device, cannot log in anymore and
+// is erased with its data once the grace period is over, unless the owner cancels with the emailed link.
+func (s *accountService) DeleteAccount(ctx echo.Context, accountId string) error {
+	account, err := s.accountRepository.FindByID(ctx, accountId)
+	if err != nil {
+		return err
+	}
+	if isDeleted(account) {
+		return errors.New("already_deleted")
+	}
+
+	now := time.Now()
+	deletedAt, err := types.ParseDateTime(now)
+	if err != nil {
+		return err
+	}
+	eraseAfter, err := types.ParseDateTime(now.Add(domain.DeletionGracePeriod))
+	if err != nil {
+		return err
+	}
+	account.Set("deleted_at", deletedAt.String())
+	account.Set("erase_after", eraseAfter.String())
+	if _, err := s.accountRepository.Update(ctx, account); err != nil {
+		return fmt.Errorf("there was an error deleting the account: %w", err)
+	}
+
+	if err := s.refreshTokenRepository.RevokeByAccountId(ctx, account.Id); err != nil {
+		return err
+	}
+
+	token, err := s.issueAccountToken(ctx, account.Id, domain.CancelDeletionPurpose, domain.DeletionGracePeriod)
+	if err != nil {
+		return err
+	}
+	if err := utils.SendAccountDeletionEmail(s.mailer, account.GetString("username"), account.Email(), token, eraseAfter.Time()); err != nil {
+		return fmt.Errorf("Failed to send email: %w", err)
+	}
+	return nil
+}
+
+// CancelDeletion restores the account the cancellation token was emailed to, if it was not erased yet.
+func (s *accountService) CancelDeletion(ctx echo.Context, token string) error {
+	account, err := s.consumeAccountToken(ctx, domain.CancelDeletionPurpose, token)
+	if err != nil {
+		return err
+	}
+
+	account.Set("deleted_at", "")
+	account.Set("erase_after", "")
+	if _, err := s.accountRepository.Update(ctx, account); err != nil {
+		return fmt.Errorf("there was an error cancelling the account deletion: %w", err)
+	}
+	return s.accountTokenRepository.InvalidateByAccountId(ctx, account.Id, domain.CancelDeletionPurpose)
+}
+
+// GetPatientsPendingDeletion returns the patients of the health specialist that deleted their account
+// and will be erased at the end of the grace period.
+func (s *accountService) GetPatientsPendingDeletion(ctx echo.Context, parentId string) ([]*models.Record, error) {
+	return s.accountRepository.FindPendingDeletionByParentID(ctx, parentId)
+}
+
+func isDeleted(account *models.Record) bool {
+	return !account.GetDateTime("deleted_at").IsZero()
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	auditDomain "github.com/arosace/WellnessWaveApi/internal/audit/domain"
+	auditModel "github.com/arosace/WellnessWaveApi/internal/audit/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// errInvalidCredentials is returned for every failed login, whether the account does not exist, is not
+// verified, is locked or the password is wrong, so the response cannot be used to discover accounts.
+var errInvalidCredentials = errors.New("invalid_credentials")
+
+// UnlockAccount unlocks the account the unlock token was emailed to and resets its failed login count.
+func (s *accountService) UnlockAccount(ctx echo.Context, token string) error {
+	account, err := s.consumeAccountToken(ctx, domain.UnlockPurpose, token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.resetLoginFailures(ctx, account); err != nil {
+		return err
+	}
+	return s.accountTokenRepository.InvalidateByAccountId(ctx, account.Id, domain.UnlockPurpose)
+}
+
+func isLocked(account *models.Record) bool {
+	return account.GetDateTime("locked_until").Time().After(time.Now())
+}
+
+// recordLoginFailure counts a failed login on the account and locks it once it reaches the threshold.
+// Failures are only counted while the account is unlocked, so every failure after a lock expired
+// locks the account again for twice as long.
+func (s *accountService) recordLoginFailure(ctx echo.Context, account *models.Record) error {
+	attempts := account.GetInt("failed_login_attempts") + 1
+	account.Set("failed_login_attempts", attempts)
+
+	lockDuration := utils.BackoffDelay(attempts, domain.LoginFailureThreshold, domain.LockoutBaseDuration, domain.LockoutMaxDuration)
+	var lockedUntil types.DateTime
+	if lockDuration > 0 {
+		var err error
+		lockedUntil, err = types.ParseDateTime(time.Now().Add(lockDuration))
+		if err != nil {
+			return err
+		}
+		account.Set("locked_until", lockedUntil.String())
+	}
+
+	if _, err := s.accountRepository.Update(ctx, account); err != nil {
+		return fmt.Errorf("there was an error counting the failed login: %w", err)
+	}
+
+	if lockDuration > 0 {
+		// the lock is in place, failing to notify the owner must not tell the caller the account exists
+		if err := s.notifyLockout(ctx, account, attempts, lockedUntil); err != nil {
+			log.Printf("Failed to notify lockout of account %s: %v", account.Id, err)
+		}
+	}
+	return nil
+}
+
+// notifyLockout records the lockout in the audit log and emails the owner a link to unlock their account.
+func (s *accountService) notifyLockout(ctx echo.Context, account *models.Record, attempts int, lockedUntil types.DateTime) error {
+	patientId := ""
+	if account.GetString("role") == domain.PatientRole {
+		patientId = account.Id
+	}
+	err := s.auditService.Record(ctx, auditModel.AuditEntry{
+		Action:           auditDomain.AccountLockedAction,
+		Outcome:          auditDomain.SuccessOutcome,
+		TargetCollection: domain.TableName,
+		TargetID:         account.Id,
+		PatientID:        patientId,
+		Details: map[string]interface{}{
+			"failed_login_attempts": attempts,
+			"locked_until":          lockedUntil.String(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	token, err := s.issueAccountToken(ctx, account.Id, domain.UnlockPurpose, domain.UnlockTokenDuration)
+	if err != nil {
+		return err
+	}
+	if err := utils.SendAccountLockedEmail(s.mailer, account.GetString("username"), account.Email(), token); err != nil {
+		return fmt.Errorf("Failed to send email: %w", err)
+	}
+	return nil
+}
+
+// resetLoginFailures clears the failed login count and lock of the account, if any.
+func (s *accountService) resetLoginFailures(ctx echo.Context, account *models.Record) error {
+	if account.GetInt("failed_login_attempts") == 0 && account.GetDateTime("locked_until").IsZero() {
+		return nil
+	}
+	account.Set("failed_login_attempts", 0)
+	account.Set("locked_until", "")
+	if _, err := s.accountRepository.Update(ctx, account); err != nil {
+		return fmt.Errorf("there was an error resetting the failed logins: %w", err)
+	}
+	return nil
+}
+
+// verifyDummyPassword spends the time of a password check when there is no password to check,
+// so that unknown and locked accounts cannot be told apart by how long the login takes.
+func (s *accountService) verifyDummyPassword(password string) {
+	s.dummyPasswordOnce.Do(func() {
+		s.dummyPasswordHash, _ = s.passwordHasher.Hash("dummy password used for timing")
+	})
+	if s.dummyPasswordHash != "" {
+		s.passwordHasher.Verify(password, s.dummyPasswordHash)
+	}
+}
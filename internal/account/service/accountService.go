@@ -1,56 +1,137 @@
 package service
 
 import (
+	"crypto/subtle"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/arosace/WellnessWaveApi/internal/account/domain"
 	"github.com/arosace/WellnessWaveApi/internal/account/model"
 	"github.com/arosace/WellnessWaveApi/internal/account/repository"
+	auditService "github.com/arosace/WellnessWaveApi/internal/audit/service"
+	organisationRepository "github.com/arosace/WellnessWaveApi/internal/organisation/repository"
 	"github.com/arosace/WellnessWaveApi/pkg/utils"
 	encryption "github.com/arosace/WellnessWaveApi/pkg/utils"
 	"github.com/labstack/echo/v5"
 	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/mailer"
 )
 
 // AccountService defines the interface for account operations.
 type AccountService interface {
-	AddAccount(ctx echo.Context, account model.Account) (*models.Record, error)
+	AddAccount(ctx echo.Context, registration model.RegisterAccountBody) (*models.Record, error)
 	GetAccounts(ctx echo.Context) ([]*models.Record, error)
 	GetAccountById(ctx echo.Context, id string) (*models.Record, error)
 	GetAttachedAccounts(ctx echo.Context, parentId string) ([]*models.Record, error)
 	GetAccountByEmail(ctx echo.Context, email string) (*models.Record, error)
 	CheckAccountExists(ctx echo.Context, email string) bool
 	AttachAccount(ctx echo.Context, accountToAttach model.AttachAccountBody) (*models.Record, error)
+	GetCareTeam(ctx echo.Context, patientId string) ([]*models.Record, error)
+	AddCareTeamMember(ctx echo.Context, patientId string, body model.CareTeamMemberBody) (*models.Record, error)
+	RequestAttach(ctx echo.Context, patientId string, body model.AttachRequestBody) (*models.Record, error)
+	GetPendingAttachRequests(ctx echo.Context, accountId string) ([]*models.Record, error)
+	AcceptAttachRequest(ctx echo.Context, specialistId string, requestId string) (*models.Record, error)
+	DeclineAttachRequest(ctx echo.Context, specialistId string, requestId string) (*models.Record, error)
+	CancelAttachRequest(ctx echo.Context, patientId string, requestId string) (*models.Record, error)
 	UpdateAccount(ctx echo.Context, accountToUpdate model.Account, infoType string) (*models.Record, error)
 	Authorize(ctx echo.Context, credentials model.LogInCredentials) (*models.Record, error)
+	UnlockAccount(ctx echo.Context, token string) error
+	DeleteAccount(ctx echo.Context, accountId string) error
+	CancelDeletion(ctx echo.Context, token string) error
+	GetPatientsPendingDeletion(ctx echo.Context, parentId string) ([]*models.Record, error)
 	VerifyAccount(echo.Context, string) (*models.Record, error)
+	IssueSession(ctx echo.Context, account *models.Record) (*model.Session, error)
+	RefreshSession(ctx echo.Context, refreshToken string) (*models.Record, *model.Session, error)
+	Logout(ctx echo.Context, accountId string, refreshToken string) error
+	LogoutAllDevices(ctx echo.Context, accountId string) error
+	RequestPasswordReset(ctx echo.Context, email string) error
+	ResetPassword(ctx echo.Context, token string, password string) error
+	SendInvite(ctx echo.Context, account *models.Record) error
+	AcceptInvite(ctx echo.Context, token string, password string) (*models.Record, error)
+	StartTwoFactorChallenge(ctx echo.Context, account *models.Record) (*model.TwoFactorChallenge, error)
+	CompleteTwoFactorChallenge(ctx echo.Context, challengeToken string, code string, recoveryCode string) (*models.Record, error)
+	EnrollTwoFactor(ctx echo.Context, accountId string) (*model.TwoFactorEnrollment, error)
+	ConfirmTwoFactor(ctx echo.Context, accountId string, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx echo.Context, accountId string, code string) ([]string, error)
+	DisableTwoFactor(ctx echo.Context, accountId string, code string) error
 }
 
 type accountService struct {
-	accountRepository repository.AccountRepository
-	encryptor         encryption.Encryption
+	accountRepository       repository.AccountRepository
+	refreshTokenRepository  repository.RefreshTokenRepository
+	accountTokenRepository  repository.AccountTokenRepository
+	careTeamRepository      repository.CareTeamRepository
+	attachRequestRepository repository.AttachRequestRepository
+	organisationRepository  organisationRepository.OrganisationRepository
+	encryptor               encryption.Encryption
+	passwordHasher          utils.PasswordHasher
+	mailer                  mailer.Mailer
+	passwordResetLimiter    *utils.RateLimiter
+	twoFactorLimiter        *utils.RateLimiter
+	loginBackoff            *utils.Backoff
+	auditService            auditService.AuditService
+	dummyPasswordHash       string
+	dummyPasswordOnce       sync.Once
+	// requireSpecialistTwoFactor makes two factor authentication mandatory for health specialists,
+	// organisations can also make it mandatory for their own members only
+	requireSpecialistTwoFactor bool
 }
 
 // NewAccountService creates a new instance of the account service.
-func NewAccountService(accountRepo repository.AccountRepository, encryptor encryption.Encryption) AccountService {
+func NewAccountService(
+	accountRepo repository.AccountRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	accountTokenRepo repository.AccountTokenRepository,
+	careTeamRepo repository.CareTeamRepository,
+	attachRequestRepo repository.AttachRequestRepository,
+	organisationRepo organisationRepository.OrganisationRepository,
+	encryptor encryption.Encryption,
+	passwordHasher utils.PasswordHasher,
+	mailClient mailer.Mailer,
+	auditor auditService.AuditService,
+	requireSpecialistTwoFactor bool,
+) AccountService {
 	return &accountService{
-		accountRepository: accountRepo,
-		encryptor:         encryptor,
+		accountRepository:       accountRepo,
+		refreshTokenRepository:  refreshTokenRepo,
+		accountTokenRepository:  accountTokenRepo,
+		careTeamRepository:      careTeamRepo,
+		attachRequestRepository: attachRequestRepo,
+		organisationRepository:  organisationRepo,
+		encryptor:               encryptor,
+		passwordHasher:          passwordHasher,
+		mailer:                  mailClient,
+		passwordResetLimiter:    utils.NewRateLimiter(domain.PasswordResetRequestLimit, domain.PasswordResetRequestWindow),
+		twoFactorLimiter:        utils.NewRateLimiter(domain.TwoFactorAttemptLimit, domain.TwoFactorAttemptWindow),
+		loginBackoff:            utils.NewBackoff(domain.IPFailureThreshold, domain.IPBackoffBaseDuration, domain.IPBackoffMaxDuration),
+		auditService:            auditor,
+
+		requireSpecialistTwoFactor: requireSpecialistTwoFactor,
 	}
 }
 
-func (s *accountService) AddAccount(ctx echo.Context, account model.Account) (*models.Record, error) {
-	encryptedPassword, err := s.encryptor.Encrypt(account.Password)
+// AddAccount registers the account, taking only what the visitor may choose from the registration: the
+// account is not invited by anyone and two factor authentication can only be set up through enrollment.
+func (s *accountService) AddAccount(ctx echo.Context, registration model.RegisterAccountBody) (*models.Record, error) {
+	passwordHash, err := s.passwordHasher.Hash(registration.Password)
 	if err != nil {
-		return nil, errors.New("error when encrypting password")
+		return nil, errors.New("error when hashing password")
 	}
-	account.EncryptedPassword = encryptedPassword
-	return s.accountRepository.Add(ctx, account)
+	return s.accountRepository.Add(ctx, model.Account{
+		Role:         registration.Role,
+		FirstName:    registration.FirstName,
+		LastName:     registration.LastName,
+		Email:        registration.Email,
+		Password:     registration.Password,
+		PasswordHash: passwordHash,
+		Username:     fmt.Sprintf("%s %s", registration.FirstName, registration.LastName),
+	})
 }
 
+// GetAccounts returns the patients of the care teams of the caller, as specialists only see their own patients.
 func (s *accountService) GetAccounts(ctx echo.Context) ([]*models.Record, error) {
-	return s.accountRepository.List(ctx)
+	return s.accountRepository.FindByParentID(ctx, utils.GetAuthAccountId(ctx))
 }
 
 func (s *accountService) VerifyAccount(ctx echo.Context, email string) (*models.Record, error) {
@@ -89,6 +170,11 @@ func (s *accountService) AttachAccount(ctx echo.Context, accountToAttach model.A
 		return nil, err
 	}
 
+	careRole := accountToAttach.CareRole
+	if careRole == "" {
+		careRole = domain.PrimaryCareRole
+	}
+
 	//one should not be able to attach to themselves
 	if parent.Email() == accountToAttach.Email {
 		return nil, errors.New("cannot attach account to itself")
@@ -101,44 +187,79 @@ func (s *accountService) AttachAccount(ctx echo.Context, accountToAttach model.A
 	}
 
 	//if it does not exist create account and attach
-	//account will be created with random password, this will need to be changed by patient
+	//account will be created with a random password that is never shared, the patient
+	//chooses their own password when accepting the invitation sent to their email
 	if account == nil {
-		randPassword, err := utils.GenerateRandomPassword(8)
+		randPassword, err := utils.GenerateRandomPassword(32)
 		if err != nil {
 			return nil, errors.New("error generating eandom password for attached account")
 		}
-		encryptedRandPassword, err := s.encryptor.Encrypt(randPassword)
+		randPasswordHash, err := s.passwordHasher.Hash(randPassword)
 		if err != nil {
-			return nil, errors.New("error encrypting random password for attached account")
+			return nil, errors.New("error hashing random password for attached account")
 		}
 
 		newAccount, err := s.accountRepository.Add(ctx, model.Account{
-			FirstName:         accountToAttach.FirstName,
-			LastName:          accountToAttach.LastName,
-			Email:             accountToAttach.Email,
-			Role:              domain.PatientRole,
-			ParentID:          accountToAttach.ParentID,
-			Password:          randPassword,
-			EncryptedPassword: encryptedRandPassword,
-			Username:          fmt.Sprintf("%s %s", accountToAttach.FirstName, accountToAttach.LastName),
+			FirstName:    accountToAttach.FirstName,
+			LastName:     accountToAttach.LastName,
+			Email:        accountToAttach.Email,
+			Role:         domain.PatientRole,
+			InvitedBy:    accountToAttach.ParentID,
+			Password:     randPassword,
+			PasswordHash: randPasswordHash,
+			Username:     fmt.Sprintf("%s %s", accountToAttach.FirstName, accountToAttach.LastName),
 		})
 		if err != nil {
 			return nil, err
 		}
+		if _, err := s.careTeamRepository.Add(ctx, model.CareTeamMember{PatientID: newAccount.Id, SpecialistID: parent.Id, Role: careRole}); err != nil {
+			return nil, err
+		}
+		if err := s.SendInvite(ctx, newAccount); err != nil {
+			return nil, err
+		}
 		return newAccount, nil
-	} else { //if it exists
-		if account.GetString("parent_id") != "" && account.GetString("parent_id") != accountToAttach.ParentID { //if it is already attached return error
-			return nil, errors.New("cannot attach an account that is already attached to another")
-		} else if account.GetString("parent_id") == accountToAttach.ParentID { //is account is already attached to right health specialist
-			return nil, nil
-		} else { //if it is not already attached update it
-			_, err := s.accountRepository.Attach(ctx, account, accountToAttach.ParentID)
-			if err != nil {
-				return nil, err
+	}
+
+	if account.GetString("role") != domain.PatientRole {
+		return nil, errors.New("cannot attach an account that is not a patient")
+	}
+
+	//if it exists and the parent is already part of its care team
+	members, err := s.careTeamRepository.FindByPatientId(ctx, account.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		if member.GetString("specialist_id") == parent.Id {
+			//invitations expire, attaching a patient that never accepted theirs sends a new one
+			if !account.Verified() {
+				if err := s.SendInvite(ctx, account); err != nil {
+					return nil, err
+				}
 			}
-			// send patient account updated event
+			return nil, nil
 		}
 	}
+
+	//patients with a care team join other specialists through their care team or a transfer, not by email
+	if len(members) > 0 {
+		return nil, errors.New("cannot attach an account that already has a care team, it has to be added to it by the patient or their primary specialist")
+	}
+	//patients without a care team join the organisation of the specialist, but never leave another one
+	if organisationId := account.GetString("organisation_id"); organisationId != parent.GetString("organisation_id") {
+		if organisationId != "" {
+			return nil, errors.New("cannot attach an account of another organisation")
+		}
+		//their events and plans move with them, or they would no longer see their history
+		if err := s.organisationRepository.AddPatient(ctx, parent.GetString("organisation_id"), account.Id); err != nil {
+			return nil, err
+		}
+		account.Set("organisation_id", parent.GetString("organisation_id"))
+	}
+	if _, err := s.careTeamRepository.Add(ctx, model.CareTeamMember{PatientID: account.Id, SpecialistID: parent.Id, Role: careRole}); err != nil {
+		return nil, err
+	}
 	return account, nil
 }
 
@@ -178,10 +299,6 @@ func (s *accountService) UpdateAccount(ctx echo.Context, account model.Account,
 			isToUpdate = true
 			oldAccount.Set("last_name", account.FirstName)
 		}
-		if account.ParentID != oldAccount.GetString("parent_id") && account.ParentID != "" {
-			isToUpdate = true
-			oldAccount.Set("parent_id", account.ParentID)
-		}
 
 		if !isToUpdate {
 			return oldAccount, nil
@@ -194,22 +311,19 @@ func (s *accountService) UpdateAccount(ctx echo.Context, account model.Account,
 		return s.accountRepository.Update(ctx, oldAccount)
 	}
 
-	decryptedOldPassword, err := s.encryptor.Decrypt(oldAccount.GetString("encrypted_password"))
-	if err != nil {
-		return nil, fmt.Errorf("error decrypting old account password: %w", err)
-	}
-
-	if account.Password != decryptedOldPassword {
-		isToUpdate = true
-		if utils.PasswordIsValid(account.Password) {
-			encryptedPassword, err := s.encryptor.Encrypt(account.Password)
-			if err != nil {
-				return nil, errors.New("error when encrypting password")
+	if account.Password != "" {
+		samePassword, err := s.checkPassword(oldAccount, account.Password)
+		if err != nil {
+			return nil, err
+		}
+		if !samePassword {
+			if !utils.PasswordIsValid(account.Password) {
+				return nil, errors.New("password does not comply with authentication rules")
+			}
+			isToUpdate = true
+			if err := s.setPassword(oldAccount, account.Password); err != nil {
+				return nil, err
 			}
-			oldAccount.Set("encrypted_password", encryptedPassword)
-			oldAccount.SetPassword(encryptedPassword)
-		} else {
-			return nil, errors.New("password does not comply with authentication rules")
 		}
 	}
 	if account.Email != oldAccount.Email() && utils.EmailIsValid(account.Email) {
@@ -224,22 +338,90 @@ func (s *accountService) UpdateAccount(ctx echo.Context, account model.Account,
 	return oldAccount, nil
 }
 
+// Authorize checks the credentials of a login. Failures are counted per ip address, which is slowed
+// down with an exponential backoff, and per account, which is locked after too many of them.
 func (s *accountService) Authorize(ctx echo.Context, credentials model.LogInCredentials) (*models.Record, error) {
+	ip := utils.ClientIP(ctx)
+	if s.loginBackoff.RetryAfter(ip) > 0 {
+		return nil, errors.New("too_many_attempts")
+	}
+
 	account, err := s.accountRepository.FindByEmail(ctx, credentials.Email)
+	if err != nil {
+		if err.Error() != "not_found" {
+			return nil, err
+		}
+		s.verifyDummyPassword(credentials.Password)
+		s.loginBackoff.Failure(ip)
+		return nil, errInvalidCredentials
+	}
+
+	if isLocked(account) || isDeleted(account) {
+		s.verifyDummyPassword(credentials.Password)
+		s.loginBackoff.Failure(ip)
+		return nil, errInvalidCredentials
+	}
+
+	validPassword, err := s.checkPassword(account, credentials.Password)
 	if err != nil {
 		return nil, err
 	}
+	if !validPassword {
+		s.loginBackoff.Failure(ip)
+		if err := s.recordLoginFailure(ctx, account); err != nil {
+			return nil, err
+		}
+		return nil, errInvalidCredentials
+	}
+	// checked after the password so that only the owner learns the account is not verified yet
 	if !account.Verified() {
-		return nil, errors.New("account_not_verified")
+		return nil, errInvalidCredentials
 	}
 
-	decryptedPassword, err := s.encryptor.Decrypt(account.GetString("encrypted_password"))
-	if err != nil {
+	if err := s.resetLoginFailures(ctx, account); err != nil {
 		return nil, err
 	}
 
-	if decryptedPassword != credentials.Password {
-		return nil, errors.New("not_authorized")
+	// accounts created before password hashing, or hashed with outdated parameters,
+	// are migrated now that the plain text password is known to be correct
+	if account.GetString("password_hash") == "" ||
+		account.GetString("encrypted_password") != "" ||
+		s.passwordHasher.NeedsRehash(account.GetString("password_hash")) {
+		if err := s.setPassword(account, credentials.Password); err != nil {
+			return nil, err
+		}
+		if _, err := s.accountRepository.Update(ctx, account); err != nil {
+			return nil, fmt.Errorf("there was an error migrating the account password: %w", err)
+		}
 	}
+
 	return account, nil
 }
+
+// checkPassword compares the password with the stored hash, falling back to the
+// legacy encrypted password for accounts that have not been migrated yet.
+func (s *accountService) checkPassword(account *models.Record, password string) (bool, error) {
+	if passwordHash := account.GetString("password_hash"); passwordHash != "" {
+		return s.passwordHasher.Verify(password, passwordHash)
+	}
+
+	decryptedPassword, err := s.encryptor.DecryptWithAssociatedData(
+		account.GetString("encrypted_password"),
+		encryption.FieldAssociatedData(account.Id, "encrypted_password"),
+	)
+	if err != nil {
+		return false, fmt.Errorf("error decrypting legacy account password: %w", err)
+	}
+	return subtle.ConstantTimeCompare([]byte(decryptedPassword), []byte(password)) == 1, nil
+}
+
+// setPassword stores a fresh hash of the password and drops the legacy encrypted password.
+func (s *accountService) setPassword(account *models.Record, password string) error {
+	passwordHash, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		return errors.New("error when hashing password")
+	}
+	account.Set("password_hash", passwordHash)
+	account.Set("encrypted_password", "")
+	return account.SetPassword(password)
+}
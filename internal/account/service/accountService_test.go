@@ -0,0 +1,136 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/arosace/WellnessWaveApi/internal/account/repository"
+	eventDomain "github.com/arosace/WellnessWaveApi/internal/event/domain"
+	organisationRepository "github.com/arosace/WellnessWaveApi/internal/organisation/repository"
+	plannerDomain "github.com/arosace/WellnessWaveApi/internal/planner/domain"
+	"github.com/arosace/WellnessWaveApi/pkg/testutils"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginBackoffIgnoresForwardedFor(t *testing.T) {
+	dao := testutils.NewTestApp(t).Dao()
+	accounts := &models.Collection{Name: domain.TableName, Type: models.CollectionTypeAuth, Schema: schema.NewSchema()}
+	if err := dao.SaveCollection(accounts); err != nil {
+		t.Fatal(err)
+	}
+	service := &accountService{
+		accountRepository: repository.NewAccountRepository(dao, testutils.NewEncryptor(t)),
+		passwordHasher:    utils.NewPasswordHasher(),
+		loginBackoff:      utils.NewBackoff(domain.IPFailureThreshold, domain.IPBackoffBaseDuration, domain.IPBackoffMaxDuration),
+	}
+	login := func(remoteAddr string, forwardedFor string) error {
+		request := httptest.NewRequest(http.MethodPost, "/v1/accounts/login", nil)
+		request.RemoteAddr = remoteAddr
+		request.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		_, err := service.Authorize(echo.New().NewContext(request, httptest.NewRecorder()), model.LogInCredentials{Email: "nobody@example.com", Password: "wrong"})
+		return err
+	}
+
+	for i := 0; i < domain.IPFailureThreshold; i++ {
+		assert.EqualError(t, login("203.0.113.7:4000", fmt.Sprintf("198.51.100.%d", i)), "invalid_credentials")
+	}
+	assert.EqualError(t, login("203.0.113.7:4001", "198.51.100.250"), "too_many_attempts", "a new forwarded address does not reset the backoff")
+	assert.EqualError(t, login("203.0.113.8:4000", ""), "invalid_credentials")
+
+	_, proxies, _ := net.ParseCIDR("203.0.113.7/32")
+	utils.SetTrustedProxies([]*net.IPNet{proxies})
+	defer utils.SetTrustedProxies(nil)
+	assert.EqualError(t, login("203.0.113.7:4000", "198.51.100.250"), "invalid_credentials", "behind a trusted proxy the forwarded client is kept apart")
+}
+
+func TestRecoveryCodesAreStoredAsMACs(t *testing.T) {
+	dao := testutils.NewTestApp(t).Dao()
+	testutils.NewCollection(t, dao, domain.AccountTokensTableName, append(
+		testutils.TextFields("account_id", "purpose", "token_hash"),
+		testutils.DateField("expires_at"),
+		testutils.BoolField("used"),
+	)...)
+	encryptor := testutils.NewEncryptor(t)
+	service := &accountService{
+		accountTokenRepository: repository.NewAccountTokenRepository(dao),
+		encryptor:              encryptor,
+		twoFactorLimiter:       utils.NewRateLimiter(100, time.Minute),
+	}
+	account := &models.Record{}
+	account.Id = "account"
+	other := &models.Record{}
+	other.Id = "other"
+
+	codes, err := service.issueRecoveryCodes(nil, account.Id)
+	assert.Nil(t, err)
+	stored, err := dao.FindFirstRecordByData(domain.AccountTokensTableName, "account_id", account.Id)
+	assert.Nil(t, err)
+	assert.NotEqual(t, encryptor.HashSHA256(utils.NormalizeRecoveryCode(codes[0]), domain.RecoveryCodePurpose), stored.GetString("token_hash"))
+
+	assert.EqualError(t, service.useRecoveryCode(nil, other, codes[0]), "invalid_code", "codes are bound to their account")
+	assert.Nil(t, service.useRecoveryCode(nil, account, codes[0]))
+	assert.EqualError(t, service.useRecoveryCode(nil, account, codes[0]), "invalid_code", "codes are used once")
+	assert.Nil(t, service.useRecoveryCode(nil, account, codes[1]))
+
+	_, err = service.accountTokenRepository.Add(nil, model.AccountToken{
+		AccountID: account.Id,
+		Purpose:   domain.RecoveryCodePurpose,
+		TokenHash: encryptor.HashSHA256(utils.NormalizeRecoveryCode("LEGAC-YCODE"), domain.RecoveryCodePurpose),
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, service.useRecoveryCode(nil, account, "legac-ycode"), "codes hashed before are accepted until replaced")
+}
+
+func TestAttachedPatientKeepsTheirHistory(t *testing.T) {
+	dao := testutils.NewTestApp(t).Dao()
+	accounts := &models.Collection{Name: domain.TableName, Type: models.CollectionTypeAuth, Schema: schema.NewSchema(testutils.TextFields("role", utils.TenantField, utils.SealedFieldsField)...)}
+	if err := dao.SaveCollection(accounts); err != nil {
+		t.Fatal(err)
+	}
+	testutils.NewCollection(t, dao, domain.CareTeamTableName, testutils.TextFields("patient_id", "specialist_id", "role")...)
+	events := testutils.NewCollection(t, dao, eventDomain.TABLENAME, testutils.TextFields("health_specialist_id", "patient_id", utils.TenantField)...)
+	plans := testutils.NewCollection(t, dao, plannerDomain.PLANS_TABLENAME, testutils.TextFields("health_specialist_id", "patient_id", utils.TenantField)...)
+	testutils.NewCollection(t, dao, plannerDomain.EXERCISE_PLAN_TABLENAME, testutils.TextFields("health_specialist_id", "patient_id", utils.TenantField)...)
+	dailyPlans := testutils.NewCollection(t, dao, plannerDomain.DAILY_PLANS_TABLENAME, testutils.TextFields("plan_id", utils.TenantField)...)
+	testutils.NewCollection(t, dao, plannerDomain.DAILY_EXERCISE_PLANS_TABLENAME, testutils.TextFields("plan_id", utils.TenantField)...)
+
+	specialist := testutils.NewRecord(t, dao, accounts, map[string]any{"username": "specialist", "tokenKey": "specialist", "email": "specialist@example.com", "role": domain.HealthSpecialistRole, utils.TenantField: "clinic"})
+	patient := testutils.NewRecord(t, dao, accounts, map[string]any{"username": "patient", "tokenKey": "patient", "email": "patient@example.com", "role": domain.PatientRole})
+	//the history the patient kept from their former specialist, who detached them
+	event := testutils.NewRecord(t, dao, events, map[string]any{"health_specialist_id": "former", "patient_id": patient.Id})
+	plan := testutils.NewRecord(t, dao, plans, map[string]any{"health_specialist_id": "former", "patient_id": patient.Id})
+	dailyPlan := testutils.NewRecord(t, dao, dailyPlans, map[string]any{"plan_id": plan.Id})
+	other := testutils.NewRecord(t, dao, events, map[string]any{"health_specialist_id": "former", "patient_id": "someone else"})
+
+	service := &accountService{
+		accountRepository:      repository.NewAccountRepository(dao, testutils.NewEncryptor(t)),
+		careTeamRepository:     repository.NewCareTeamRepository(dao),
+		organisationRepository: organisationRepository.NewOrganisationRepository(dao),
+	}
+	attached, err := service.AttachAccount(nil, model.AttachAccountBody{ParentID: specialist.Id, Email: patient.Email()})
+	assert.Nil(t, err)
+	assert.Equal(t, "clinic", attached.GetString(utils.TenantField))
+
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	c.Set(utils.AuthAccountIdKey, patient.Id)
+	c.Set(utils.AuthOrganisationIdKey, "clinic")
+	for collection, id := range map[string]string{domain.TableName: patient.Id, eventDomain.TABLENAME: event.Id, plannerDomain.PLANS_TABLENAME: plan.Id, plannerDomain.DAILY_PLANS_TABLENAME: dailyPlan.Id} {
+		params := dbx.Params{"id": id}
+		_, err := dao.FindFirstRecordByFilter(collection, utils.TenantFilter(c, "id = {:id}", params), params)
+		assert.Nil(t, err, "the patient still sees their %s", collection)
+	}
+	moved, err := dao.FindRecordById(eventDomain.TABLENAME, other.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "", moved.GetString(utils.TenantField), "the records of other patients stay where they are")
+}
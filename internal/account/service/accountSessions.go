@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// IssueSession starts a new refresh token family for the account and returns its first tokens.
+func (s *accountService) IssueSession(ctx echo.Context, account *models.Record) (*model.Session, error) {
+	familyId, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, errors.New("error generating refresh token family")
+	}
+	return s.issueSession(ctx, account, familyId)
+}
+
+// RefreshSession rotates the given refresh token. Presenting a token that was already rotated
+// or revoked, or that is being rotated by another request, is treated as theft and revokes the whole family.
+func (s *accountService) RefreshSession(ctx echo.Context, refreshToken string) (*models.Record, *model.Session, error) {
+	token, err := s.refreshTokenRepository.FindByHash(ctx, s.hashRefreshToken(refreshToken))
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, nil, errors.New("invalid_refresh_token")
+		}
+		return nil, nil, err
+	}
+
+	if token.GetBool("revoked") {
+		if err := s.refreshTokenRepository.RevokeFamily(ctx, token.GetString("family_id")); err != nil {
+			return nil, nil, fmt.Errorf("there was an error revoking reused refresh token family: %w", err)
+		}
+		return nil, nil, errors.New("refresh_token_reused")
+	}
+
+	if token.GetDateTime("expires_at").Time().Before(time.Now()) {
+		return nil, nil, errors.New("refresh_token_expired")
+	}
+
+	account, err := s.accountRepository.FindByID(ctx, token.GetString("account_id"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session, err := s.issueSession(ctx, account, token.GetString("family_id"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rotated, err := s.refreshTokenRepository.Rotate(ctx, token, s.hashRefreshToken(session.RefreshToken))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !rotated {
+		// another request rotated the token first, the session just issued belongs to the revoked family
+		if err := s.refreshTokenRepository.RevokeFamily(ctx, token.GetString("family_id")); err != nil {
+			return nil, nil, fmt.Errorf("there was an error revoking reused refresh token family: %w", err)
+		}
+		return nil, nil, errors.New("refresh_token_reused")
+	}
+
+	return account, session, nil
+}
+
+// Logout revokes the refresh token family the given token belongs to.
+func (s *accountService) Logout(ctx echo.Context, accountId string, refreshToken string) error {
+	token, err := s.refreshTokenRepository.FindByHash(ctx, s.hashRefreshToken(refreshToken))
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return errors.New("invalid_refresh_token")
+		}
+		return err
+	}
+	if token.GetString("account_id") != accountId {
+		return errors.New("invalid_refresh_token")
+	}
+	return s.refreshTokenRepository.RevokeFamily(ctx, token.GetString("family_id"))
+}
+
+// LogoutAllDevices revokes every refresh token issued to the account.
+func (s *accountService) LogoutAllDevices(ctx echo.Context, accountId string) error {
+	return s.refreshTokenRepository.RevokeByAccountId(ctx, accountId)
+}
+
+func (s *accountService) issueSession(ctx echo.Context, account *models.Record, familyId string) (*model.Session, error) {
+	accessToken, expiresAt, err := utils.GenerateAccessToken(account.Id, account.GetString("role"), accountTenant(account))
+	if err != nil {
+		return nil, fmt.Errorf("error generating access token: %w", err)
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, errors.New("error generating refresh token")
+	}
+	refreshExpiresAt := time.Now().Add(domain.RefreshTokenDuration)
+	refreshExpiresAtDateTime, err := types.ParseDateTime(refreshExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.refreshTokenRepository.Add(ctx, model.RefreshToken{
+		AccountID: account.Id,
+		FamilyID:  familyId,
+		TokenHash: s.hashRefreshToken(refreshToken),
+		ExpiresAt: refreshExpiresAtDateTime.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Session{
+		AccessToken:           accessToken,
+		ExpiresAt:             expiresAt.Unix(),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshExpiresAt.Unix(),
+	}, nil
+}
+
+func (s *accountService) hashRefreshToken(refreshToken string) string {
+	return s.encryptor.HashSHA256(refreshToken, domain.RefreshTokensTableName)
+}
+
+// accountTenant returns the organisation the tokens of the account are scoped to.
+func accountTenant(account *models.Record) utils.Tenant {
+	return utils.Tenant{
+		OrganisationID:   account.GetString("organisation_id"),
+		OrganisationRole: account.GetString("organisation_role"),
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// RequestPasswordReset emails a single use reset link to the account owner.
+// Unknown emails are ignored silently so the endpoint cannot be used to discover accounts.
+func (s *accountService) RequestPasswordReset(ctx echo.Context, email string) error {
+	if !s.passwordResetLimiter.Allow(strings.ToLower(email)) {
+		return errors.New("too_many_requests")
+	}
+
+	account, err := s.accountRepository.FindByEmail(ctx, email)
+	if err != nil {
+		if err.Error() == "not_found" {
+			return nil
+		}
+		return err
+	}
+
+	token, err := s.issueAccountToken(ctx, account.Id, domain.PasswordResetPurpose, domain.PasswordResetTokenDuration)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.SendPasswordResetEmail(s.mailer, account.GetString("username"), account.Email(), token); err != nil {
+		return fmt.Errorf("Failed to send email: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token and logs the account out of every device.
+func (s *accountService) ResetPassword(ctx echo.Context, token string, password string) error {
+	account, err := s.consumeAccountToken(ctx, domain.PasswordResetPurpose, token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.setPassword(account, password); err != nil {
+		return err
+	}
+	if _, err := s.accountRepository.Update(ctx, account); err != nil {
+		return fmt.Errorf("there was an error updating the account password: %w", err)
+	}
+
+	if err := s.accountTokenRepository.InvalidateByAccountId(ctx, account.Id, domain.PasswordResetPurpose); err != nil {
+		return err
+	}
+	return s.refreshTokenRepository.RevokeByAccountId(ctx, account.Id)
+}
+
+// SendInvite emails a single use invitation link to a patient attached by their health specialist.
+func (s *accountService) SendInvite(ctx echo.Context, account *models.Record) error {
+	token, err := s.issueAccountToken(ctx, account.Id, domain.InvitePurpose, domain.InviteTokenDuration)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.SendPatientInviteEmail(s.mailer, account.GetString("username"), account.Email(), token); err != nil {
+		return fmt.Errorf("Failed to send email: %w", err)
+	}
+	return nil
+}
+
+// AcceptInvite sets the password chosen by the invited patient and verifies their account.
+func (s *accountService) AcceptInvite(ctx echo.Context, token string, password string) (*models.Record, error) {
+	account, err := s.consumeAccountToken(ctx, domain.InvitePurpose, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.setPassword(account, password); err != nil {
+		return nil, err
+	}
+	account.Set("verified", true)
+	if _, err := s.accountRepository.Update(ctx, account); err != nil {
+		return nil, fmt.Errorf("there was an error accepting the invitation: %w", err)
+	}
+
+	if err := s.accountTokenRepository.InvalidateByAccountId(ctx, account.Id, domain.InvitePurpose); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// issueAccountToken stores the hash of a new single use token and returns the token to be emailed.
+func (s *accountService) issueAccountToken(ctx echo.Context, accountId string, purpose string, duration time.Duration) (string, error) {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", fmt.Errorf("error generating %s token", purpose)
+	}
+	expiresAt, err := types.ParseDateTime(time.Now().Add(duration))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.accountTokenRepository.Add(ctx, model.AccountToken{
+		AccountID: accountId,
+		Purpose:   purpose,
+		TokenHash: s.encryptor.HashSHA256(token, purpose),
+		ExpiresAt: expiresAt.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeAccountToken marks a valid single use token as used and returns the account it was issued to.
+func (s *accountService) consumeAccountToken(ctx echo.Context, purpose string, token string) (*models.Record, error) {
+	record, err := s.accountTokenRepository.FindUnused(ctx, purpose, s.encryptor.HashSHA256(token, purpose))
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("invalid_token")
+		}
+		return nil, err
+	}
+
+	if record.GetDateTime("expires_at").Time().Before(time.Now()) {
+		return nil, errors.New("token_expired")
+	}
+
+	account, err := s.accountRepository.FindByID(ctx, record.GetString("account_id"))
+	if err != nil {
+		return nil, err
+	}
+
+	consumed, err := s.accountTokenRepository.Consume(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		// another request used the token first
+		return nil, errors.New("invalid_token")
+	}
+	return account, nil
+}
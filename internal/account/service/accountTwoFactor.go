@@ -0,0 +1,276 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// StartTwoFactorChallenge returns the challenge an account must complete after its password was
+// checked, or nil when the account can be given a session straight away.
+func (s *accountService) StartTwoFactorChallenge(ctx echo.Context, account *models.Record) (*model.TwoFactorChallenge, error) {
+	enabled := account.GetBool("totp_enabled")
+	if !enabled && !s.twoFactorRequired(account) {
+		return nil, nil
+	}
+
+	tokenUse := utils.MFAChallengeTokenUse
+	if !enabled {
+		tokenUse = utils.MFAEnrollmentTokenUse
+	}
+	token, expiresAt, err := utils.GenerateMFAChallengeToken(account.Id, account.GetString("role"), accountTenant(account), tokenUse)
+	if err != nil {
+		return nil, errors.New("error generating two factor challenge")
+	}
+
+	return &model.TwoFactorChallenge{
+		MFARequired:        true,
+		EnrollmentRequired: !enabled,
+		ChallengeToken:     token,
+		ChallengeExpiresAt: expiresAt.Unix(),
+	}, nil
+}
+
+// CompleteTwoFactorChallenge checks the code, or recovery code, given for a log in challenge and
+// returns the account to issue a session to.
+func (s *accountService) CompleteTwoFactorChallenge(ctx echo.Context, challengeToken string, code string, recoveryCode string) (*models.Record, error) {
+	claims, err := utils.DecodeAccountToken(challengeToken, utils.MFAChallengeTokenUse)
+	if err != nil {
+		if err.Error() == "token_expired" {
+			return nil, errors.New("challenge_expired")
+		}
+		return nil, errors.New("invalid_challenge")
+	}
+
+	account, err := s.accountRepository.FindByID(ctx, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if !account.GetBool("totp_enabled") {
+		return nil, errors.New("two_factor_not_enabled")
+	}
+
+	if recoveryCode != "" {
+		if err := s.useRecoveryCode(ctx, account, recoveryCode); err != nil {
+			return nil, err
+		}
+		return account, nil
+	}
+	if err := s.checkTOTP(ctx, account, code); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// EnrollTwoFactor stores a new secret for the account, it is only enabled once ConfirmTwoFactor
+// has checked a code generated from it.
+func (s *accountService) EnrollTwoFactor(ctx echo.Context, accountId string) (*model.TwoFactorEnrollment, error) {
+	account, err := s.accountRepository.FindByID(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+	if account.GetBool("totp_enabled") {
+		return nil, errors.New("two_factor_already_enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.New("error generating two factor secret")
+	}
+	account.Set("totp_secret", secret)
+	account.Set("totp_last_step", 0)
+	if _, err := s.accountRepository.Update(ctx, account); err != nil {
+		return nil, fmt.Errorf("there was an error saving the two factor secret: %w", err)
+	}
+
+	return &model.TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(domain.TOTPIssuer, account.Email(), secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables two factor authentication once the code proves the secret was saved
+// in an authenticator app, and returns a new set of recovery codes.
+func (s *accountService) ConfirmTwoFactor(ctx echo.Context, accountId string, code string) ([]string, error) {
+	account, err := s.accountRepository.FindByID(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+	if account.GetBool("totp_enabled") {
+		return nil, errors.New("two_factor_already_enabled")
+	}
+	if account.GetString("totp_secret") == "" {
+		return nil, errors.New("two_factor_not_enrolled")
+	}
+
+	if err := s.checkTOTP(ctx, account, code); err != nil {
+		return nil, err
+	}
+	account.Set("totp_enabled", true)
+	if _, err := s.accountRepository.Update(ctx, account); err != nil {
+		return nil, fmt.Errorf("there was an error enabling two factor authentication: %w", err)
+	}
+
+	return s.issueRecoveryCodes(ctx, account.Id)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the account.
+func (s *accountService) RegenerateRecoveryCodes(ctx echo.Context, accountId string, code string) ([]string, error) {
+	account, err := s.accountRepository.FindByID(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+	if !account.GetBool("totp_enabled") {
+		return nil, errors.New("two_factor_not_enabled")
+	}
+	if err := s.checkTOTP(ctx, account, code); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(ctx, account.Id)
+}
+
+// DisableTwoFactor turns two factor authentication off, unless it is mandatory for the account.
+func (s *accountService) DisableTwoFactor(ctx echo.Context, accountId string, code string) error {
+	account, err := s.accountRepository.FindByID(ctx, accountId)
+	if err != nil {
+		return err
+	}
+	if !account.GetBool("totp_enabled") {
+		return errors.New("two_factor_not_enabled")
+	}
+	if s.twoFactorRequired(account) {
+		return errors.New("two_factor_required")
+	}
+	if err := s.checkTOTP(ctx, account, code); err != nil {
+		return err
+	}
+
+	account.Set("totp_enabled", false)
+	account.Set("totp_secret", "")
+	account.Set("totp_last_step", 0)
+	if _, err := s.accountRepository.Update(ctx, account); err != nil {
+		return fmt.Errorf("there was an error disabling two factor authentication: %w", err)
+	}
+	return s.accountTokenRepository.InvalidateByAccountId(ctx, account.Id, domain.RecoveryCodePurpose)
+}
+
+// twoFactorRequired reports whether the account may not skip or disable two factor authentication,
+// either because every health specialist must use it or because the organisation of the account requires it.
+func (s *accountService) twoFactorRequired(account *models.Record) bool {
+	if s.requireSpecialistTwoFactor && account.GetString("role") == domain.HealthSpecialistRole {
+		return true
+	}
+	organisationId := account.GetString("organisation_id")
+	if organisationId == "" || account.GetString("role") != domain.HealthSpecialistRole {
+		return false
+	}
+	// the mandate of the organisation applies whatever the tenant of the request, e.g. at log in
+	organisation, err := s.organisationRepository.FindByID(nil, organisationId)
+	if err != nil {
+		log.Printf("Failed to check the two factor mandate of organisation %s, requiring it: %v", organisationId, err)
+		return true
+	}
+	return organisation.GetBool("require_two_factor")
+}
+
+// checkTOTP validates a code of the account's secret. Each code is accepted once, and the number
+// of attempts per account is limited so that codes cannot be guessed.
+func (s *accountService) checkTOTP(ctx echo.Context, account *models.Record, code string) error {
+	if !s.twoFactorLimiter.Allow(account.Id) {
+		return errors.New("too_many_attempts")
+	}
+
+	step, ok := utils.ValidateTOTP(account.GetString("totp_secret"), code, time.Now())
+	if !ok || step <= int64(account.GetInt("totp_last_step")) {
+		return errors.New("invalid_code")
+	}
+
+	used, err := s.accountRepository.UseTOTPStep(ctx, account, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		// another request used the code first
+		return errors.New("invalid_code")
+	}
+	return nil
+}
+
+// useRecoveryCode consumes one of the account's recovery codes.
+func (s *accountService) useRecoveryCode(ctx echo.Context, account *models.Record, recoveryCode string) error {
+	if !s.twoFactorLimiter.Allow(account.Id) {
+		return errors.New("too_many_attempts")
+	}
+
+	records, err := s.accountTokenRepository.FindUnusedByAccountId(ctx, account.Id, domain.RecoveryCodePurpose)
+	if err != nil {
+		return err
+	}
+	var record *models.Record
+	for _, candidate := range records {
+		if s.isRecoveryCode(account.Id, candidate.GetString("token_hash"), recoveryCode) {
+			record = candidate
+			break
+		}
+	}
+	if record == nil {
+		return errors.New("invalid_code")
+	}
+
+	consumed, err := s.accountTokenRepository.Consume(ctx, record)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return errors.New("invalid_code")
+	}
+	return nil
+}
+
+// issueRecoveryCodes replaces the recovery codes of the account and returns the new ones, only their MACs
+// are stored: the codes are too short for a plain hash not to be brute forced from a leaked database.
+func (s *accountService) issueRecoveryCodes(ctx echo.Context, accountId string) ([]string, error) {
+	if err := s.accountTokenRepository.InvalidateByAccountId(ctx, accountId, domain.RecoveryCodePurpose); err != nil {
+		return nil, err
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(domain.RecoveryCodeCount)
+	if err != nil {
+		return nil, errors.New("error generating recovery codes")
+	}
+	for _, code := range codes {
+		_, err := s.accountTokenRepository.Add(ctx, model.AccountToken{
+			AccountID: accountId,
+			Purpose:   domain.RecoveryCodePurpose,
+			TokenHash: s.encryptor.MAC(utils.NormalizeRecoveryCode(code), recoveryCodeContext(accountId)),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// isRecoveryCode reports whether the stored MAC is the one of the recovery code for the account. Codes issued
+// before they were stored as MACs were stored as a plain SHA-256 hash, they are accepted until replaced.
+func (s *accountService) isRecoveryCode(accountId string, tokenHash string, recoveryCode string) bool {
+	code := utils.NormalizeRecoveryCode(recoveryCode)
+	if !strings.Contains(tokenHash, "$") {
+		return subtle.ConstantTimeCompare([]byte(tokenHash), []byte(s.encryptor.HashSHA256(code, domain.RecoveryCodePurpose))) == 1
+	}
+	return s.encryptor.VerifyMAC(code, recoveryCodeContext(accountId), tokenHash)
+}
+
+// recoveryCodeContext binds the MAC of a recovery code to its purpose and account.
+func recoveryCodeContext(accountId string) string {
+	return domain.RecoveryCodePurpose + "." + accountId
+}
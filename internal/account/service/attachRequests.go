@@ -0,0 +1,232 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// RequestAttach asks a health specialist to follow the patient. A new request to the same specialist
+// replaces the one still pending, if any.
+func (s *accountService) RequestAttach(ctx echo.Context, patientId string, body model.AttachRequestBody) (*models.Record, error) {
+	patient, err := s.accountRepository.FindByID(ctx, patientId)
+	if err != nil {
+		return nil, err
+	}
+	if patient.GetString("role") != domain.PatientRole {
+		return nil, errors.New("not_a_patient")
+	}
+	// the specialist can be part of any organisation, the request is made from the public directory
+	specialist, err := s.accountRepository.FindByID(nil, body.SpecialistID)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("invalid_specialist")
+		}
+		return nil, err
+	}
+	if specialist.GetString("role") != domain.HealthSpecialistRole || isDeleted(specialist) {
+		return nil, errors.New("invalid_specialist")
+	}
+	if err := s.checkAttachable(ctx, patient, specialist); err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := types.ParseDateTime(time.Now().Add(domain.AttachRequestDuration))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachRequestRepository.CancelPending(ctx, patient.Id, specialist.Id); err != nil {
+		return nil, err
+	}
+	request, err := s.attachRequestRepository.Add(ctx, model.AttachRequest{
+		PatientID:    patient.Id,
+		SpecialistID: specialist.Id,
+		Status:       domain.AttachRequestPending,
+		ExpiresAt:    expiresAt.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := utils.SendAttachRequestEmail(s.mailer, specialist.GetString("username"), specialist.Email(), patient.GetString("username"), request.Id); err != nil {
+		return nil, errors.New("Failed to send email: " + err.Error())
+	}
+	return request, nil
+}
+
+// GetPendingAttachRequests returns the requests the account made as a patient, or received as a health
+// specialist, still waiting for an answer.
+func (s *accountService) GetPendingAttachRequests(ctx echo.Context, accountId string) ([]*models.Record, error) {
+	requests, err := s.attachRequestRepository.FindPendingByAccountId(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []*models.Record{}
+	for _, request := range requests {
+		if !isRequestExpired(request) {
+			pending = append(pending, request)
+		}
+	}
+	return pending, nil
+}
+
+// AcceptAttachRequest attaches the patient to the health specialist as AttachAccount does, as their
+// primary care team member, and lets the patient know.
+func (s *accountService) AcceptAttachRequest(ctx echo.Context, specialistId string, requestId string) (*models.Record, error) {
+	request, err := s.findPendingAttachRequest(ctx, "specialist_id", specialistId, requestId)
+	if err != nil {
+		return nil, err
+	}
+	patient, err := s.accountRepository.FindByID(nil, request.GetString("patient_id"))
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+	specialist, err := s.accountRepository.FindByID(ctx, specialistId)
+	if err != nil {
+		return nil, err
+	}
+
+	// the patient may have joined a care team or an organisation since the request was made
+	if isDeleted(patient) || s.checkAttachable(ctx, patient, specialist) != nil {
+		request.Set("status", domain.AttachRequestCancelled)
+		if _, err := s.attachRequestRepository.Update(ctx, request); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("request_outdated")
+	}
+
+	_, err = s.AttachAccount(ctx, model.AttachAccountBody{
+		FirstName: patient.GetString("first_name"),
+		LastName:  patient.GetString("last_name"),
+		Role:      domain.PatientRole,
+		Email:     patient.Email(),
+		ParentID:  specialistId,
+		CareRole:  domain.PrimaryCareRole,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decideAttachRequest(ctx, request, domain.AttachRequestAccepted); err != nil {
+		log.Printf("Failed to record the acceptance of attach request %s: %v", request.Id, err)
+	}
+
+	if err := utils.SendAttachRequestDecidedEmail(s.mailer, patient.GetString("username"), patient.Email(), specialist.GetString("username"), true); err != nil {
+		log.Printf("Failed to notify the acceptance of attach request %s: %v", request.Id, err)
+	}
+	return request, nil
+}
+
+// DeclineAttachRequest refuses the request, the patient is told so and can request another specialist.
+func (s *accountService) DeclineAttachRequest(ctx echo.Context, specialistId string, requestId string) (*models.Record, error) {
+	request, err := s.findPendingAttachRequest(ctx, "specialist_id", specialistId, requestId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decideAttachRequest(ctx, request, domain.AttachRequestDeclined); err != nil {
+		return nil, err
+	}
+
+	patient, err := s.accountRepository.FindByID(nil, request.GetString("patient_id"))
+	if err == nil {
+		var specialist *models.Record
+		if specialist, err = s.accountRepository.FindByID(ctx, specialistId); err == nil {
+			err = utils.SendAttachRequestDecidedEmail(s.mailer, patient.GetString("username"), patient.Email(), specialist.GetString("username"), false)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to notify the decline of attach request %s: %v", request.Id, err)
+	}
+	return request, nil
+}
+
+// CancelAttachRequest withdraws the request of the patient, the health specialist is told so.
+func (s *accountService) CancelAttachRequest(ctx echo.Context, patientId string, requestId string) (*models.Record, error) {
+	request, err := s.findPendingAttachRequest(ctx, "patient_id", patientId, requestId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decideAttachRequest(ctx, request, domain.AttachRequestCancelled); err != nil {
+		return nil, err
+	}
+
+	specialist, err := s.accountRepository.FindByID(nil, request.GetString("specialist_id"))
+	if err == nil {
+		var patient *models.Record
+		if patient, err = s.accountRepository.FindByID(ctx, patientId); err == nil {
+			err = utils.SendAttachRequestCancelledEmail(s.mailer, specialist.GetString("username"), specialist.Email(), patient.GetString("username"))
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to notify the cancellation of attach request %s: %v", request.Id, err)
+	}
+	return request, nil
+}
+
+// checkAttachable reports why AttachAccount would refuse to attach the patient to the health specialist, if it would.
+func (s *accountService) checkAttachable(ctx echo.Context, patient *models.Record, specialist *models.Record) error {
+	members, err := s.careTeamRepository.FindByPatientId(ctx, patient.Id)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if member.GetString("specialist_id") == specialist.Id {
+			return errors.New("already_member")
+		}
+	}
+	// patients with a care team are added to it by their primary specialist instead
+	if len(members) > 0 {
+		return errors.New("has_care_team")
+	}
+	if organisationId := patient.GetString("organisation_id"); organisationId != "" && organisationId != specialist.GetString("organisation_id") {
+		return errors.New("other_organisation")
+	}
+	return nil
+}
+
+func (s *accountService) decideAttachRequest(ctx echo.Context, request *models.Record, status string) error {
+	now, err := types.ParseDateTime(time.Now())
+	if err != nil {
+		return err
+	}
+	request.Set("status", status)
+	request.Set("decided_at", now.String())
+	_, err = s.attachRequestRepository.Update(ctx, request)
+	return err
+}
+
+// findPendingAttachRequest returns the request if the account is its patient or specialist, as given by
+// field, and it still waits for an answer.
+func (s *accountService) findPendingAttachRequest(ctx echo.Context, field string, accountId string, requestId string) (*models.Record, error) {
+	request, err := s.attachRequestRepository.FindByID(ctx, requestId)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+	if request.GetString(field) != accountId {
+		return nil, errors.New("not_found")
+	}
+	if request.GetString("status") != domain.AttachRequestPending {
+		return nil, errors.New("request_not_pending")
+	}
+	if isRequestExpired(request) {
+		return nil, errors.New("request_expired")
+	}
+	return request, nil
+}
+
+func isRequestExpired(request *models.Record) bool {
+	return request.GetDateTime("expires_at").Time().Before(time.Now())
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// GetCareTeam returns the health specialists following the patient, each with their care role.
+func (s *accountService) GetCareTeam(ctx echo.Context, patientId string) ([]*models.Record, error) {
+	return s.careTeamRepository.FindByPatientId(ctx, patientId)
+}
+
+// AddCareTeamMember adds a health specialist to the care team of the patient with a care role not held
+// by another member yet.
+func (s *accountService) AddCareTeamMember(ctx echo.Context, patientId string, body model.CareTeamMemberBody) (*models.Record, error) {
+	patient, err := s.accountRepository.FindByID(ctx, patientId)
+	if err != nil {
+		return nil, err
+	}
+	if patient.GetString("role") != domain.PatientRole {
+		return nil, errors.New("not_a_patient")
+	}
+
+	// specialists outside of any organisation are not related to the patient yet, the tenant is checked below
+	specialist, err := s.accountRepository.FindByID(nil, body.SpecialistID)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("invalid_specialist")
+		}
+		return nil, err
+	}
+	if specialist.GetString("role") != domain.HealthSpecialistRole || isDeleted(specialist) ||
+		specialist.GetString(utils.TenantField) != patient.GetString(utils.TenantField) {
+		return nil, errors.New("invalid_specialist")
+	}
+
+	members, err := s.careTeamRepository.FindByPatientId(ctx, patientId)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		if member.GetString("specialist_id") == specialist.Id {
+			return nil, errors.New("already_member")
+		}
+		if member.GetString("role") == body.Role {
+			return nil, errors.New("care_role_taken")
+		}
+	}
+
+	return s.careTeamRepository.Add(ctx, model.CareTeamMember{
+		PatientID:    patientId,
+		SpecialistID: specialist.Id,
+		Role:         body.Role,
+	})
+}
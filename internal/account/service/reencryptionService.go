@@ -0,0 +1,72 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/account/repository"
+	encryption "github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+)
+
+// ReencryptionService rewrites encrypted account fields with the active encryption key, bound to the
+// account and field they are stored in, so that a retired key can be removed from the keyring once it has run.
+type ReencryptionService interface {
+	ReencryptAccounts(ctx echo.Context) (int, error)
+}
+
+type reencryptionService struct {
+	accountRepository repository.AccountRepository
+	encryptor         encryption.Encryption
+}
+
+func NewReencryptionService(accountRepo repository.AccountRepository, encryptor encryption.Encryption) ReencryptionService {
+	return &reencryptionService{
+		accountRepository: accountRepo,
+		encryptor:         encryptor,
+	}
+}
+
+// ReencryptAccounts returns the number of accounts that were rewritten. Accounts are saved one by one,
+// the job can be run again after a failure and skips values already encrypted with the active key.
+func (s *reencryptionService) ReencryptAccounts(ctx echo.Context) (int, error) {
+	accounts, err := s.accountRepository.FindWithEncryptedFields(ctx, domain.EncryptedFields)
+	if err != nil {
+		return 0, err
+	}
+
+	reencrypted := 0
+	for _, account := range accounts {
+		changed := false
+		for _, field := range domain.EncryptedFields {
+			value := account.GetString(field)
+			if value == "" || !s.encryptor.NeedsReencrypt(value) {
+				continue
+			}
+
+			associatedData := encryption.FieldAssociatedData(account.Id, field)
+			plainText, err := s.encryptor.DecryptWithAssociatedData(value, associatedData)
+			if err != nil {
+				return reencrypted, fmt.Errorf("could not decrypt %s of account %s: %w", field, account.Id, err)
+			}
+			encryptedText, err := s.encryptor.EncryptWithAssociatedData(plainText, associatedData)
+			if err != nil {
+				return reencrypted, fmt.Errorf("could not encrypt %s of account %s: %w", field, account.Id, err)
+			}
+			account.Set(field, encryptedText)
+			changed = true
+		}
+
+		if !changed {
+			continue
+		}
+		if _, err := s.accountRepository.Update(ctx, account); err != nil {
+			return reencrypted, fmt.Errorf("could not save account %s: %w", account.Id, err)
+		}
+		reencrypted++
+	}
+
+	// fields encrypted by the repository itself
+	resealed, err := s.accountRepository.Reencrypt(ctx)
+	return reencrypted + resealed, err
+}
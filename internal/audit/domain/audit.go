@@ -0,0 +1,24 @@
+package domain
+
+const TableName = "audit_logs"
+
+// Actions recorded by the system itself, actions taken through a route are named after the route.
+const (
+	AccountLockedAction = "account.locked"
+	AccountErasedAction = "account.erased"
+)
+
+// Outcomes of an audited action.
+const (
+	SuccessOutcome = "success"
+	DeniedOutcome  = "denied"
+	FailureOutcome = "failure"
+)
+
+// GenesisHash is the previous hash of the first entry of the chain.
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 500
+)
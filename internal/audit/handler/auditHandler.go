@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/arosace/WellnessWaveApi/internal/audit/model"
+	"github.com/arosace/WellnessWaveApi/internal/audit/service"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+// AuditHandler handles HTTP requests for the audit log, which is only exposed to admins.
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+func (h *AuditHandler) HandleGetAuditLog(ctx echo.Context) error {
+	res := model.AuditLogResponse{}
+
+	query := model.AuditQuery{
+		PatientID: ctx.QueryParam("patientId"),
+		ActorID:   ctx.QueryParam("actorId"),
+	}
+	var err error
+	if limit := ctx.QueryParam("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			res.Error = "invalid_data: limit"
+			return apis.NewBadRequestError(res.Error, nil)
+		}
+	}
+	if offset := ctx.QueryParam("offset"); offset != "" {
+		if query.Offset, err = strconv.Atoi(offset); err != nil {
+			res.Error = "invalid_data: offset"
+			return apis.NewBadRequestError(res.Error, nil)
+		}
+	}
+	if err := query.ValidateModel(); err != nil {
+		res.Error = err.Error()
+		return apis.NewBadRequestError(res.Error, nil)
+	}
+
+	entries, err := h.auditService.Find(ctx, query)
+	if err != nil {
+		res.Error = fmt.Sprintf("failed_to_get_audit_log: %v", err)
+		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+	}
+
+	res.Data = entries
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *AuditHandler) HandleVerifyAuditLog(ctx echo.Context) error {
+	verification, err := h.auditService.VerifyChain(ctx)
+	if err != nil {
+		return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("failed_to_verify_audit_log: %v", err), nil)
+	}
+	return ctx.JSON(http.StatusOK, verification)
+}
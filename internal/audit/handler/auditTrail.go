@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/arosace/WellnessWaveApi/internal/audit/domain"
+	"github.com/arosace/WellnessWaveApi/internal/audit/model"
+	"github.com/arosace/WellnessWaveApi/internal/audit/service"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+// Target describes the record a route reads or changes. Either extractor may be nil, e.g. when the
+// record is created by the request and has no id yet.
+type Target struct {
+	Collection string
+	RecordID   utils.ParamExtractor
+	PatientID  utils.ParamExtractor
+}
+
+// AuditTrail builds the middleware recording every request of the audited routes.
+type AuditTrail struct {
+	auditService service.AuditService
+}
+
+func NewAuditTrail(auditService service.AuditService) *AuditTrail {
+	return &AuditTrail{auditService: auditService}
+}
+
+// Audit records the action whatever its outcome. It goes after the authentication middleware, so the
+// actor is known, and before Authorize, so requests denied by a policy are recorded too.
+func (t *AuditTrail) Audit(action string, target Target) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// extracted before the handler runs, binding the request body consumes it
+			recordId, patientId := extract(target.RecordID, c), extract(target.PatientID, c)
+
+			err := next(c)
+
+			status := statusCode(c, err)
+			entry := model.AuditEntry{
+				Action:           action,
+				Outcome:          outcome(status),
+				StatusCode:       status,
+				ActorID:          utils.GetAuthAccountId(c),
+				ActorRole:        utils.GetAuthRole(c),
+				TargetCollection: target.Collection,
+				TargetID:         recordId,
+				PatientID:        patientId,
+				Details: map[string]interface{}{
+					"method": c.Request().Method,
+					"path":   c.Request().URL.Path,
+				},
+			}
+			// the response is already written, a failure to record it cannot be reported to the caller
+			if recordErr := t.auditService.Record(c, entry); recordErr != nil {
+				log.Printf("Failed to record %s in the audit log: %v", action, recordErr)
+			}
+			return err
+		}
+	}
+}
+
+func extract(param utils.ParamExtractor, c echo.Context) string {
+	if param == nil {
+		return ""
+	}
+	return param(c)
+}
+
+// statusCode returns the status the request was answered with, or will be once the error is handled.
+func statusCode(c echo.Context, err error) int {
+	if err == nil {
+		return c.Response().Status
+	}
+	var apiErr *apis.ApiError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
+
+func outcome(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return domain.DeniedOutcome
+	case status >= http.StatusBadRequest:
+		return domain.FailureOutcome
+	default:
+		return domain.SuccessOutcome
+	}
+}
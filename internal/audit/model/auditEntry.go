@@ -0,0 +1,53 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// AuditEntry records who performed an action, on which record and with what outcome.
+// ActorID is empty for actions taken by the system itself, e.g. locking an account, and PatientID
+// is set whenever the action touches the data of a patient so their history can be queried.
+// Entries form a chain: each one stores the hash of the previous one and a hash of its own content.
+type AuditEntry struct {
+	ID               string                 `json:"id,omitempty"`
+	Sequence         int                    `json:"sequence"`
+	OccurredAt       string                 `json:"occurred_at"`
+	Action           string                 `json:"action"`
+	Outcome          string                 `json:"outcome"`
+	StatusCode       int                    `json:"status_code"`
+	ActorID          string                 `json:"actor_id"`
+	ActorRole        string                 `json:"actor_role"`
+	TargetCollection string                 `json:"target_collection"`
+	TargetID         string                 `json:"target_id"`
+	PatientID        string                 `json:"patient_id"`
+	IP               string                 `json:"ip"`
+	Details          map[string]interface{} `json:"details"`
+	PrevHash         string                 `json:"prev_hash"`
+	Hash             string                 `json:"hash"`
+}
+
+// ComputeHash hashes every field of the entry but its id and own hash, chained to the previous hash.
+func (e *AuditEntry) ComputeHash() (string, error) {
+	payload, err := json.Marshal([]interface{}{
+		e.Sequence,
+		e.OccurredAt,
+		e.Action,
+		e.Outcome,
+		e.StatusCode,
+		e.ActorID,
+		e.ActorRole,
+		e.TargetCollection,
+		e.TargetID,
+		e.PatientID,
+		e.IP,
+		e.Details,
+		e.PrevHash,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
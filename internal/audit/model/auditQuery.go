@@ -0,0 +1,21 @@
+package model
+
+import "errors"
+
+// AuditQuery filters the audit log by the patient whose data was touched or by the actor.
+type AuditQuery struct {
+	PatientID string
+	ActorID   string
+	Limit     int
+	Offset    int
+}
+
+func (q *AuditQuery) ValidateModel() error {
+	if q.PatientID == "" && q.ActorID == "" {
+		return errors.New("missing_data: patientId or actorId")
+	}
+	if q.Limit < 0 || q.Offset < 0 {
+		return errors.New("invalid_data: limit and offset must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package model
+
+type AuditLogResponse struct {
+	Data  []AuditEntry `json:"data"`
+	Error string       `json:"error"`
+}
+
+// ChainVerification reports whether every entry of the audit log still matches its hash and links
+// to the previous one. BrokenAt is the sequence of the first entry that does not.
+type ChainVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int    `json:"checked"`
+	BrokenAt int    `json:"broken_at,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
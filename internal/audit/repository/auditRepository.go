@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/arosace/WellnessWaveApi/internal/audit/domain"
+	"github.com/arosace/WellnessWaveApi/internal/audit/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// AuditRepository defines the interface for audit log data access, entries can only be appended.
+type AuditRepository interface {
+	Append(echo.Context, model.AuditEntry) (*model.AuditEntry, error)
+	Find(echo.Context, model.AuditQuery) ([]model.AuditEntry, error)
+	ListAfter(ctx echo.Context, sequence int, limit int) ([]model.AuditEntry, error)
+}
+
+type AuditRepo struct {
+	Dao *daos.Dao
+	// mux serializes appends, every entry needs the hash of the one before it
+	mux sync.Mutex
+}
+
+func NewAuditRepository(dao *daos.Dao) *AuditRepo {
+	return &AuditRepo{Dao: dao}
+}
+
+// Append links the entry to the last one of the chain, hashes it and saves it.
+func (r *AuditRepo) Append(ctx echo.Context, entry model.AuditEntry) (*model.AuditEntry, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	collection, err := r.Dao.FindCollectionByNameOrId(domain.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.Dao.RunInTransaction(func(txDao *daos.Dao) error {
+		last, err := txDao.FindRecordsByFilter(domain.TableName, "sequence > 0", "-sequence", 1, 0)
+		if err != nil {
+			return err
+		}
+		entry.Sequence, entry.PrevHash = 1, domain.GenesisHash
+		if len(last) > 0 {
+			entry.Sequence = last[0].GetInt("sequence") + 1
+			entry.PrevHash = last[0].GetString("hash")
+		}
+		entry.OccurredAt = types.NowDateTime().String()
+		if entry.Details == nil {
+			entry.Details = map[string]interface{}{}
+		}
+		entry.Hash, err = entry.ComputeHash()
+		if err != nil {
+			return err
+		}
+
+		record := models.NewRecord(collection)
+		utils.LoadFromStruct(record, &entry)
+		if err := txDao.SaveRecord(record); err != nil {
+			return err
+		}
+		entry.ID = record.Id
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to save audit entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// Find returns the entries about the patient or by the actor of the query, newest first.
+func (r *AuditRepo) Find(ctx echo.Context, query model.AuditQuery) ([]model.AuditEntry, error) {
+	filter := "sequence > 0"
+	params := dbx.Params{}
+	if query.PatientID != "" {
+		filter += " && patient_id = {:patient_id}"
+		params["patient_id"] = query.PatientID
+	}
+	if query.ActorID != "" {
+		filter += " && actor_id = {:actor_id}"
+		params["actor_id"] = query.ActorID
+	}
+
+	records, err := r.Dao.FindRecordsByFilter(domain.TableName, filter, "-sequence", query.Limit, query.Offset, params)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving audit entries: %w", err)
+	}
+	return toEntries(records)
+}
+
+// ListAfter returns up to limit entries following the given sequence, in chain order.
+func (r *AuditRepo) ListAfter(ctx echo.Context, sequence int, limit int) ([]model.AuditEntry, error) {
+	records, err := r.Dao.FindRecordsByFilter(
+		domain.TableName,
+		"sequence > {:sequence}",
+		"sequence",
+		limit,
+		0,
+		dbx.Params{"sequence": sequence},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving audit entries: %w", err)
+	}
+	return toEntries(records)
+}
+
+func toEntries(records []*models.Record) ([]model.AuditEntry, error) {
+	entries := make([]model.AuditEntry, 0, len(records))
+	for _, record := range records {
+		entry := model.AuditEntry{
+			ID:               record.Id,
+			Sequence:         record.GetInt("sequence"),
+			OccurredAt:       record.GetDateTime("occurred_at").String(),
+			Action:           record.GetString("action"),
+			Outcome:          record.GetString("outcome"),
+			StatusCode:       record.GetInt("status_code"),
+			ActorID:          record.GetString("actor_id"),
+			ActorRole:        record.GetString("actor_role"),
+			TargetCollection: record.GetString("target_collection"),
+			TargetID:         record.GetString("target_id"),
+			PatientID:        record.GetString("patient_id"),
+			IP:               record.GetString("ip"),
+			PrevHash:         record.GetString("prev_hash"),
+			Hash:             record.GetString("hash"),
+		}
+		details, err := json.Marshal(record.Get("details"))
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(details, &entry.Details); err != nil {
+			return nil, fmt.Errorf("audit entry %d has invalid details: %w", entry.Sequence, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
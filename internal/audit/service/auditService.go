@@ -0,0 +1,73 @@
+package service
+
+import (
+	"github.com/arosace/WellnessWaveApi/internal/audit/domain"
+	"github.com/arosace/WellnessWaveApi/internal/audit/model"
+	"github.com/arosace/WellnessWaveApi/internal/audit/repository"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+)
+
+// AuditService records security relevant actions and access to patient data so they can be reviewed later.
+type AuditService interface {
+	Record(ctx echo.Context, entry model.AuditEntry) error
+	Find(ctx echo.Context, query model.AuditQuery) ([]model.AuditEntry, error)
+	VerifyChain(ctx echo.Context) (*model.ChainVerification, error)
+}
+
+type auditService struct {
+	auditRepository repository.AuditRepository
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) AuditService {
+	return &auditService{
+		auditRepository: auditRepo,
+	}
+}
+
+// Record appends the entry to the audit log, filling in the ip address of the client when it is not set.
+// The X-Forwarded-For header is only trusted from the trusted proxies, see utils.ClientIP.
+func (s *auditService) Record(ctx echo.Context, entry model.AuditEntry) error {
+	if entry.IP == "" && ctx != nil {
+		entry.IP = utils.ClientIP(ctx)
+	}
+	_, err := s.auditRepository.Append(ctx, entry)
+	return err
+}
+
+func (s *auditService) Find(ctx echo.Context, query model.AuditQuery) ([]model.AuditEntry, error) {
+	if query.Limit == 0 || query.Limit > domain.MaxQueryLimit {
+		query.Limit = domain.DefaultQueryLimit
+	}
+	return s.auditRepository.Find(ctx, query)
+}
+
+// VerifyChain walks the whole audit log and checks every entry still matches its hash and follows
+// the previous one, so that an entry edited, inserted or removed in the database is detected.
+func (s *auditService) VerifyChain(ctx echo.Context) (*model.ChainVerification, error) {
+	result := &model.ChainVerification{Valid: true}
+	prevHash, sequence := domain.GenesisHash, 0
+
+	for {
+		entries, err := s.auditRepository.ListAfter(ctx, sequence, domain.MaxQueryLimit)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			hash, err := entry.ComputeHash()
+			if err != nil {
+				return nil, err
+			}
+			if entry.Sequence != sequence+1 || entry.PrevHash != prevHash || entry.Hash != hash {
+				result.Valid = false
+				result.BrokenAt = entry.Sequence
+				return result, nil
+			}
+			prevHash, sequence = entry.Hash, entry.Sequence
+			result.Checked++
+		}
+		if len(entries) < domain.MaxQueryLimit {
+			return result, nil
+		}
+	}
+}
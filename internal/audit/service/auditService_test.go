@@ -0,0 +1,42 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arosace/WellnessWaveApi/internal/audit/model"
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingRepository struct {
+	entries []model.AuditEntry
+}
+
+func (r *recordingRepository) Append(ctx echo.Context, entry model.AuditEntry) (*model.AuditEntry, error) {
+	r.entries = append(r.entries, entry)
+	return &entry, nil
+}
+
+func (r *recordingRepository) Find(ctx echo.Context, query model.AuditQuery) ([]model.AuditEntry, error) {
+	return r.entries, nil
+}
+
+func (r *recordingRepository) ListAfter(ctx echo.Context, sequence int, limit int) ([]model.AuditEntry, error) {
+	return nil, nil
+}
+
+func TestRecordKeepsTheAddressOfTheConnection(t *testing.T) {
+	repository := &recordingRepository{}
+	service := NewAuditService(repository)
+
+	request := httptest.NewRequest(http.MethodGet, "/v1/events", nil)
+	request.RemoteAddr = "203.0.113.7:4000"
+	request.Header.Set(echo.HeaderXForwardedFor, "198.51.100.1")
+	request.Header.Set(echo.HeaderXRealIP, "198.51.100.2")
+	assert.Nil(t, service.Record(echo.New().NewContext(request, httptest.NewRecorder()), model.AuditEntry{Action: "events.list"}))
+
+	assert.Len(t, repository.entries, 1)
+	assert.Equal(t, "203.0.113.7", repository.entries[0].IP, "headers set by the client are not trusted")
+}
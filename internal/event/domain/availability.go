@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"time"
+	// the timezones of the specialists are resolved even on hosts without a timezone database
+	_ "time/tzdata"
+)
+
+const (
+	// AvailabilityTableName stores the weekly working hours and slot durations of each health specialist.
+	AvailabilityTableName = "availabilities"
+	// AvailabilityExceptionsTableName stores the times a health specialist is not available despite
+	// their working hours, e.g. holidays.
+	AvailabilityExceptionsTableName = "availability_exceptions"
+
+	// ClockLayout is the layout of the start and end times of the weekly rules, in the timezone of the specialist.
+	ClockLayout = "15:04"
+
+	// DefaultSlotDuration is the duration of the slots of event types without a duration of their own.
+	DefaultSlotDuration = 30 * time.Minute
+	MinSlotMinutes      = 5
+	MaxSlotMinutes      = 8 * 60
+
+	// MaxAvailabilityRange is the longest period free slots can be computed for at once.
+	MaxAvailabilityRange = 31 * 24 * time.Hour
+)
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+const (
+	// DefaultMaxAdvanceDays is how far ahead patients can book when their health specialist did not say.
+	DefaultMaxAdvanceDays = 60
+	MaxAdvanceDays        = 365
+	MaxMinNotice          = 30 * 24 * time.Hour
+	// DefaultCancellationNotice is how long before an event it can be cancelled without being late,
+	// when the health specialist did not say.
+	DefaultCancellationNotice = 24 * time.Hour
+)
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// Scopes of a change to an occurrence of a recurring event.
+const (
+	// ThisOccurrence detaches the occurrence from its series before changing it.
+	ThisOccurrence = "this"
+	// FollowingOccurrences splits the series at the occurrence and changes the new series.
+	FollowingOccurrences = "following"
+	// WholeSeries changes every occurrence of the series.
+	WholeSeries = "series"
+)
+
+// DefaultExpansionWindow is how far ahead recurring events are expanded when listing events without an end.
+const DefaultExpansionWindow = 90 * 24 * time.Hour
+
+func IsScope(scope string) bool {
+	return scope == ThisOccurrence || scope == FollowingOccurrences || scope == WholeSeries
+}
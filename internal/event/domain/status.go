@@ -0,0 +1,47 @@
+package domain
+
+// Statuses of an event. Events booked by patients of a health specialist requiring approval are requested
+// until the specialist confirms or declines them, the other events start confirmed. Confirmed events end
+// completed, cancelled or with the patient not showing up.
+const (
+	RequestedStatus = "requested"
+	ConfirmedStatus = "confirmed"
+	DeclinedStatus  = "declined"
+	CancelledStatus = "cancelled"
+	CompletedStatus = "completed"
+	NoShowStatus    = "no_show"
+)
+
+// transitions lists the statuses each status can move to, the others are final.
+var transitions = map[string][]string{
+	RequestedStatus: {ConfirmedStatus, DeclinedStatus, CancelledStatus},
+	ConfirmedStatus: {CompletedStatus, CancelledStatus, NoShowStatus},
+}
+
+// FreeingStatuses are the statuses of events that no longer take up the time of their participants.
+var FreeingStatuses = []string{DeclinedStatus, CancelledStatus}
+
+// CanTransition reports whether an event with the status can move to the next one.
+func CanTransition(status string, next string) bool {
+	for _, allowed := range transitions[status] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpen reports whether events with the status are still to take place, and can be rescheduled.
+func IsOpen(status string) bool {
+	return len(transitions[status]) > 0
+}
+
+// FreesTime reports whether events with the status no longer take up the time of their participants.
+func FreesTime(status string) bool {
+	for _, freeing := range FreeingStatuses {
+		if status == freeing {
+			return true
+		}
+	}
+	return false
+}
@@ -1,3 +1,6 @@
 package domain
 
 const Layout = "2006-01-02 15:04:05"
+
+// DateLayout is the layout of days, e.g. to query the availability of a whole day.
+const DateLayout = "2006-01-02"
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/model"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+func (h *EventHandler) HandleGetFreeSlots(ctx echo.Context) error {
+	res := model.EventResponse{}
+
+	query := model.AvailabilityQuery{
+		HealthSpecialistID: ctx.QueryParam("healthSpecialistId"),
+		EventType:          ctx.QueryParam("eventType"),
+		From:               ctx.QueryParam("from"),
+		To:                 ctx.QueryParam("to"),
+	}
+	if err := query.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	slots, err := h.eventService.GetFreeSlots(ctx, query)
+	if err != nil {
+		return availabilityError(err, "Failed to compute free slots")
+	}
+
+	res.Data = slots
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *EventHandler) HandleGetAvailability(ctx echo.Context) error {
+	res := model.EventResponse{}
+
+	availability, err := h.eventService.GetAvailability(ctx, ctx.PathParam("healthSpecialistId"))
+	if err != nil {
+		return availabilityError(err, "Failed to get availability")
+	}
+
+	res.Data = availability
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *EventHandler) HandleSetAvailability(ctx echo.Context) error {
+	res := model.EventResponse{}
+	var body model.Availability
+
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("wrong_data_type", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	availability, err := h.eventService.SetAvailability(ctx, ctx.PathParam("healthSpecialistId"), body)
+	if err != nil {
+		return availabilityError(err, "Failed to set availability")
+	}
+
+	res.Data = availability
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *EventHandler) HandleGetAvailabilityExceptions(ctx echo.Context) error {
+	res := model.EventResponse{}
+
+	exceptions, err := h.eventService.GetAvailabilityExceptions(ctx, ctx.PathParam("healthSpecialistId"))
+	if err != nil {
+		return availabilityError(err, "Failed to get availability exceptions")
+	}
+
+	res.Data = exceptions
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *EventHandler) HandleAddAvailabilityException(ctx echo.Context) error {
+	res := model.EventResponse{}
+	var body model.AvailabilityException
+
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("wrong_data_type", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	exception, err := h.eventService.AddAvailabilityException(ctx, ctx.PathParam("healthSpecialistId"), body)
+	if err != nil {
+		return availabilityError(err, "Failed to add availability exception")
+	}
+
+	res.Data = exception
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *EventHandler) HandleDeleteAvailabilityException(ctx echo.Context) error {
+	if err := h.eventService.DeleteAvailabilityException(ctx, ctx.PathParam("healthSpecialistId"), ctx.PathParam("exceptionId")); err != nil {
+		return availabilityError(err, "Failed to delete availability exception")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// availabilityError maps the errors of the availability service methods to api errors.
+func availabilityError(err error, message string) error {
+	if err.Error() == "not_found" {
+		return apis.NewNotFoundError(err.Error(), nil)
+	}
+	return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("%s: %v", message, err), nil)
+}
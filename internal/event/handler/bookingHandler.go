@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+func (h *EventHandler) HandleBookEvent(ctx echo.Context) error {
+	res := utils.GenericHttpResponse{}
+	var booking model.Booking
+	if err := ctx.Bind(&booking); err != nil {
+		return apis.NewBadRequestError("wrong_data_type", nil)
+	}
+	if err := booking.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	event, err := h.eventService.BookEvent(ctx, utils.GetAuthAccountId(ctx), booking)
+	if err != nil {
+		var conflict *model.ConflictError
+		if errors.As(err, &conflict) {
+			return conflictResponse(ctx, conflict)
+		}
+		return bookingError(err, "Failed to book event")
+	}
+
+	res.Data = event
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+// bookingError maps the errors of the booking service methods to api errors.
+func bookingError(err error, message string) error {
+	switch err.Error() {
+	case "not_found":
+		return apis.NewNotFoundError(err.Error(), nil)
+	case "invalid_data: event_date", "too_short_notice", "too_far_in_advance":
+		return apis.NewBadRequestError(err.Error(), nil)
+	case "slot_unavailable":
+		return apis.NewApiError(http.StatusConflict, err.Error(), nil)
+	}
+	return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("%s: %v", message, err), nil)
+}
@@ -36,6 +36,10 @@ func (h *EventHandler) HandleScheduleEvent(ctx echo.Context) error {
 
 	scheduledEvent, err := h.eventService.ScheduleEvent(ctx, event)
 	if err != nil {
+		var conflict *model.ConflictError
+		if errors.As(err, &conflict) {
+			return conflictResponse(ctx, conflict)
+		}
 		return apis.NewBadRequestError(fmt.Sprintf("Failed to add event due to: %v", err), nil)
 	}
 
@@ -51,20 +55,21 @@ func (h *EventHandler) HandleGetEvents(ctx echo.Context) error {
 	healthSpecialistId := ctx.QueryParam("healthSpecialistId")
 	patientId := ctx.QueryParam("patientId")
 	after := ctx.QueryParam("after")
+	before := ctx.QueryParam("before")
 
 	if healthSpecialistId != "" && patientId != "" {
 		return apis.NewBadRequestError("Both healthSpecialistId and patientId were specified but only one of the two is expected", nil)
 	}
 
 	if healthSpecialistId != "" {
-		events, err = h.getEventsByHealthSpecialistId(ctx, healthSpecialistId, after)
+		events, err = h.getEventsByHealthSpecialistId(ctx, healthSpecialistId, after, before)
 		if err != nil {
 			return apis.NewBadRequestError(fmt.Sprintf("There was an error retrieving events by specialist id: %s", err.Error()), nil)
 		}
 	}
 
 	if patientId != "" {
-		events, err = h.getEventsByPatientId(ctx, patientId, after)
+		events, err = h.getEventsByPatientId(ctx, patientId, after, before)
 		if err != nil {
 			return apis.NewBadRequestError(fmt.Sprintf("There was an error retrieving events by patient id: %s", err.Error()), nil)
 		}
@@ -91,9 +96,19 @@ func (h *EventHandler) HandleRescheduleEvent(ctx echo.Context) error {
 
 	rescheduledEvent, err := h.eventService.RescheduleEvent(ctx, rescheduleRequest)
 	if err != nil {
+		var conflict *model.ConflictError
+		if errors.As(err, &conflict) {
+			return conflictResponse(ctx, conflict)
+		}
 		if utils.IsErrorNotFound(err) {
 			return apis.NewApiError(http.StatusNotFound, fmt.Sprintf("Failed to reschedule event: no event with id [%s] was found", rescheduleRequest.EventID), nil)
 		}
+		switch err.Error() {
+		case "event_closed":
+			return apis.NewApiError(http.StatusConflict, err.Error(), nil)
+		case "not_an_occurrence":
+			return apis.NewNotFoundError(err.Error(), nil)
+		}
 		return apis.NewBadRequestError(fmt.Sprintf("Failed to reschedule event: %s", err.Error()), nil)
 	}
 
@@ -101,16 +116,24 @@ func (h *EventHandler) HandleRescheduleEvent(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, res)
 }
 
-func (h *EventHandler) getEventsByHealthSpecialistId(ctx echo.Context, id string, after string) ([]*models.Record, error) {
-	events, err := h.eventService.GetEventsByHealthSpecialistId(ctx, id, after)
+// conflictResponse answers with a 409 listing the events the scheduled event overlaps.
+func conflictResponse(ctx echo.Context, conflict *model.ConflictError) error {
+	return ctx.JSON(http.StatusConflict, model.EventResponse{
+		Data:  conflict.Conflicts,
+		Error: conflict.Error(),
+	})
+}
+
+func (h *EventHandler) getEventsByHealthSpecialistId(ctx echo.Context, id string, after string, before string) ([]*models.Record, error) {
+	events, err := h.eventService.GetEventsByHealthSpecialistId(ctx, id, after, before)
 	if err != nil {
 		return nil, errors.New("Failed to retrieve events")
 	}
 	return events, nil
 }
 
-func (h *EventHandler) getEventsByPatientId(ctx echo.Context, id string, after string) ([]*models.Record, error) {
-	events, err := h.eventService.GetEventsByPatientId(ctx, id, after)
+func (h *EventHandler) getEventsByPatientId(ctx echo.Context, id string, after string, before string) ([]*models.Record, error) {
+	events, err := h.eventService.GetEventsByPatientId(ctx, id, after, before)
 	if err != nil {
 		return nil, errors.New("Failed to retrieve events")
 	}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"github.com/arosace/WellnessWaveApi/internal/event/service"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+)
+
+// EventPolicies exposes event lookups to the authorization policies of the event routes.
+type EventPolicies struct {
+	eventService service.EventService
+}
+
+func NewEventPolicies(eventService service.EventService) *EventPolicies {
+	return &EventPolicies{eventService: eventService}
+}
+
+// Organiser resolves the health specialist who organises the extracted event.
+// It resolves to an empty string when the event cannot be found, which denies access.
+func (p *EventPolicies) Organiser(eventId utils.ParamExtractor) utils.ParamExtractor {
+	return func(c echo.Context) string {
+		id := eventId(c)
+		if id == "" {
+			return ""
+		}
+		event, err := p.eventService.GetEventById(c, id)
+		if err != nil {
+			return ""
+		}
+		return event.GetString("health_specialist_id")
+	}
+}
+
+// Patient resolves the patient the extracted event is scheduled with.
+// It resolves to an empty string when the event cannot be found.
+func (p *EventPolicies) Patient(eventId utils.ParamExtractor) utils.ParamExtractor {
+	return func(c echo.Context) string {
+		id := eventId(c)
+		if id == "" {
+			return ""
+		}
+		event, err := p.eventService.GetEventById(c, id)
+		if err != nil {
+			return ""
+		}
+		return event.GetString("patient_id")
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/domain"
+	"github.com/arosace/WellnessWaveApi/internal/event/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+func (h *EventHandler) HandleConfirmEvent(ctx echo.Context) error {
+	return h.transition(ctx, domain.ConfirmedStatus, "Failed to confirm event")
+}
+
+func (h *EventHandler) HandleDeclineEvent(ctx echo.Context) error {
+	return h.transition(ctx, domain.DeclinedStatus, "Failed to decline event")
+}
+
+func (h *EventHandler) HandleCancelEvent(ctx echo.Context) error {
+	return h.transition(ctx, domain.CancelledStatus, "Failed to cancel event")
+}
+
+func (h *EventHandler) HandleCompleteEvent(ctx echo.Context) error {
+	return h.transition(ctx, domain.CompletedStatus, "Failed to complete event")
+}
+
+func (h *EventHandler) HandleNoShowEvent(ctx echo.Context) error {
+	return h.transition(ctx, domain.NoShowStatus, "Failed to mark event as a no-show")
+}
+
+// transition moves the event of the path to the status on behalf of the caller, with the reason of the body if any.
+func (h *EventHandler) transition(ctx echo.Context, status string, message string) error {
+	res := utils.GenericHttpResponse{}
+	var change model.StatusChange
+	if err := ctx.Bind(&change); err != nil {
+		return apis.NewBadRequestError("wrong_data_type", nil)
+	}
+	if err := change.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	event, err := h.eventService.TransitionEvent(ctx, ctx.PathParam("id"), status, utils.GetAuthAccountId(ctx), change)
+	if err != nil {
+		return statusError(err, message)
+	}
+
+	res.Data = event
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// statusError maps the errors of the event status changes to api errors.
+func statusError(err error, message string) error {
+	switch err.Error() {
+	case "not_found", "not_an_occurrence":
+		return apis.NewNotFoundError(err.Error(), nil)
+	case "invalid_transition", "event_started", "event_not_started":
+		return apis.NewApiError(http.StatusConflict, err.Error(), nil)
+	}
+	return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("%s: %v", message, err), nil)
+}
@@ -0,0 +1,254 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/domain"
+)
+
+// Availability holds the working hours of a health specialist, as weekly rules in their timezone,
+// and how long the slots of each event type last.
+type Availability struct {
+	ID                 string         `json:"id,omitempty"`
+	HealthSpecialistID string         `json:"health_specialist_id"`
+	Timezone           string         `json:"timezone"`
+	WeeklyRules        []WeeklyRule   `json:"weekly_rules"`
+	SlotDurations      []SlotDuration `json:"slot_durations"`
+	// DefaultSlotMinutes is the duration of the slots of the other event types, 30 minutes when not set.
+	DefaultSlotMinutes int `json:"default_slot_minutes"`
+
+	// the rules patients booking a slot have to follow
+	MinNoticeMinutes int  `json:"min_notice_minutes"`
+	MaxAdvanceDays   int  `json:"max_advance_days"`
+	RequiresApproval bool `json:"requires_approval"`
+	// CancellationNoticeMinutes is how long before an event it can be cancelled without being late,
+	// 24 hours when not set.
+	CancellationNoticeMinutes int `json:"cancellation_notice_minutes"`
+}
+
+// WeeklyRule makes the health specialist available every week on the weekday, 0 being Sunday,
+// from start to end.
+type WeeklyRule struct {
+	Weekday int    `json:"weekday"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+type SlotDuration struct {
+	EventType string `json:"event_type"`
+	Minutes   int    `json:"minutes"`
+}
+
+func (m *Availability) ValidateModel() error {
+	if m.Timezone == "" {
+		m.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(m.Timezone); err != nil {
+		return errors.New("invalid_timezone")
+	}
+	for _, rule := range m.WeeklyRules {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+	eventTypes := map[string]bool{}
+	for _, duration := range m.SlotDurations {
+		if duration.EventType == "" || eventTypes[duration.EventType] {
+			return errors.New("invalid_slot_duration: event_type")
+		}
+		eventTypes[duration.EventType] = true
+		if !isSlotMinutes(duration.Minutes) {
+			return fmt.Errorf("invalid_slot_duration: %s", duration.EventType)
+		}
+	}
+	if m.DefaultSlotMinutes != 0 && !isSlotMinutes(m.DefaultSlotMinutes) {
+		return errors.New("invalid_slot_duration: default_slot_minutes")
+	}
+	if m.MinNoticeMinutes < 0 || time.Duration(m.MinNoticeMinutes)*time.Minute > domain.MaxMinNotice {
+		return errors.New("invalid_booking_rule: min_notice_minutes")
+	}
+	if m.MaxAdvanceDays == 0 {
+		m.MaxAdvanceDays = domain.DefaultMaxAdvanceDays
+	}
+	if m.MaxAdvanceDays < 1 || m.MaxAdvanceDays > domain.MaxAdvanceDays {
+		return errors.New("invalid_booking_rule: max_advance_days")
+	}
+	if m.CancellationNoticeMinutes == 0 {
+		m.CancellationNoticeMinutes = int(domain.DefaultCancellationNotice.Minutes())
+	}
+	if m.CancellationNoticeMinutes < 1 || time.Duration(m.CancellationNoticeMinutes)*time.Minute > domain.MaxMinNotice {
+		return errors.New("invalid_booking_rule: cancellation_notice_minutes")
+	}
+	return nil
+}
+
+// BookingWindow returns the earliest and latest times patients can book a slot starting at, from now.
+func (m *Availability) BookingWindow(now time.Time) (time.Time, time.Time) {
+	maxAdvanceDays := m.MaxAdvanceDays
+	if maxAdvanceDays == 0 {
+		maxAdvanceDays = domain.DefaultMaxAdvanceDays
+	}
+	return now.Add(time.Duration(m.MinNoticeMinutes) * time.Minute), now.AddDate(0, 0, maxAdvanceDays)
+}
+
+// IsLateCancellation reports whether cancelling at now an event starting at start breaks the cancellation notice.
+func (m *Availability) IsLateCancellation(start time.Time, now time.Time) bool {
+	notice := domain.DefaultCancellationNotice
+	if m.CancellationNoticeMinutes != 0 {
+		notice = time.Duration(m.CancellationNoticeMinutes) * time.Minute
+	}
+	return start.Sub(now) < notice
+}
+
+// SlotDuration returns how long the slots of the event type last.
+func (m *Availability) SlotDuration(eventType string) time.Duration {
+	for _, duration := range m.SlotDurations {
+		if duration.EventType == eventType {
+			return time.Duration(duration.Minutes) * time.Minute
+		}
+	}
+	if m.DefaultSlotMinutes != 0 {
+		return time.Duration(m.DefaultSlotMinutes) * time.Minute
+	}
+	return domain.DefaultSlotDuration
+}
+
+func (r WeeklyRule) validate() error {
+	if r.Weekday < int(time.Sunday) || r.Weekday > int(time.Saturday) {
+		return errors.New("invalid_rule: weekday")
+	}
+	start, err := time.Parse(domain.ClockLayout, r.Start)
+	if err != nil {
+		return errors.New("invalid_rule: start")
+	}
+	end, err := time.Parse(domain.ClockLayout, r.End)
+	if err != nil {
+		return errors.New("invalid_rule: end")
+	}
+	if !start.Before(end) {
+		return errors.New("invalid_rule: start must be before end")
+	}
+	return nil
+}
+
+func isSlotMinutes(minutes int) bool {
+	return minutes >= domain.MinSlotMinutes && minutes <= domain.MaxSlotMinutes
+}
+
+// AvailabilityException makes the health specialist unavailable from StartsAt to EndsAt, e.g. on holidays.
+type AvailabilityException struct {
+	ID                 string `json:"id,omitempty"`
+	HealthSpecialistID string `json:"health_specialist_id"`
+	StartsAt           string `json:"starts_at"`
+	EndsAt             string `json:"ends_at"`
+	Reason             string `json:"reason"`
+}
+
+func (m *AvailabilityException) ValidateModel() error {
+	var missingData []string
+	if m.StartsAt == "" {
+		missingData = append(missingData, "starts_at")
+	}
+	if m.EndsAt == "" {
+		missingData = append(missingData, "ends_at")
+	}
+	if len(missingData) > 0 {
+		return fmt.Errorf("missing_data: %s", strings.Join(missingData, ", "))
+	}
+
+	startsAt, err := time.Parse(domain.Layout, m.StartsAt)
+	if err != nil {
+		return errors.New("invalid_data: starts_at")
+	}
+	endsAt, err := time.Parse(domain.Layout, m.EndsAt)
+	if err != nil {
+		return errors.New("invalid_data: ends_at")
+	}
+	if !startsAt.Before(endsAt) {
+		return errors.New("invalid_data: starts_at must be before ends_at")
+	}
+	return nil
+}
+
+// AvailabilityQuery asks for the free slots of a health specialist for an event type between From and To,
+// given as event dates or as days, a day given as To being included.
+type AvailabilityQuery struct {
+	HealthSpecialistID string
+	EventType          string
+	From               string
+	To                 string
+
+	// the bounds of the period, in UTC, once validated
+	Start time.Time
+	End   time.Time
+}
+
+func (q *AvailabilityQuery) ValidateModel() error {
+	var missingData []string
+	if q.HealthSpecialistID == "" {
+		missingData = append(missingData, "healthSpecialistId")
+	}
+	if q.From == "" {
+		missingData = append(missingData, "from")
+	}
+	if q.To == "" {
+		missingData = append(missingData, "to")
+	}
+	if len(missingData) > 0 {
+		return fmt.Errorf("missing_data: %s", strings.Join(missingData, ", "))
+	}
+
+	var err error
+	if q.Start, err = time.Parse(domain.Layout, q.From); err != nil {
+		if q.Start, err = time.Parse(domain.DateLayout, q.From); err != nil {
+			return errors.New("invalid_data: from")
+		}
+	}
+	if q.End, err = time.Parse(domain.Layout, q.To); err != nil {
+		if q.End, err = time.Parse(domain.DateLayout, q.To); err != nil {
+			return errors.New("invalid_data: to")
+		}
+		q.End = q.End.AddDate(0, 0, 1)
+	}
+	if !q.Start.Before(q.End) || q.End.Sub(q.Start) > domain.MaxAvailabilityRange {
+		return errors.New("invalid_range")
+	}
+	return nil
+}
+
+// Booking is the request of a patient to book a free slot of a health specialist of their care team.
+type Booking struct {
+	HealthSpecialistID string `json:"health_specialist_id"`
+	EventType          string `json:"event_type"`
+	EventDescription   string `json:"event_description"`
+	EventDate          string `json:"event_date"`
+}
+
+func (m *Booking) ValidateModel() error {
+	var missingData []string
+	if m.HealthSpecialistID == "" {
+		missingData = append(missingData, "health_specialist_id")
+	}
+	if m.EventType == "" {
+		missingData = append(missingData, "event_type")
+	}
+	if m.EventDate == "" {
+		missingData = append(missingData, "event_date")
+	}
+	if len(missingData) > 0 {
+		return fmt.Errorf("missing_data: %s", strings.Join(missingData, ", "))
+	}
+	if _, err := time.Parse(domain.Layout, m.EventDate); err != nil {
+		return errors.New("invalid_data: event_date")
+	}
+	return nil
+}
+
+// Slot is a free period of a health specialist, in UTC like event dates.
+type Slot struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// Conflict is an event overlapping the one being scheduled. It only tells when the event takes place and
+// which participant of the new event it involves, not who it is with or what it is about. Occurrences of
+// recurring events also tell the occurrence they are.
+type Conflict struct {
+	EventID      string `json:"event_id"`
+	EventDate    string `json:"event_date"`
+	EndDate      string `json:"end_date"`
+	RecurrenceID string `json:"recurrence_id,omitempty"`
+	Participant  string `json:"participant"`
+}
+
+// ConflictError is returned when an event overlaps other events of its participants.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	return "event_conflict"
+}
+
+// NewConflictError lists the overlapping events as conflicts of the health specialist or of the patient
+// of the event being scheduled.
+func NewConflictError(events []*models.Record, healthSpecialistId string) *ConflictError {
+	conflicts := make([]Conflict, 0, len(events))
+	for _, event := range events {
+		participant := "patient"
+		if event.GetString("health_specialist_id") == healthSpecialistId {
+			participant = "health_specialist"
+		}
+		conflicts = append(conflicts, Conflict{
+			EventID:      event.Id,
+			EventDate:    event.GetDateTime("event_date").String(),
+			EndDate:      event.GetDateTime("end_date").String(),
+			RecurrenceID: event.GetDateTime("recurrence_id").String(),
+			Participant:  participant,
+		})
+	}
+	return &ConflictError{Conflicts: conflicts}
+}
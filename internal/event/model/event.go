@@ -3,6 +3,11 @@ package model
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/domain"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/pocketbase/pocketbase/models"
 )
 
 type Event struct {
@@ -10,8 +15,27 @@ type Event struct {
 	HealthSpecialistID string `json:"health_specialist_id"`
 	PatientID          string `json:"patient_id"`
 	EventType          string `json:"event_type"`
-	EventDescription   string `json:"event_description"`
+	EventDescription   string `json:"event_description" encrypted:"true"`
 	EventDate          string `json:"event_date"`
+	// DurationMinutes defaults to the slot duration of the event type for the health specialist.
+	DurationMinutes int    `json:"duration_minutes"`
+	EndDate         string `json:"end_date"`
+	Status          string `json:"status"`
+	// the last change of status, with the reason given and whether a cancellation broke the cancellation notice
+	StatusReason     string `json:"status_reason" encrypted:"true"`
+	StatusChangedBy  string `json:"status_changed_by"`
+	StatusChangedAt  string `json:"status_changed_at"`
+	LateCancellation bool   `json:"late_cancellation"`
+	// RRule makes the event recur following an RFC 5545 recurrence rule, but for the occurrences of ExDates.
+	RRule   string   `json:"rrule"`
+	ExDates []string `json:"exdates"`
+	// SeriesID and RecurrenceID link an occurrence detached from a series, or a series split from another,
+	// to the series and to the start of the occurrence it replaces.
+	SeriesID     string `json:"series_id"`
+	RecurrenceID string `json:"recurrence_id"`
+	// AllowOverlap schedules the event even if one of its participants has another event at that time.
+	// It is only read from requests and never stored.
+	AllowOverlap bool `json:"allow_overlap,omitempty"`
 }
 
 // ValidateModel validates the event data.
@@ -37,5 +61,51 @@ func (e *Event) ValidateModel() error {
 		return fmt.Errorf("missing_data: %s", strings.Join(errorStrings, ", "))
 	}
 
+	if e.DurationMinutes < 0 || e.DurationMinutes > domain.MaxSlotMinutes {
+		return fmt.Errorf("invalid_data: duration_minutes")
+	}
+
+	if e.RRule != "" {
+		rule, err := utils.ParseRRule(e.RRule)
+		if err != nil {
+			return err
+		}
+		start, err := time.Parse(domain.Layout, e.EventDate)
+		if err != nil {
+			return fmt.Errorf("invalid_data: event_date")
+		}
+		if !rule.Includes(start, start) {
+			return fmt.Errorf("invalid_rrule: event_date is not an occurrence")
+		}
+		e.RRule = rule.String()
+	}
+	for _, exdate := range e.ExDates {
+		if _, err := time.Parse(domain.Layout, exdate); err != nil {
+			return fmt.Errorf("invalid_data: exdates")
+		}
+	}
+
 	return nil
 }
+
+// OccurrenceOf copies the series into an event starting at the occurrence, part of the same series.
+func OccurrenceOf(series *models.Record, occurrence time.Time) Event {
+	seriesId := series.GetString("series_id")
+	if seriesId == "" {
+		seriesId = series.Id
+	}
+	duration := time.Duration(series.GetInt("duration_minutes")) * time.Minute
+	return Event{
+		HealthSpecialistID: series.GetString("health_specialist_id"),
+		PatientID:          series.GetString("patient_id"),
+		EventType:          series.GetString("event_type"),
+		EventDescription:   series.GetString("event_description"),
+		EventDate:          occurrence.Format(domain.Layout),
+		DurationMinutes:    series.GetInt("duration_minutes"),
+		EndDate:            occurrence.Add(duration).Format(domain.Layout),
+		Status:             series.GetString("status"),
+		StatusChangedBy:    series.GetString("status_changed_by"),
+		StatusChangedAt:    series.GetString("status_changed_at"),
+		SeriesID:           seriesId,
+	}
+}
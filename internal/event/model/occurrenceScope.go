@@ -0,0 +1,31 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/domain"
+)
+
+// OccurrenceScope picks which occurrences of a recurring event a change applies to: the occurrence starting
+// at recurrence_id only, it and the following ones, or the whole series. Without recurrence_id the change
+// applies to the whole series, and it is ignored for events that do not recur.
+type OccurrenceScope struct {
+	RecurrenceID string `json:"recurrence_id"`
+	Scope        string `json:"scope"`
+}
+
+func (m *OccurrenceScope) ValidateModel() error {
+	if m.Scope == "" {
+		m.Scope = domain.ThisOccurrence
+	}
+	if !domain.IsScope(m.Scope) {
+		return errors.New("invalid_data: scope")
+	}
+	if m.RecurrenceID != "" {
+		if _, err := time.Parse(domain.Layout, m.RecurrenceID); err != nil {
+			return errors.New("invalid_data: recurrence_id")
+		}
+	}
+	return nil
+}
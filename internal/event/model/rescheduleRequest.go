@@ -3,11 +3,17 @@ package model
 import (
 	"errors"
 	"fmt"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/domain"
 )
 
 type RescheduleRequest struct {
 	EventID string `json:"event_id"`
 	NewDate string `json:"date"`
+	// DurationMinutes changes the duration of the event when set, it is kept otherwise.
+	DurationMinutes int  `json:"duration_minutes"`
+	AllowOverlap    bool `json:"allow_overlap"`
+	OccurrenceScope
 }
 
 func (r *RescheduleRequest) ValidateModel() error {
@@ -21,5 +27,8 @@ func (r *RescheduleRequest) ValidateModel() error {
 	if len(errorList) > 0 {
 		return errors.New(fmt.Sprintf("missing_parameters: %v", errorList))
 	}
-	return nil
+	if r.DurationMinutes < 0 || r.DurationMinutes > domain.MaxSlotMinutes {
+		return errors.New("invalid_data: duration_minutes")
+	}
+	return r.OccurrenceScope.ValidateModel()
 }
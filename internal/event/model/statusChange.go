@@ -0,0 +1,24 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// MaxReasonLength is the longest reason a status change can be given.
+const MaxReasonLength = 500
+
+// StatusChange is the optional reason given when moving an event to another status, and the occurrences
+// of a recurring event it applies to.
+type StatusChange struct {
+	Reason string `json:"reason"`
+	OccurrenceScope
+}
+
+func (m *StatusChange) ValidateModel() error {
+	m.Reason = strings.TrimSpace(m.Reason)
+	if len(m.Reason) > MaxReasonLength {
+		return errors.New("invalid_data: reason")
+	}
+	return m.OccurrenceScope.ValidateModel()
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/domain"
+	"github.com/arosace/WellnessWaveApi/internal/event/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// AvailabilityRepository defines the interface for the data access of the working hours of health specialists.
+type AvailabilityRepository interface {
+	FindByHealthSpecialistId(echo.Context, string) (*models.Record, error)
+	Upsert(echo.Context, model.Availability) (*models.Record, error)
+	AddException(echo.Context, model.AvailabilityException) (*models.Record, error)
+	FindExceptionById(echo.Context, string) (*models.Record, error)
+	FindExceptions(echo.Context, string, string, string) ([]*models.Record, error)
+	DeleteException(echo.Context, *models.Record) error
+}
+
+type AvailabilityRepo struct {
+	Dao *daos.Dao
+}
+
+func NewAvailabilityRepository(dao *daos.Dao) *AvailabilityRepo {
+	return &AvailabilityRepo{Dao: dao}
+}
+
+func (r *AvailabilityRepo) FindByHealthSpecialistId(ctx echo.Context, healthSpecialistId string) (*models.Record, error) {
+	params := dbx.Params{"health_specialist_id": healthSpecialistId}
+	record, err := r.Dao.FindFirstRecordByFilter(
+		domain.AvailabilityTableName,
+		utils.TenantFilter(ctx, "health_specialist_id = {:health_specialist_id}", params, "health_specialist_id"),
+		params,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving the availability of [%s]: %w", healthSpecialistId, err)
+	}
+	return record, nil
+}
+
+// Upsert creates the availability of the health specialist or replaces the one they have.
+func (r *AvailabilityRepo) Upsert(ctx echo.Context, availability model.Availability) (*models.Record, error) {
+	record, err := r.FindByHealthSpecialistId(ctx, availability.HealthSpecialistID)
+	if err != nil {
+		if !utils.IsErrorNotFound(err) {
+			return nil, err
+		}
+		collection, err := r.Dao.FindCollectionByNameOrId(domain.AvailabilityTableName)
+		if err != nil {
+			return nil, err
+		}
+		record = models.NewRecord(collection)
+		utils.StampTenant(ctx, record)
+	}
+
+	availability.ID = record.Id
+	utils.LoadFromStruct(record, &availability)
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("Failed to save availability: %w", err)
+	}
+	return record, nil
+}
+
+func (r *AvailabilityRepo) AddException(ctx echo.Context, exception model.AvailabilityException) (*models.Record, error) {
+	collection, err := r.Dao.FindCollectionByNameOrId(domain.AvailabilityExceptionsTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.NewRecord(collection)
+	utils.LoadFromStruct(record, &exception)
+	utils.StampTenant(ctx, record)
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("Failed to save availability exception: %w", err)
+	}
+	return record, nil
+}
+
+func (r *AvailabilityRepo) FindExceptionById(ctx echo.Context, id string) (*models.Record, error) {
+	record, err := r.Dao.FindRecordById(domain.AvailabilityExceptionsTableName, id)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving availability exception [%s]: %w", id, err)
+	}
+	if err := utils.CheckTenant(ctx, record, "health_specialist_id"); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// FindExceptions returns the exceptions of the health specialist overlapping the period from after
+// to before, each bound being optional.
+func (r *AvailabilityRepo) FindExceptions(ctx echo.Context, healthSpecialistId string, after string, before string) ([]*models.Record, error) {
+	params := dbx.Params{"health_specialist_id": healthSpecialistId}
+	filter := "health_specialist_id = {:health_specialist_id}"
+	if after != "" {
+		filter += " && ends_at > {:after}"
+		params["after"] = after
+	}
+	if before != "" {
+		filter += " && starts_at < {:before}"
+		params["before"] = before
+	}
+
+	records, err := r.Dao.FindRecordsByFilter(
+		domain.AvailabilityExceptionsTableName,
+		utils.TenantFilter(ctx, filter, params, "health_specialist_id"),
+		"starts_at",
+		-1,
+		0,
+		params,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving the availability exceptions of [%s]: %w", healthSpecialistId, err)
+	}
+	return records, nil
+}
+
+func (r *AvailabilityRepo) DeleteException(ctx echo.Context, record *models.Record) error {
+	if err := r.Dao.DeleteRecord(record); err != nil {
+		return fmt.Errorf("there was an error deleting availability exception [%s]: %w", record.Id, err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/arosace/WellnessWaveApi/internal/event/domain"
 	"github.com/arosace/WellnessWaveApi/internal/event/model"
@@ -11,27 +12,42 @@ import (
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/daos"
 	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
 )
 
+// ownerFields hold the accounts an event belongs to, for the scoping of accounts outside of any organisation.
+var ownerFields = []string{"health_specialist_id", "patient_id"}
+
 type EventRepo struct {
-	Dao *daos.Dao
+	Dao    *daos.Dao
+	Cipher *utils.FieldCipher
 }
 
 type EventRepository interface {
-	Add(ctx echo.Context, event model.Event) (*models.Record, error)
+	Add(ctx echo.Context, event model.Event, allowOverlap bool) (*models.Record, error)
 	GetByHealthSpecialistId(echo.Context, string, string) ([]*models.Record, error)
 	GetByPatientId(echo.Context, string, string) ([]*models.Record, error)
-	Update(echo.Context, *models.Record) (*models.Record, error)
+	GetByHealthSpecialistIdBetween(echo.Context, string, string, string) ([]*models.Record, error)
+	CountByPatientId(echo.Context, string) (int, error)
+	Update(echo.Context, *models.Record, bool) (*models.Record, error)
 	GetById(echo.Context, string) (*models.Record, error)
+	Reencrypt(echo.Context) (int, error)
+	BackfillEndDates(echo.Context, time.Duration) (int, error)
+	BackfillStatuses(echo.Context, string) (int, error)
+	SplitSeries(echo.Context, *models.Record, time.Time) (*models.Record, error)
+	InTransaction(func(EventRepository) error) error
 }
 
-func NewEventRepository(dao *daos.Dao) *EventRepo {
+func NewEventRepository(dao *daos.Dao, encryptor utils.Encryption) *EventRepo {
 	return &EventRepo{
-		Dao: dao,
+		Dao:    dao,
+		Cipher: utils.NewFieldCipher(encryptor),
 	}
 }
 
-func (r *EventRepo) Add(ctx echo.Context, event model.Event) (*models.Record, error) {
+// Add saves the event, failing with a ConflictError if it overlaps another event of its health specialist
+// or patient unless allowOverlap is set.
+func (r *EventRepo) Add(ctx echo.Context, event model.Event, allowOverlap bool) (*models.Record, error) {
 	collection, err := r.Dao.FindCollectionByNameOrId(domain.TABLENAME)
 	if err != nil {
 		return nil, err
@@ -39,7 +55,8 @@ func (r *EventRepo) Add(ctx echo.Context, event model.Event) (*models.Record, er
 
 	record := models.NewRecord(collection)
 	utils.LoadFromStruct(record, &event)
-	if err := r.Dao.SaveRecord(record); err != nil {
+	utils.StampTenant(ctx, record)
+	if err := r.saveWithoutConflicts(record, allowOverlap); err != nil {
 		return nil, fmt.Errorf("Failed to save event: %w", err)
 	}
 
@@ -51,13 +68,14 @@ func (r *EventRepo) GetByHealthSpecialistId(ctx echo.Context, healthSpecialistId
 	params := dbx.Params{column: healthSpecialistId}
 	filter := fmt.Sprintf("%s = {:%s}", column, column)
 	if after != "" {
-		filter += " && event_date > {:after}"
+		// recurring events may have occurrences after it whenever they start
+		filter += " && (event_date > {:after} || rrule != '')"
 		params["after"] = after
 	}
 
 	records, err := r.Dao.FindRecordsByFilter(
 		domain.TABLENAME,
-		filter,
+		utils.TenantFilter(ctx, filter, params, ownerFields...),
 		"-event_date",
 		-1,
 		0,
@@ -66,8 +84,7 @@ func (r *EventRepo) GetByHealthSpecialistId(ctx echo.Context, healthSpecialistId
 	if err != nil {
 		return nil, fmt.Errorf("there was an error retrieving events by %s: %w", column, err)
 	}
-
-	return records, nil
+	return r.Cipher.OpenAll(records, model.Event{}), nil
 }
 
 func (r *EventRepo) GetByPatientId(ctx echo.Context, patientId string, after string) ([]*models.Record, error) {
@@ -75,13 +92,14 @@ func (r *EventRepo) GetByPatientId(ctx echo.Context, patientId string, after str
 	params := dbx.Params{column: patientId}
 	filter := fmt.Sprintf("%s = {:%s}", column, column)
 	if after != "" {
-		filter += " && event_date > {:after}"
+		// recurring events may have occurrences after it whenever they start
+		filter += " && (event_date > {:after} || rrule != '')"
 		params["after"] = after
 	}
 
 	records, err := r.Dao.FindRecordsByFilter(
 		domain.TABLENAME,
-		filter,
+		utils.TenantFilter(ctx, filter, params, ownerFields...),
 		"-event_date",
 		-1,
 		0,
@@ -90,8 +108,39 @@ func (r *EventRepo) GetByPatientId(ctx echo.Context, patientId string, after str
 	if err != nil {
 		return nil, fmt.Errorf("there was an error retrieving events by %s: %w", column, err)
 	}
+	return r.Cipher.OpenAll(records, model.Event{}), nil
+}
+
+// GetByHealthSpecialistIdBetween returns the events of the health specialist taking place from after
+// to before, oldest first, with the recurring events starting before that may have occurrences then.
+func (r *EventRepo) GetByHealthSpecialistIdBetween(ctx echo.Context, healthSpecialistId string, after string, before string) ([]*models.Record, error) {
+	params := dbx.Params{"health_specialist_id": healthSpecialistId, "after": after, "before": before}
+	records, err := r.Dao.FindRecordsByFilter(
+		domain.TABLENAME,
+		utils.TenantFilter(ctx, "health_specialist_id = {:health_specialist_id} && (event_date >= {:after} || rrule != '') && event_date < {:before}", params, ownerFields...),
+		"event_date",
+		-1,
+		0,
+		params,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving events by health_specialist_id: %w", err)
+	}
+	return r.Cipher.OpenAll(records, model.Event{}), nil
+}
 
-	return records, nil
+// CountByPatientId returns how many events the patient has, without loading them.
+func (r *EventRepo) CountByPatientId(ctx echo.Context, patientId string) (int, error) {
+	var count int
+	err := r.Dao.RecordQuery(domain.TABLENAME).
+		Select("count(*)").
+		AndWhere(dbx.HashExp{"patient_id": patientId}).
+		AndWhere(utils.TenantExp(ctx, utils.TenantField, ownerFields...)).
+		Row(&count)
+	if err != nil {
+		return 0, fmt.Errorf("there was an error counting events by patient_id: %w", err)
+	}
+	return count, nil
 }
 
 func (r *EventRepo) GetById(ctx echo.Context, id string) (*models.Record, error) {
@@ -99,17 +148,276 @@ func (r *EventRepo) GetById(ctx echo.Context, id string) (*models.Record, error)
 	if err != nil {
 		return nil, fmt.Errorf("there was an error retrieving event [%s]: %w", id, err)
 	}
+	if err := utils.CheckTenant(ctx, record, ownerFields...); err != nil {
+		return nil, err
+	}
+	if err := r.Cipher.Open(record, model.Event{}); err != nil {
+		return nil, err
+	}
 	return record, nil
 }
 
-func (r *EventRepo) Update(ctx echo.Context, record *models.Record) (*models.Record, error) {
+// Update saves the event, failing with a ConflictError if it overlaps another event of its health specialist
+// or patient unless allowOverlap is set.
+func (r *EventRepo) Update(ctx echo.Context, record *models.Record, allowOverlap bool) (*models.Record, error) {
 	record.MarkAsNotNew()
-	if err := r.Dao.SaveRecord(record); err != nil {
-		return nil, fmt.Errorf("there was an error rescheduling event: %w", err)
+	if err := r.saveWithoutConflicts(record, allowOverlap); err != nil {
+		return nil, fmt.Errorf("there was an error updating event: %w", err)
+	}
+	return record, nil
+}
+
+// SplitSeries ends the series before the occurrence and returns a new series with the occurrence and the
+// following ones, in the organisation of the series, which can then be changed on their own. The series is
+// returned as is when the occurrence is its start. Neither part takes up more time than the series did, so
+// they are saved without checking for overlaps.
+func (r *EventRepo) SplitSeries(ctx echo.Context, series *models.Record, occurrence time.Time) (*models.Record, error) {
+	start := series.GetDateTime("event_date").Time()
+	if occurrence.Equal(start) {
+		return series, nil
+	}
+	rule, err := utils.ParseRRule(series.GetString("rrule"))
+	if err != nil {
+		return nil, err
+	}
+
+	following := *rule
+	if rule.Count > 0 {
+		following.Count = rule.Count - rule.CountBefore(start, occurrence)
+	}
+	previous := *rule
+	previous.Count = 0
+	previous.Until = occurrence.Add(-time.Second)
+
+	var previousExDates, followingExDates []string
+	for _, exdate := range exDates(series) {
+		if exdate.Before(occurrence) {
+			previousExDates = append(previousExDates, exdate.Format(domain.Layout))
+		} else {
+			followingExDates = append(followingExDates, exdate.Format(domain.Layout))
+		}
+	}
+
+	collection, err := r.Dao.FindCollectionByNameOrId(domain.TABLENAME)
+	if err != nil {
+		return nil, err
+	}
+	event := model.OccurrenceOf(series, occurrence)
+	event.RRule = following.String()
+	event.ExDates = followingExDates
+	record := models.NewRecord(collection)
+	utils.LoadFromStruct(record, &event)
+	record.Set(utils.TenantField, series.GetString(utils.TenantField))
+
+	err = r.Dao.RunInTransaction(func(txDao *daos.Dao) error {
+		events := &EventRepo{Dao: txDao, Cipher: r.Cipher}
+		if err := events.saveWithoutConflicts(record, true); err != nil {
+			return fmt.Errorf("Failed to save event: %w", err)
+		}
+		series.Set("rrule", previous.String())
+		series.Set("exdates", previousExDates)
+		_, err := events.Update(ctx, series, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 	return record, nil
 }
 
+// InTransaction runs fn with a repository saving its changes in a single transaction, so either all of them
+// are saved or none is if fn fails.
+func (r *EventRepo) InTransaction(fn func(EventRepository) error) error {
+	return r.Dao.RunInTransaction(func(txDao *daos.Dao) error {
+		return fn(&EventRepo{Dao: txDao, Cipher: r.Cipher})
+	})
+}
+
+// BackfillEndDates gives the events scheduled before events had a duration the given one, returning
+// how many were updated.
+func (r *EventRepo) BackfillEndDates(ctx echo.Context, duration time.Duration) (int, error) {
+	var events []struct {
+		ID        string         `db:"id"`
+		EventDate types.DateTime `db:"event_date"`
+	}
+	err := r.Dao.DB().
+		Select("id", "event_date").
+		From(domain.TABLENAME).
+		Where(dbx.HashExp{"end_date": ""}).
+		All(&events)
+	if err != nil {
+		return 0, fmt.Errorf("there was an error fetching events without an end date: %w", err)
+	}
+
+	for i, event := range events {
+		endDate, err := types.ParseDateTime(event.EventDate.Time().Add(duration))
+		if err != nil {
+			return i, err
+		}
+		_, err = r.Dao.DB().Update(
+			domain.TABLENAME,
+			dbx.Params{"end_date": endDate.String(), "duration_minutes": int(duration.Minutes())},
+			dbx.HashExp{"id": event.ID},
+		).Execute()
+		if err != nil {
+			return i, fmt.Errorf("there was an error setting the end date of event [%s]: %w", event.ID, err)
+		}
+	}
+	return len(events), nil
+}
+
+// BackfillStatuses gives the events scheduled before events had a status the given one, returning
+// how many were updated.
+func (r *EventRepo) BackfillStatuses(ctx echo.Context, status string) (int, error) {
+	result, err := r.Dao.DB().Update(domain.TABLENAME, dbx.Params{"status": status}, dbx.HashExp{"status": ""}).Execute()
+	if err != nil {
+		return 0, fmt.Errorf("there was an error setting the status of events: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	return int(updated), err
+}
+
+// saveWithoutConflicts checks that the event does not overlap the other events of its participants and
+// saves it in the same transaction, so two events cannot be booked at the same time concurrently.
+func (r *EventRepo) saveWithoutConflicts(record *models.Record, allowOverlap bool) error {
+	err := r.Dao.RunInTransaction(func(txDao *daos.Dao) error {
+		if !allowOverlap {
+			conflicts, err := findConflicts(txDao, record)
+			if err != nil {
+				return err
+			}
+			if len(conflicts) > 0 {
+				return model.NewConflictError(conflicts, record.GetString("health_specialist_id"))
+			}
+		}
+
+		if err := r.Cipher.Seal(record, model.Event{}); err != nil {
+			return err
+		}
+		return txDao.SaveRecord(record)
+	})
+	if err != nil {
+		return err
+	}
+	return r.Cipher.Open(record, model.Event{})
+}
+
+// findConflicts returns the events of the participants of the event overlapping it, or the occurrences
+// overlapping one of its occurrences for recurring events. Recurring events are only checked over the
+// expansion window from their start or now, whichever comes last, as they may not end.
+func findConflicts(dao *daos.Dao, record *models.Record) ([]*models.Record, error) {
+	candidates := []*models.Record{record}
+	if record.GetString("rrule") != "" {
+		from := time.Now().UTC()
+		if start := record.GetDateTime("event_date").Time(); from.Before(start) {
+			from = start
+		}
+		var err error
+		if candidates, err = occurrences(record, from, from.Add(domain.DefaultExpansionWindow)); err != nil {
+			return nil, err
+		}
+		if len(candidates) == 0 {
+			return nil, nil
+		}
+	}
+	start := candidates[0].GetDateTime("event_date")
+	end := candidates[len(candidates)-1].GetDateTime("end_date")
+
+	params := dbx.Params{
+		"id":                   record.Id,
+		"health_specialist_id": record.GetString("health_specialist_id"),
+		"patient_id":           record.GetString("patient_id"),
+		"start":                start.String(),
+		"end":                  end.String(),
+	}
+	// recurring events may have occurrences in the window whenever they start
+	filter := "id != {:id} && (health_specialist_id = {:health_specialist_id} || patient_id = {:patient_id}) && event_date < {:end} && (end_date > {:start} || rrule != '')"
+	for i, status := range domain.FreeingStatuses {
+		param := fmt.Sprintf("freeing%d", i)
+		filter += fmt.Sprintf(" && status != {:%s}", param)
+		params[param] = status
+	}
+	events, err := dao.FindRecordsByFilter(
+		domain.TABLENAME,
+		filter,
+		"event_date",
+		-1,
+		0,
+		params,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error looking for conflicting events: %w", err)
+	}
+
+	conflicts := []*models.Record{}
+	for _, event := range events {
+		duration := time.Duration(event.GetInt("duration_minutes")) * time.Minute
+		// occurrences starting before the window may still end in it
+		eventOccurrences, err := occurrences(event, start.Time().Add(-duration), end.Time())
+		if err != nil {
+			return nil, err
+		}
+		for _, occurrence := range eventOccurrences {
+			if overlapsAny(occurrence, candidates) {
+				conflicts = append(conflicts, occurrence)
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// occurrences returns the event itself if it does not recur, or its occurrences starting from after to before
+// otherwise, carrying their start as recurrence_id.
+func occurrences(record *models.Record, after time.Time, before time.Time) ([]*models.Record, error) {
+	if record.GetString("rrule") == "" {
+		return []*models.Record{record}, nil
+	}
+	rule, err := utils.ParseRRule(record.GetString("rrule"))
+	if err != nil {
+		return nil, err
+	}
+
+	duration := time.Duration(record.GetInt("duration_minutes")) * time.Minute
+	events := []*models.Record{}
+	for _, start := range rule.Between(record.GetDateTime("event_date").Time(), after, before, exDates(record)) {
+		event := record.CleanCopy()
+		event.Set("event_date", start.Format(domain.Layout))
+		event.Set("end_date", start.Add(duration).Format(domain.Layout))
+		event.Set("recurrence_id", start.Format(domain.Layout))
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// exDates returns the excluded dates of the recurring event.
+func exDates(record *models.Record) []time.Time {
+	exdates := []time.Time{}
+	var values []string
+	record.UnmarshalJSONField("exdates", &values)
+	for _, value := range values {
+		if exdate, err := time.Parse(domain.Layout, value); err == nil {
+			exdates = append(exdates, exdate)
+		}
+	}
+	return exdates
+}
+
+func overlapsAny(event *models.Record, others []*models.Record) bool {
+	start := event.GetDateTime("event_date").Time()
+	end := event.GetDateTime("end_date").Time()
+	for _, other := range others {
+		if start.Before(other.GetDateTime("end_date").Time()) && end.After(other.GetDateTime("event_date").Time()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reencrypt rewrites the encrypted fields of every event with the active encryption key.
+func (r *EventRepo) Reencrypt(ctx echo.Context) (int, error) {
+	return r.Cipher.ReencryptCollection(r.Dao, domain.TABLENAME, model.Event{})
+}
+
 func (r *EventRepo) LoadFromStruct(record *models.Record, event *model.Event) error {
 	data, err := json.Marshal(event)
 	if err != nil {
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/domain"
+	"github.com/arosace/WellnessWaveApi/internal/event/model"
+	"github.com/arosace/WellnessWaveApi/pkg/testutils"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRepository(t *testing.T) *EventRepo {
+	app := testutils.NewTestApp(t)
+	testutils.NewCollection(t, app.Dao(), domain.TABLENAME, testutils.EventFields()...)
+	return NewEventRepository(app.Dao(), testutils.NewEncryptor(t))
+}
+
+func newEvent(patientId string, eventDate string, rrule string) model.Event {
+	return model.Event{
+		HealthSpecialistID: "specialist",
+		PatientID:          patientId,
+		EventType:          "consultation",
+		EventDate:          eventDate,
+		DurationMinutes:    60,
+		Status:             domain.ConfirmedStatus,
+		RRule:              rrule,
+	}
+}
+
+func addEvent(t *testing.T, repository *EventRepo, event model.Event) (*models.Record, error) {
+	start, err := time.Parse(domain.Layout, event.EventDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.EndDate = start.Add(time.Duration(event.DurationMinutes) * time.Minute).Format(domain.Layout)
+	return repository.Add(nil, event, false)
+}
+
+func conflictsOf(t *testing.T, err error) []model.Conflict {
+	var conflictErr *model.ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	return conflictErr.Conflicts
+}
+
+func TestEventsOverlappingOccurrencesConflict(t *testing.T) {
+	repository := newTestRepository(t)
+
+	// every Monday from 4 March 2030, but for 11 March
+	weekly := newEvent("patient", "2030-03-04 09:00:00", "FREQ=WEEKLY")
+	weekly.ExDates = []string{"2030-03-11 09:00:00"}
+	series, err := addEvent(t, repository, weekly)
+	assert.Nil(t, err)
+
+	_, err = addEvent(t, repository, newEvent("other", "2030-03-18 09:30:00", ""))
+	conflicts := conflictsOf(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, series.Id, conflicts[0].EventID)
+	assert.Equal(t, "2030-03-18 09:00:00.000Z", conflicts[0].RecurrenceID)
+	assert.Equal(t, "health_specialist", conflicts[0].Participant)
+
+	_, err = addEvent(t, repository, newEvent("other", "2030-03-11 09:30:00", ""))
+	assert.Nil(t, err, "excluded occurrences do not take up time")
+	_, err = addEvent(t, repository, newEvent("other", "2030-03-19 09:00:00", ""))
+	assert.Nil(t, err)
+}
+
+func TestRecurringEventsOverlappingEventsConflict(t *testing.T) {
+	repository := newTestRepository(t)
+
+	// Monday 1 April 2030
+	oneOff, err := addEvent(t, repository, newEvent("patient", "2030-04-01 09:30:00", ""))
+	assert.Nil(t, err)
+
+	_, err = addEvent(t, repository, newEvent("other", "2030-03-04 09:00:00", "FREQ=WEEKLY"))
+	conflicts := conflictsOf(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, oneOff.Id, conflicts[0].EventID)
+
+	_, err = addEvent(t, repository, newEvent("other", "2030-03-04 09:00:00", "FREQ=WEEKLY;COUNT=4"))
+	assert.Nil(t, err, "the series ends before the event")
+
+	_, err = addEvent(t, repository, newEvent("third", "2030-03-06 09:00:00", "FREQ=DAILY"))
+	conflicts = conflictsOf(t, err)
+	assert.Equal(t, "2030-03-11 09:00:00.000Z", conflicts[0].RecurrenceID, "occurrences of both series are compared")
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/domain"
+	"github.com/arosace/WellnessWaveApi/internal/event/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// interval is a period a health specialist is busy or free, from start to end.
+type interval struct {
+	start time.Time
+	end   time.Time
+}
+
+func (i interval) overlaps(other interval) bool {
+	return i.start.Before(other.end) && other.start.Before(i.end)
+}
+
+// GetAvailability returns the working hours of the health specialist, none until they set them.
+func (e *eventService) GetAvailability(ctx echo.Context, healthSpecialistId string) (*model.Availability, error) {
+	record, err := e.availabilityRepository.FindByHealthSpecialistId(ctx, healthSpecialistId)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return &model.Availability{
+				HealthSpecialistID:        healthSpecialistId,
+				Timezone:                  "UTC",
+				WeeklyRules:               []model.WeeklyRule{},
+				SlotDurations:             []model.SlotDuration{},
+				MaxAdvanceDays:            domain.DefaultMaxAdvanceDays,
+				CancellationNoticeMinutes: int(domain.DefaultCancellationNotice.Minutes()),
+			}, nil
+		}
+		return nil, err
+	}
+	return toAvailability(record), nil
+}
+
+// SetAvailability replaces the working hours and slot durations of the health specialist.
+func (e *eventService) SetAvailability(ctx echo.Context, healthSpecialistId string, availability model.Availability) (*model.Availability, error) {
+	availability.HealthSpecialistID = healthSpecialistId
+	if availability.WeeklyRules == nil {
+		availability.WeeklyRules = []model.WeeklyRule{}
+	}
+	if availability.SlotDurations == nil {
+		availability.SlotDurations = []model.SlotDuration{}
+	}
+	record, err := e.availabilityRepository.Upsert(ctx, availability)
+	if err != nil {
+		return nil, err
+	}
+	return toAvailability(record), nil
+}
+
+// GetAvailabilityExceptions returns the exceptions of the health specialist that are not over yet.
+func (e *eventService) GetAvailabilityExceptions(ctx echo.Context, healthSpecialistId string) ([]*models.Record, error) {
+	return e.availabilityRepository.FindExceptions(ctx, healthSpecialistId, time.Now().UTC().Format(domain.Layout), "")
+}
+
+func (e *eventService) AddAvailabilityException(ctx echo.Context, healthSpecialistId string, exception model.AvailabilityException) (*models.Record, error) {
+	exception.ID = ""
+	exception.HealthSpecialistID = healthSpecialistId
+	return e.availabilityRepository.AddException(ctx, exception)
+}
+
+func (e *eventService) DeleteAvailabilityException(ctx echo.Context, healthSpecialistId string, exceptionId string) error {
+	exception, err := e.availabilityRepository.FindExceptionById(ctx, exceptionId)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return errors.New("not_found")
+		}
+		return err
+	}
+	if exception.GetString("health_specialist_id") != healthSpecialistId {
+		return errors.New("not_found")
+	}
+	return e.availabilityRepository.DeleteException(ctx, exception)
+}
+
+// GetFreeSlots splits the working hours of the health specialist in the queried period into slots of the
+// duration of the event type, leaving out the past, the exceptions and the slots overlapping their events.
+func (e *eventService) GetFreeSlots(ctx echo.Context, query model.AvailabilityQuery) ([]model.Slot, error) {
+	availability, err := e.GetAvailability(ctx, query.HealthSpecialistID)
+	if err != nil {
+		return nil, err
+	}
+	busy, err := e.busyIntervals(ctx, availability, query.Start, query.End)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := time.LoadLocation(availability.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	duration := availability.SlotDuration(query.EventType)
+	now := time.Now()
+
+	candidates := []interval{}
+	first := query.Start.In(location)
+	for day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, location); day.Before(query.End); day = day.AddDate(0, 0, 1) {
+		for _, rule := range availability.WeeklyRules {
+			if time.Weekday(rule.Weekday) != day.Weekday() {
+				continue
+			}
+			window, err := ruleWindow(rule, day)
+			if err != nil {
+				continue
+			}
+			for start := window.start; !start.Add(duration).After(window.end); start = start.Add(duration) {
+				slot := interval{start: start, end: start.Add(duration)}
+				if slot.start.Before(query.Start) || slot.end.After(query.End) || slot.start.Before(now) {
+					continue
+				}
+				candidates = append(candidates, slot)
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].start.Before(candidates[j].start) })
+
+	slots := []model.Slot{}
+	var last *interval
+	for i, candidate := range candidates {
+		// overlapping weekly rules would offer the same time twice
+		if last != nil && candidate.start.Before(last.end) {
+			continue
+		}
+		if overlapsAny(candidate, busy) {
+			continue
+		}
+		last = &candidates[i]
+		slots = append(slots, model.Slot{
+			Start: candidate.start.UTC().Format(domain.Layout),
+			End:   candidate.end.UTC().Format(domain.Layout),
+		})
+	}
+	return slots, nil
+}
+
+// busyIntervals returns the exceptions and the events of the health specialist overlapping the period.
+func (e *eventService) busyIntervals(ctx echo.Context, availability *model.Availability, start time.Time, end time.Time) ([]interval, error) {
+	exceptions, err := e.availabilityRepository.FindExceptions(ctx, availability.HealthSpecialistID, start.Format(domain.Layout), end.Format(domain.Layout))
+	if err != nil {
+		return nil, err
+	}
+	// events starting before the period may still be running when it starts
+	lookBack := time.Duration(domain.MaxSlotMinutes) * time.Minute
+	records, err := e.eventRepository.GetByHealthSpecialistIdBetween(ctx, availability.HealthSpecialistID, start.Add(-lookBack).Format(domain.Layout), end.Format(domain.Layout))
+	if err != nil {
+		return nil, err
+	}
+	events, err := expandSeries(records, start.Add(-lookBack), end, true)
+	if err != nil {
+		return nil, err
+	}
+
+	busy := make([]interval, 0, len(exceptions)+len(events))
+	for _, exception := range exceptions {
+		busy = append(busy, interval{
+			start: exception.GetDateTime("starts_at").Time(),
+			end:   exception.GetDateTime("ends_at").Time(),
+		})
+	}
+	for _, event := range events {
+		if domain.FreesTime(event.GetString("status")) {
+			continue
+		}
+		busy = append(busy, interval{
+			start: event.GetDateTime("event_date").Time(),
+			end:   event.GetDateTime("end_date").Time(),
+		})
+	}
+	return busy, nil
+}
+
+// ruleWindow returns the working hours the weekly rule gives on the day, in the timezone of the day.
+func ruleWindow(rule model.WeeklyRule, day time.Time) (interval, error) {
+	start, err := time.Parse(domain.ClockLayout, rule.Start)
+	if err != nil {
+		return interval{}, err
+	}
+	end, err := time.Parse(domain.ClockLayout, rule.End)
+	if err != nil {
+		return interval{}, err
+	}
+	return interval{
+		start: time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, day.Location()),
+		end:   time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, day.Location()),
+	}, nil
+}
+
+func overlapsAny(slot interval, busy []interval) bool {
+	for _, period := range busy {
+		if slot.overlaps(period) {
+			return true
+		}
+	}
+	return false
+}
+
+func toAvailability(record *models.Record) *model.Availability {
+	availability := &model.Availability{
+		ID:                        record.Id,
+		HealthSpecialistID:        record.GetString("health_specialist_id"),
+		Timezone:                  record.GetString("timezone"),
+		WeeklyRules:               []model.WeeklyRule{},
+		SlotDurations:             []model.SlotDuration{},
+		DefaultSlotMinutes:        record.GetInt("default_slot_minutes"),
+		MinNoticeMinutes:          record.GetInt("min_notice_minutes"),
+		MaxAdvanceDays:            record.GetInt("max_advance_days"),
+		RequiresApproval:          record.GetBool("requires_approval"),
+		CancellationNoticeMinutes: record.GetInt("cancellation_notice_minutes"),
+	}
+	record.UnmarshalJSONField("weekly_rules", &availability.WeeklyRules)
+	record.UnmarshalJSONField("slot_durations", &availability.SlotDurations)
+	return availability
+}
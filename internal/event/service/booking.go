@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/domain"
+	"github.com/arosace/WellnessWaveApi/internal/event/model"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// BookEvent books a free slot of the health specialist for the patient, following the booking rules of the
+// specialist. The event is requested until the specialist confirms it if they require approval.
+func (e *eventService) BookEvent(ctx echo.Context, patientId string, booking model.Booking) (*models.Record, error) {
+	start, err := time.Parse(domain.Layout, booking.EventDate)
+	if err != nil {
+		return nil, errors.New("invalid_data: event_date")
+	}
+	availability, err := e.GetAvailability(ctx, booking.HealthSpecialistID)
+	if err != nil {
+		return nil, err
+	}
+
+	earliest, latest := availability.BookingWindow(time.Now())
+	if start.Before(earliest) {
+		return nil, errors.New("too_short_notice")
+	}
+	if start.After(latest) {
+		return nil, errors.New("too_far_in_advance")
+	}
+
+	duration := availability.SlotDuration(booking.EventType)
+	slots, err := e.GetFreeSlots(ctx, model.AvailabilityQuery{
+		HealthSpecialistID: booking.HealthSpecialistID,
+		EventType:          booking.EventType,
+		Start:              start,
+		End:                start.Add(duration),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(slots) == 0 || slots[0].Start != booking.EventDate {
+		return nil, errors.New("slot_unavailable")
+	}
+
+	status := domain.ConfirmedStatus
+	if availability.RequiresApproval {
+		status = domain.RequestedStatus
+	}
+	// the slot was free when computed, scheduling checks again that nothing was booked since
+	return e.eventRepository.Add(ctx, model.Event{
+		HealthSpecialistID: booking.HealthSpecialistID,
+		PatientID:          patientId,
+		EventType:          booking.EventType,
+		EventDescription:   booking.EventDescription,
+		EventDate:          booking.EventDate,
+		DurationMinutes:    int(duration.Minutes()),
+		EndDate:            start.Add(duration).Format(domain.Layout),
+		Status:             status,
+		StatusChangedBy:    patientId,
+		StatusChangedAt:    time.Now().UTC().Format(domain.Layout),
+	}, false)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -13,52 +14,158 @@ import (
 
 type EventService interface {
 	ScheduleEvent(echo.Context, model.Event) (*models.Record, error)
-	GetEventsByHealthSpecialistId(echo.Context, string, string) ([]*models.Record, error)
-	GetEventsByPatientId(echo.Context, string, string) ([]*models.Record, error)
+	GetEventsByHealthSpecialistId(echo.Context, string, string, string) ([]*models.Record, error)
+	GetEventsByPatientId(echo.Context, string, string, string) ([]*models.Record, error)
 	RescheduleEvent(echo.Context, model.RescheduleRequest) (*models.Record, error)
 	GetEventById(echo.Context, string) (*models.Record, error)
+	GetAvailability(echo.Context, string) (*model.Availability, error)
+	SetAvailability(echo.Context, string, model.Availability) (*model.Availability, error)
+	GetAvailabilityExceptions(echo.Context, string) ([]*models.Record, error)
+	AddAvailabilityException(echo.Context, string, model.AvailabilityException) (*models.Record, error)
+	DeleteAvailabilityException(echo.Context, string, string) error
+	GetFreeSlots(echo.Context, model.AvailabilityQuery) ([]model.Slot, error)
+	BookEvent(echo.Context, string, model.Booking) (*models.Record, error)
+	TransitionEvent(echo.Context, string, string, string, model.StatusChange) (*models.Record, error)
 }
 
 type eventService struct {
-	eventRepository repository.EventRepository
+	eventRepository        repository.EventRepository
+	availabilityRepository repository.AvailabilityRepository
 }
 
-func NewEventService(eventRepo repository.EventRepository) EventService {
+func NewEventService(eventRepo repository.EventRepository, availabilityRepo repository.AvailabilityRepository) EventService {
 	return &eventService{
-		eventRepository: eventRepo,
+		eventRepository:        eventRepo,
+		availabilityRepository: availabilityRepo,
 	}
 }
 
+// ScheduleEvent schedules the event for its duration, the slot duration of its event type unless given.
+// It fails with a ConflictError if the health specialist or the patient has another event at that time,
+// unless the event explicitly allows the overlap.
 func (e *eventService) ScheduleEvent(ctx echo.Context, event model.Event) (*models.Record, error) {
-	return e.eventRepository.Add(ctx, event)
+	start, err := time.Parse(domain.Layout, event.EventDate)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error parsing the event date: %w", err)
+	}
+	duration, err := e.eventDuration(ctx, event.HealthSpecialistID, event.EventType, event.DurationMinutes)
+	if err != nil {
+		return nil, err
+	}
+	event.ID = ""
+	event.SeriesID = ""
+	event.RecurrenceID = ""
+	event.Status = domain.ConfirmedStatus
+	event.StatusChangedBy = event.HealthSpecialistID
+	event.StatusChangedAt = time.Now().UTC().Format(domain.Layout)
+	event.DurationMinutes = int(duration.Minutes())
+	event.EndDate = start.Add(duration).Format(domain.Layout)
+
+	allowOverlap := event.AllowOverlap
+	event.AllowOverlap = false
+	return e.eventRepository.Add(ctx, event, allowOverlap)
 }
 
-func (e *eventService) GetEventsByHealthSpecialistId(ctx echo.Context, healthSpecialistId string, after string) ([]*models.Record, error) {
-	return e.eventRepository.GetByHealthSpecialistId(ctx, healthSpecialistId, after)
+// GetEventsByHealthSpecialistId returns the events of the health specialist from after to before, with the
+// occurrences of their recurring events in that window.
+func (e *eventService) GetEventsByHealthSpecialistId(ctx echo.Context, healthSpecialistId string, after string, before string) ([]*models.Record, error) {
+	from, to, err := listWindow(after, before)
+	if err != nil {
+		return nil, err
+	}
+	records, err := e.eventRepository.GetByHealthSpecialistId(ctx, healthSpecialistId, after)
+	if err != nil {
+		return nil, err
+	}
+	return expandSeries(records, from, to, before == "")
 }
 
-func (e *eventService) GetEventsByPatientId(ctx echo.Context, patientId string, after string) ([]*models.Record, error) {
-	return e.eventRepository.GetByPatientId(ctx, patientId, after)
+// GetEventsByPatientId returns the events of the patient from after to before, with the occurrences of
+// their recurring events in that window.
+func (e *eventService) GetEventsByPatientId(ctx echo.Context, patientId string, after string, before string) ([]*models.Record, error) {
+	from, to, err := listWindow(after, before)
+	if err != nil {
+		return nil, err
+	}
+	records, err := e.eventRepository.GetByPatientId(ctx, patientId, after)
+	if err != nil {
+		return nil, err
+	}
+	return expandSeries(records, from, to, before == "")
 }
 
 func (e *eventService) GetEventById(ctx echo.Context, eventId string) (*models.Record, error) {
 	return e.eventRepository.GetById(ctx, eventId)
 }
 
+// RescheduleEvent moves the event to the new date as long as it is still to take place. For recurring events,
+// the new date is the one of the occurrence of recurrence_id and the occurrences of the scope move along with it.
 func (e *eventService) RescheduleEvent(ctx echo.Context, rescheduleRequest model.RescheduleRequest) (*models.Record, error) {
 	record, err := e.eventRepository.GetById(ctx, rescheduleRequest.EventID)
 	if err != nil {
 		return nil, err
 	}
+	if !domain.IsOpen(record.GetString("status")) {
+		return nil, errors.New("event_closed")
+	}
 	parsedTime, err := time.Parse(domain.Layout, rescheduleRequest.NewDate)
 	if err != nil {
 		return nil, fmt.Errorf("there was an error parsing the new date: %w", err)
 	}
-	isSame := record.GetDateTime("event_date").Time().Compare(parsedTime) == 0
+	durationMinutes := record.GetInt("duration_minutes")
+	if rescheduleRequest.DurationMinutes != 0 {
+		durationMinutes = rescheduleRequest.DurationMinutes
+	}
+	duration, err := e.eventDuration(ctx, record.GetString("health_specialist_id"), record.GetString("event_type"), durationMinutes)
+	if err != nil {
+		return nil, err
+	}
+	reference := record.GetDateTime("event_date").Time()
+	if record.GetString("rrule") != "" && rescheduleRequest.RecurrenceID != "" {
+		reference, err = time.Parse(domain.Layout, rescheduleRequest.RecurrenceID)
+		if err != nil {
+			return nil, errors.New("invalid_data: recurrence_id")
+		}
+	}
+	offset := parsedTime.Sub(reference)
+	isSame := offset == 0 && record.GetInt("duration_minutes") == int(duration.Minutes())
 	if isSame {
 		return record, nil
 	}
 
-	record.Set("event_date", rescheduleRequest.NewDate)
-	return e.eventRepository.Update(ctx, record)
+	if _, err := occurrenceStart(record, rescheduleRequest.OccurrenceScope); err != nil {
+		return nil, err
+	}
+	var rescheduled *models.Record
+	err = e.eventRepository.InTransaction(func(events repository.EventRepository) error {
+		target, err := resolveOccurrences(ctx, events, record, rescheduleRequest.OccurrenceScope)
+		if err != nil {
+			return err
+		}
+		start := target.GetDateTime("event_date").Time().Add(offset)
+		target.Set("event_date", start.Format(domain.Layout))
+		target.Set("duration_minutes", int(duration.Minutes()))
+		target.Set("end_date", start.Add(duration).Format(domain.Layout))
+		if target.GetString("rrule") != "" {
+			shiftExDates(target, offset)
+		}
+		rescheduled, err = events.Update(ctx, target, rescheduleRequest.AllowOverlap)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rescheduled, nil
+}
+
+// eventDuration returns the given duration, or the slot duration of the event type for the health specialist.
+func (e *eventService) eventDuration(ctx echo.Context, healthSpecialistId string, eventType string, minutes int) (time.Duration, error) {
+	if minutes > 0 {
+		return time.Duration(minutes) * time.Minute, nil
+	}
+	availability, err := e.GetAvailability(ctx, healthSpecialistId)
+	if err != nil {
+		return 0, err
+	}
+	return availability.SlotDuration(eventType), nil
 }
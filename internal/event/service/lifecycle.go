@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/domain"
+	"github.com/arosace/WellnessWaveApi/internal/event/model"
+	"github.com/arosace/WellnessWaveApi/internal/event/repository"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// TransitionEvent moves the event to the status on behalf of the account, recording the reason given.
+// Events can only be cancelled before they start, and completed or marked as a no-show once they started.
+// Cancelling a confirmed event within the cancellation notice of its health specialist flags it as a late
+// cancellation. The change applies to the occurrences of the scope for recurring events.
+func (e *eventService) TransitionEvent(ctx echo.Context, eventId string, status string, accountId string, change model.StatusChange) (*models.Record, error) {
+	event, err := e.eventRepository.GetById(ctx, eventId)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+	current := event.GetString("status")
+	if !domain.CanTransition(current, status) {
+		return nil, errors.New("invalid_transition")
+	}
+
+	// the checks apply to the first of the occurrences changed, before detaching them from their series
+	start, err := occurrenceStart(event, change.OccurrenceScope)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	late := false
+	switch status {
+	case domain.CancelledStatus:
+		if !start.After(now) {
+			return nil, errors.New("event_started")
+		}
+		availability, err := e.GetAvailability(ctx, event.GetString("health_specialist_id"))
+		if err != nil {
+			return nil, err
+		}
+		late = current == domain.ConfirmedStatus && availability.IsLateCancellation(start, now)
+	case domain.CompletedStatus, domain.NoShowStatus:
+		if start.After(now) {
+			return nil, errors.New("event_not_started")
+		}
+	}
+
+	changedAt, err := types.ParseDateTime(now)
+	if err != nil {
+		return nil, err
+	}
+	var changed *models.Record
+	err = e.eventRepository.InTransaction(func(events repository.EventRepository) error {
+		target, err := resolveOccurrences(ctx, events, event, change.OccurrenceScope)
+		if err != nil {
+			return err
+		}
+		if status == domain.CancelledStatus {
+			target.Set("late_cancellation", late)
+		}
+		target.Set("status", status)
+		target.Set("status_reason", change.Reason)
+		target.Set("status_changed_by", accountId)
+		target.Set("status_changed_at", changedAt.String())
+		// a change of status never takes up more time than the event already did
+		changed, err = events.Update(ctx, target, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/domain"
+	"github.com/arosace/WellnessWaveApi/internal/event/model"
+	"github.com/arosace/WellnessWaveApi/internal/event/repository"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// expandSeries replaces the recurring events by their occurrences starting from after to before, most recent
+// first. Occurrences keep the id of their series and carry their start as recurrence_id, to change them.
+// Events that do not recur are only bounded by before when keepUnbounded is not set.
+func expandSeries(records []*models.Record, after time.Time, before time.Time, keepUnbounded bool) ([]*models.Record, error) {
+	events := []*models.Record{}
+	for _, record := range records {
+		if record.GetString("rrule") == "" {
+			if keepUnbounded || record.GetDateTime("event_date").Time().Before(before) {
+				events = append(events, record)
+			}
+			continue
+		}
+
+		rule, err := utils.ParseRRule(record.GetString("rrule"))
+		if err != nil {
+			return nil, err
+		}
+		start := record.GetDateTime("event_date").Time()
+		duration := time.Duration(record.GetInt("duration_minutes")) * time.Minute
+		for _, occurrence := range rule.Between(start, after, before, exDates(record)) {
+			event := record.CleanCopy()
+			event.Set("event_date", occurrence.Format(domain.Layout))
+			event.Set("end_date", occurrence.Add(duration).Format(domain.Layout))
+			event.Set("recurrence_id", occurrence.Format(domain.Layout))
+			events = append(events, event)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].GetDateTime("event_date").Time().After(events[j].GetDateTime("event_date").Time())
+	})
+	return events, nil
+}
+
+// listWindow returns the window to expand recurring events in when listing the events from after to before,
+// before defaulting to the expansion window from after or now, whichever comes last.
+func listWindow(after string, before string) (time.Time, time.Time, error) {
+	var from, to time.Time
+	if after != "" {
+		parsed, err := types.ParseDateTime(after)
+		if err != nil {
+			return from, to, errors.New("invalid_data: after")
+		}
+		from = parsed.Time()
+	}
+	if before != "" {
+		parsed, err := types.ParseDateTime(before)
+		if err != nil {
+			return from, to, errors.New("invalid_data: before")
+		}
+		return from, parsed.Time(), nil
+	}
+
+	to = time.Now().UTC()
+	if from.After(to) {
+		to = from
+	}
+	return from, to.Add(domain.DefaultExpansionWindow), nil
+}
+
+// occurrenceStart returns the start of the occurrences the scope applies to: the occurrence for the scopes of
+// an occurrence, the start of the event otherwise. It fails if the event has no such occurrence.
+func occurrenceStart(event *models.Record, scope model.OccurrenceScope) (time.Time, error) {
+	start := event.GetDateTime("event_date").Time()
+	if event.GetString("rrule") == "" || scope.RecurrenceID == "" {
+		return start, nil
+	}
+
+	rule, err := utils.ParseRRule(event.GetString("rrule"))
+	if err != nil {
+		return start, err
+	}
+	occurrence, err := time.Parse(domain.Layout, scope.RecurrenceID)
+	if err != nil {
+		return start, errors.New("invalid_data: recurrence_id")
+	}
+	if !rule.Includes(start, occurrence) {
+		return start, errors.New("not_an_occurrence")
+	}
+	for _, exdate := range exDates(event) {
+		if exdate.Equal(occurrence) {
+			return start, errors.New("not_an_occurrence")
+		}
+	}
+	if scope.Scope == domain.WholeSeries {
+		return start, nil
+	}
+	return occurrence, nil
+}
+
+// resolveOccurrences returns the event to change for the scope: the occurrence detached from the series,
+// the series split at the occurrence, or the event itself. The repository should run in the transaction
+// saving the change, so a series is never left detached or split from a change that failed.
+func resolveOccurrences(ctx echo.Context, events repository.EventRepository, event *models.Record, scope model.OccurrenceScope) (*models.Record, error) {
+	if event.GetString("rrule") == "" || scope.RecurrenceID == "" || scope.Scope == domain.WholeSeries {
+		return event, nil
+	}
+	occurrence, err := occurrenceStart(event, scope)
+	if err != nil {
+		return nil, err
+	}
+	if scope.Scope == domain.FollowingOccurrences {
+		return events.SplitSeries(ctx, event, occurrence)
+	}
+	return detachOccurrence(ctx, events, event, occurrence)
+}
+
+// detachOccurrence turns the occurrence into an event of its own, excluded from the series it replaces.
+func detachOccurrence(ctx echo.Context, events repository.EventRepository, series *models.Record, occurrence time.Time) (*models.Record, error) {
+	event := model.OccurrenceOf(series, occurrence)
+	event.RecurrenceID = occurrence.Format(domain.Layout)
+	// the occurrence already took up this time in the series
+	record, err := events.Add(ctx, event, true)
+	if err != nil {
+		return nil, err
+	}
+
+	exdates := append(exDateStrings(series), occurrence.Format(domain.Layout))
+	series.Set("exdates", exdates)
+	if _, err := events.Update(ctx, series, true); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// shiftExDates moves the excluded dates of the series along with its start.
+func shiftExDates(series *models.Record, offset time.Duration) {
+	exdates := exDates(series)
+	if len(exdates) == 0 {
+		return
+	}
+	shifted := make([]string, 0, len(exdates))
+	for _, exdate := range exdates {
+		shifted = append(shifted, exdate.Add(offset).Format(domain.Layout))
+	}
+	series.Set("exdates", shifted)
+}
+
+func exDateStrings(event *models.Record) []string {
+	exdates := []string{}
+	event.UnmarshalJSONField("exdates", &exdates)
+	return exdates
+}
+
+func exDates(event *models.Record) []time.Time {
+	exdates := []time.Time{}
+	for _, exdate := range exDateStrings(event) {
+		if parsed, err := time.Parse(domain.Layout, exdate); err == nil {
+			exdates = append(exdates, parsed)
+		}
+	}
+	return exdates
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/arosace/WellnessWaveApi/internal/event/domain"
+	"github.com/arosace/WellnessWaveApi/internal/event/model"
+	"github.com/arosace/WellnessWaveApi/internal/event/repository"
+	"github.com/arosace/WellnessWaveApi/pkg/testutils"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestService(t *testing.T) (*eventService, *daos.Dao) {
+	app := testutils.NewTestApp(t)
+	testutils.NewCollection(t, app.Dao(), domain.TABLENAME, testutils.EventFields()...)
+	events := repository.NewEventRepository(app.Dao(), testutils.NewEncryptor(t))
+	return &eventService{eventRepository: events}, app.Dao()
+}
+
+func TestChangingOccurrencesIntoConflictsKeepsTheSeries(t *testing.T) {
+	service, dao := newTestService(t)
+
+	event := func(patientId string, start string, end string, rrule string) *models.Record {
+		record, err := service.eventRepository.Add(nil, model.Event{
+			HealthSpecialistID: "specialist",
+			PatientID:          patientId,
+			EventType:          "consultation",
+			EventDate:          start,
+			DurationMinutes:    60,
+			EndDate:            end,
+			Status:             domain.ConfirmedStatus,
+			RRule:              rrule,
+		}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return record
+	}
+	// every Monday from 4 March 2030, and Tuesday 12 March
+	series := event("patient", "2030-03-04 09:00:00", "2030-03-04 10:00:00", "FREQ=WEEKLY")
+	event("other", "2030-03-12 09:00:00", "2030-03-12 10:00:00", "")
+
+	for _, scope := range []string{domain.ThisOccurrence, domain.FollowingOccurrences} {
+		_, err := service.RescheduleEvent(nil, model.RescheduleRequest{
+			EventID:         series.Id,
+			NewDate:         "2030-03-12 09:30:00",
+			DurationMinutes: 60,
+			OccurrenceScope: model.OccurrenceScope{RecurrenceID: "2030-03-11 09:00:00", Scope: scope},
+		})
+		var conflictErr *model.ConflictError
+		assert.True(t, errors.As(err, &conflictErr), scope)
+
+		records, err := dao.FindRecordsByFilter(domain.TABLENAME, "id != ''", "", -1, 0)
+		assert.Nil(t, err)
+		assert.Len(t, records, 2, "no occurrence is left detached or split from the series")
+		stored, err := dao.FindRecordById(domain.TABLENAME, series.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, "FREQ=WEEKLY", stored.GetString("rrule"))
+		assert.Empty(t, stored.GetString("exdates"))
+	}
+
+	rescheduled, err := service.RescheduleEvent(nil, model.RescheduleRequest{
+		EventID:         series.Id,
+		NewDate:         "2030-03-13 09:00:00",
+		DurationMinutes: 60,
+		OccurrenceScope: model.OccurrenceScope{RecurrenceID: "2030-03-11 09:00:00", Scope: domain.ThisOccurrence},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, series.Id, rescheduled.GetString("series_id"))
+	stored, err := dao.FindRecordById(domain.TABLENAME, series.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2030-03-11 09:00:00"}, exDateStrings(stored))
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+const (
+	// TableName stores the clinics health specialists practise in, the tenants of the api.
+	TableName = "organisations"
+	// InvitationsTableName stores the invitations of health specialists to join an organisation.
+	InvitationsTableName = "organisation_invitations"
+
+	// Roles of the health specialists in their organisation, admins manage the organisation and see
+	// the schedule of every member.
+	AdminRole  = "admin"
+	MemberRole = "member"
+
+	PendingStatus   = "pending"
+	AcceptedStatus  = "accepted"
+	DeclinedStatus  = "declined"
+	CancelledStatus = "cancelled"
+
+	// InvitationDuration is how long the health specialist has to accept an invitation.
+	InvitationDuration = 7 * 24 * time.Hour
+)
+
+func IsOrganisationRole(role string) bool {
+	return role == AdminRole || role == MemberRole
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/arosace/WellnessWaveApi/internal/organisation/model"
+	"github.com/arosace/WellnessWaveApi/internal/organisation/service"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+// OrganisationHandler handles HTTP requests to manage organisations and their members.
+type OrganisationHandler struct {
+	organisationService service.OrganisationService
+}
+
+func NewOrganisationHandler(organisationService service.OrganisationService) *OrganisationHandler {
+	return &OrganisationHandler{organisationService: organisationService}
+}
+
+func (h *OrganisationHandler) HandleCreateOrganisation(ctx echo.Context) error {
+	res := model.OrganisationResponse{}
+	var body model.Organisation
+
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("wrong_data_type", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	organisation, err := h.organisationService.CreateOrganisation(ctx, utils.GetAuthAccountId(ctx), body)
+	if err != nil {
+		return organisationError(err, "Failed to create organisation")
+	}
+
+	res.Data = organisation
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *OrganisationHandler) HandleGetOrganisation(ctx echo.Context) error {
+	res := model.OrganisationResponse{}
+
+	organisation, err := h.organisationService.GetOrganisation(ctx, ctx.PathParam("id"))
+	if err != nil {
+		return organisationError(err, "Failed to get organisation")
+	}
+
+	res.Data = organisation
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *OrganisationHandler) HandleUpdateOrganisation(ctx echo.Context) error {
+	res := model.OrganisationResponse{}
+	var body model.Organisation
+
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("wrong_data_type", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	organisation, err := h.organisationService.UpdateOrganisation(ctx, ctx.PathParam("id"), body)
+	if err != nil {
+		return organisationError(err, "Failed to update organisation")
+	}
+
+	res.Data = organisation
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *OrganisationHandler) HandleGetMembers(ctx echo.Context) error {
+	res := model.OrganisationResponse{}
+
+	members, err := h.organisationService.GetMembers(ctx, ctx.PathParam("id"))
+	if err != nil {
+		return organisationError(err, "Failed to get organisation members")
+	}
+
+	res.Data = members
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *OrganisationHandler) HandleInvite(ctx echo.Context) error {
+	res := model.OrganisationResponse{}
+	var body model.InvitationBody
+
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("wrong_data_type", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	invitation, err := h.organisationService.Invite(ctx, ctx.PathParam("id"), utils.GetAuthAccountId(ctx), body)
+	if err != nil {
+		return organisationError(err, "Failed to invite health specialist")
+	}
+
+	res.Data = invitation
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *OrganisationHandler) HandleGetPendingInvitations(ctx echo.Context) error {
+	res := model.OrganisationResponse{}
+
+	invitations, err := h.organisationService.GetPendingInvitations(ctx, ctx.PathParam("id"))
+	if err != nil {
+		res.Error = fmt.Sprintf("Failed to get pending organisation invitations: %v", err)
+		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+	}
+
+	res.Data = invitations
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *OrganisationHandler) HandleAcceptInvitation(ctx echo.Context) error {
+	res := model.OrganisationResponse{}
+
+	invitation, err := h.organisationService.AcceptInvitation(ctx, ctx.PathParam("id"), ctx.PathParam("invitationId"))
+	if err != nil {
+		return organisationError(err, "Failed to accept organisation invitation")
+	}
+
+	res.Data = invitation
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *OrganisationHandler) HandleDeclineInvitation(ctx echo.Context) error {
+	res := model.OrganisationResponse{}
+
+	invitation, err := h.organisationService.DeclineInvitation(ctx, ctx.PathParam("id"), ctx.PathParam("invitationId"))
+	if err != nil {
+		return organisationError(err, "Failed to decline organisation invitation")
+	}
+
+	res.Data = invitation
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// organisationError maps the errors of the organisation service to their status code.
+func organisationError(err error, message string) error {
+	switch err.Error() {
+	case "not_found":
+		return apis.NewNotFoundError(err.Error(), nil)
+	case "invalid_specialist":
+		return apis.NewBadRequestError(err.Error(), nil)
+	case "already_in_organisation", "invitation_not_pending", "shared_care_teams":
+		return apis.NewApiError(http.StatusConflict, err.Error(), nil)
+	case "invitation_expired":
+		return apis.NewApiError(http.StatusGone, err.Error(), nil)
+	}
+	return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("%s: %v", message, err), nil)
+}
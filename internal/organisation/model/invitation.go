@@ -0,0 +1,44 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/arosace/WellnessWaveApi/internal/organisation/domain"
+)
+
+// Invitation asks a health specialist to join an organisation, which only happens once they accept it.
+type Invitation struct {
+	ID             string `json:"id,omitempty"`
+	OrganisationID string `json:"organisation_id"`
+	SpecialistID   string `json:"specialist_id"`
+	InvitedBy      string `json:"invited_by"`
+	Role           string `json:"role"`
+	Status         string `json:"status"`
+	ExpiresAt      string `json:"expires_at"`
+	DecidedAt      string `json:"decided_at"`
+}
+
+type InvitationBody struct {
+	SpecialistID string `json:"specialist_id"`
+	Role         string `json:"role"`
+}
+
+func (m *InvitationBody) ValidateModel() error {
+	var missingData []string
+
+	if m.SpecialistID == "" {
+		missingData = append(missingData, "specialist_id")
+	}
+
+	if len(missingData) > 0 {
+		return fmt.Errorf("missing_data: %s", strings.Join(missingData, ", "))
+	}
+
+	if m.Role != "" && !domain.IsOrganisationRole(m.Role) {
+		return errors.New("invalid_organisation_role")
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+package model
+
+// Member is a health specialist of an organisation, as listed to the other members.
+type Member struct {
+	ID               string `json:"id"`
+	Username         string `json:"username"`
+	Email            string `json:"email"`
+	OrganisationRole string `json:"organisation_role"`
+}
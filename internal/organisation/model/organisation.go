@@ -0,0 +1,28 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Organisation is a clinic, it owns the health specialists practising in it and the data they manage.
+type Organisation struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+	// RequireTwoFactor makes two factor authentication mandatory for the members of the organisation.
+	RequireTwoFactor bool `json:"require_two_factor"`
+}
+
+func (m *Organisation) ValidateModel() error {
+	var missingData []string
+
+	if strings.TrimSpace(m.Name) == "" {
+		missingData = append(missingData, "name")
+	}
+
+	if len(missingData) > 0 {
+		return fmt.Errorf("missing_data: %s", strings.Join(missingData, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/arosace/WellnessWaveApi/internal/organisation/domain"
+	"github.com/arosace/WellnessWaveApi/internal/organisation/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// InvitationRepository defines the interface for organisation invitation data access.
+type InvitationRepository interface {
+	Add(echo.Context, model.Invitation) (*models.Record, error)
+	FindByID(echo.Context, string) (*models.Record, error)
+	FindPendingBySpecialistId(echo.Context, string) ([]*models.Record, error)
+	Update(echo.Context, *models.Record) (*models.Record, error)
+	CancelPending(echo.Context, string, string) error
+}
+
+type InvitationRepo struct {
+	Dao *daos.Dao
+}
+
+func NewInvitationRepository(dao *daos.Dao) *InvitationRepo {
+	return &InvitationRepo{Dao: dao}
+}
+
+func (r *InvitationRepo) Add(ctx echo.Context, invitation model.Invitation) (*models.Record, error) {
+	collection, err := r.Dao.FindCollectionByNameOrId(domain.InvitationsTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.NewRecord(collection)
+	utils.LoadFromStruct(record, &invitation)
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("Failed to save organisation invitation: %w", err)
+	}
+
+	return record, nil
+}
+
+func (r *InvitationRepo) FindByID(ctx echo.Context, id string) (*models.Record, error) {
+	record, err := r.Dao.FindRecordById(domain.InvitationsTableName, id)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving organisation invitation [%s]: %w", id, err)
+	}
+	return record, nil
+}
+
+func (r *InvitationRepo) FindPendingBySpecialistId(ctx echo.Context, specialistId string) ([]*models.Record, error) {
+	records, err := r.Dao.FindRecordsByFilter(
+		domain.InvitationsTableName,
+		"specialist_id = {:specialist_id} && status = {:status}",
+		"-created",
+		-1,
+		0,
+		dbx.Params{"specialist_id": specialistId, "status": domain.PendingStatus},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving pending organisation invitations of [%s]: %w", specialistId, err)
+	}
+	return records, nil
+}
+
+func (r *InvitationRepo) Update(ctx echo.Context, record *models.Record) (*models.Record, error) {
+	record.MarkAsNotNew()
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("there was an error updating organisation invitation: %w", err)
+	}
+	return record, nil
+}
+
+// CancelPending cancels the invitations of the organisation to the health specialist still waiting for an answer.
+func (r *InvitationRepo) CancelPending(ctx echo.Context, organisationId string, specialistId string) error {
+	_, err := r.Dao.DB().Update(
+		domain.InvitationsTableName,
+		dbx.Params{"status": domain.CancelledStatus},
+		dbx.HashExp{"organisation_id": organisationId, "specialist_id": specialistId, "status": domain.PendingStatus},
+	).Execute()
+	if err != nil {
+		return fmt.Errorf("there was an error cancelling pending invitations of [%s]: %w", specialistId, err)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	eventDomain "github.com/arosace/WellnessWaveApi/internal/event/domain"
+	"github.com/arosace/WellnessWaveApi/internal/organisation/domain"
+	"github.com/arosace/WellnessWaveApi/internal/organisation/model"
+	plannerDomain "github.com/arosace/WellnessWaveApi/internal/planner/domain"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// OrganisationRepository defines the interface for organisation data access.
+type OrganisationRepository interface {
+	Add(echo.Context, model.Organisation) (*models.Record, error)
+	FindByID(echo.Context, string) (*models.Record, error)
+	Update(echo.Context, *models.Record) (*models.Record, error)
+	Remove(echo.Context, *models.Record) error
+	FindMembers(echo.Context, string) ([]*models.Record, error)
+	FindSpecialist(echo.Context, string) (*models.Record, error)
+	Join(echo.Context, string, string, string) error
+	AddPatient(echo.Context, string, string) error
+}
+
+type OrganisationRepo struct {
+	Dao *daos.Dao
+}
+
+func NewOrganisationRepository(dao *daos.Dao) *OrganisationRepo {
+	return &OrganisationRepo{Dao: dao}
+}
+
+func (r *OrganisationRepo) Add(ctx echo.Context, organisation model.Organisation) (*models.Record, error) {
+	collection, err := r.Dao.FindCollectionByNameOrId(domain.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.NewRecord(collection)
+	utils.LoadFromStruct(record, &organisation)
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("Failed to save organisation: %w", err)
+	}
+
+	return record, nil
+}
+
+// FindByID returns the organisation, which is only visible from within itself.
+func (r *OrganisationRepo) FindByID(ctx echo.Context, id string) (*models.Record, error) {
+	record, err := r.Dao.FindRecordById(domain.TableName, id)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving organisation [%s]: %w", id, err)
+	}
+	if organisationId, scoped := utils.TenantScope(ctx); scoped && organisationId != record.Id {
+		return nil, fmt.Errorf("organisation [%s] is out of tenant: %w", id, sql.ErrNoRows)
+	}
+	return record, nil
+}
+
+func (r *OrganisationRepo) Update(ctx echo.Context, record *models.Record) (*models.Record, error) {
+	record.MarkAsNotNew()
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("there was an error updating organisation: %w", err)
+	}
+	return record, nil
+}
+
+func (r *OrganisationRepo) Remove(ctx echo.Context, record *models.Record) error {
+	if err := r.Dao.DeleteRecord(record); err != nil {
+		return fmt.Errorf("there was an error removing organisation: %w", err)
+	}
+	return nil
+}
+
+// FindMembers returns the health specialists of the organisation.
+func (r *OrganisationRepo) FindMembers(ctx echo.Context, organisationId string) ([]*models.Record, error) {
+	params := dbx.Params{"organisation_id": organisationId, "role": accountDomain.HealthSpecialistRole}
+	records, err := r.Dao.FindRecordsByFilter(
+		accountDomain.TableName,
+		utils.TenantFilter(ctx, "organisation_id = {:organisation_id} && role = {:role} && deleted_at = ''", params, "id"),
+		"username",
+		-1,
+		0,
+		params,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving the members of organisation [%s]: %w", organisationId, err)
+	}
+	return records, nil
+}
+
+// FindSpecialist returns the health specialist account, whichever tenant it belongs to, so that
+// specialists outside of any organisation can be invited.
+func (r *OrganisationRepo) FindSpecialist(ctx echo.Context, id string) (*models.Record, error) {
+	record, err := r.Dao.FindFirstRecordByFilter(
+		accountDomain.TableName,
+		"id = {:id} && role = {:role} && deleted_at = ''",
+		dbx.Params{"id": id, "role": accountDomain.HealthSpecialistRole},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving health specialist [%s]: %w", id, err)
+	}
+	return record, nil
+}
+
+// Join makes the health specialist a member of the organisation with the given role. Everything the
+// specialist manages moves into the organisation with them, in a single transaction: their events,
+// meals, exercises and plans, and the patients of their care teams that are not part of any organisation
+// yet, together with the events and plans of those patients. It fails with shared_care_teams while one of
+// those patients is also followed by a specialist outside of the organisation, who would lose them.
+func (r *OrganisationRepo) Join(ctx echo.Context, organisationId string, specialistId string, role string) error {
+	return r.Dao.RunInTransaction(func(txDao *daos.Dao) error {
+		if _, err := txDao.FindRecordById(domain.TableName, organisationId); err != nil {
+			return fmt.Errorf("there was an error retrieving organisation [%s]: %w", organisationId, err)
+		}
+
+		result, err := txDao.DB().Update(
+			accountDomain.TableName,
+			dbx.Params{"organisation_id": organisationId, "organisation_role": role},
+			dbx.HashExp{"id": specialistId, "organisation_id": ""},
+		).Execute()
+		if err != nil {
+			return fmt.Errorf("there was an error adding health specialist [%s] to organisation [%s]: %w", specialistId, organisationId, err)
+		}
+		// a health specialist belongs to a single organisation
+		if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+			return errors.New("already_in_organisation")
+		}
+
+		var patientIds []string
+		err = txDao.DB().
+			Select("patient.id").
+			From(accountDomain.TableName+" patient").
+			InnerJoin(accountDomain.CareTeamTableName+" member", dbx.NewExp("member.patient_id = patient.id")).
+			Where(dbx.HashExp{"member.specialist_id": specialistId, "patient.organisation_id": ""}).
+			Column(&patientIds)
+		if err != nil {
+			return fmt.Errorf("there was an error fetching the patients of health specialist [%s]: %w", specialistId, err)
+		}
+		patients := make([]interface{}, len(patientIds))
+		for i, id := range patientIds {
+			patients[i] = id
+		}
+
+		if len(patients) > 0 {
+			var shared int
+			err := txDao.DB().
+				Select("count(*)").
+				From(accountDomain.CareTeamTableName+" member").
+				InnerJoin(accountDomain.TableName+" specialist", dbx.NewExp("specialist.id = member.specialist_id")).
+				Where(dbx.In("member.patient_id", patients...)).
+				AndWhere(dbx.Not(dbx.HashExp{"specialist.organisation_id": organisationId})).
+				Row(&shared)
+			if err != nil {
+				return fmt.Errorf("there was an error checking the care teams of health specialist [%s]: %w", specialistId, err)
+			}
+			if shared > 0 {
+				return errors.New("shared_care_teams")
+			}
+		}
+
+		if len(patients) > 0 {
+			if err := moveToOrganisation(txDao, accountDomain.TableName, organisationId, dbx.In("id", patients...)); err != nil {
+				return err
+			}
+		}
+
+		owned := dbx.HashExp{"health_specialist_id": specialistId}
+		for _, table := range []string{
+			plannerDomain.MEALS_TABLENAME,
+			plannerDomain.EXERCISE_TABLENAME,
+			eventDomain.AvailabilityTableName,
+			eventDomain.AvailabilityExceptionsTableName,
+		} {
+			if err := moveToOrganisation(txDao, table, organisationId, owned); err != nil {
+				return err
+			}
+		}
+
+		where := dbx.Expression(owned)
+		if len(patients) > 0 {
+			where = dbx.Or(owned, dbx.In("patient_id", patients...))
+		}
+		return moveEventsAndPlans(txDao, organisationId, where)
+	})
+}
+
+// AddPatient makes the patient, who is not part of any organisation, part of the organisation together
+// with their events and plans, in a single transaction, so that they keep seeing their history. It fails
+// with already_in_organisation when the patient is part of an organisation already.
+func (r *OrganisationRepo) AddPatient(ctx echo.Context, organisationId string, patientId string) error {
+	return r.Dao.RunInTransaction(func(txDao *daos.Dao) error {
+		result, err := txDao.DB().Update(
+			accountDomain.TableName,
+			dbx.Params{"organisation_id": organisationId},
+			dbx.HashExp{"id": patientId, "role": accountDomain.PatientRole, "organisation_id": ""},
+		).Execute()
+		if err != nil {
+			return fmt.Errorf("there was an error adding patient [%s] to organisation [%s]: %w", patientId, organisationId, err)
+		}
+		if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+			return errors.New("already_in_organisation")
+		}
+
+		return moveEventsAndPlans(txDao, organisationId, dbx.HashExp{"patient_id": patientId})
+	})
+}
+
+// moveEventsAndPlans assigns the events, meal plans and exercise plans matching where, and the daily
+// plans of those plans, that are not part of any organisation, to the organisation.
+func moveEventsAndPlans(dao *daos.Dao, organisationId string, where dbx.Expression) error {
+	tables := []struct {
+		name       string
+		dailyPlans string
+	}{
+		{eventDomain.TABLENAME, ""},
+		{plannerDomain.PLANS_TABLENAME, plannerDomain.DAILY_PLANS_TABLENAME},
+		{plannerDomain.EXERCISE_PLAN_TABLENAME, plannerDomain.DAILY_EXERCISE_PLANS_TABLENAME},
+	}
+	for _, table := range tables {
+		if err := moveToOrganisation(dao, table.name, organisationId, where); err != nil {
+			return err
+		}
+		if table.dailyPlans == "" {
+			continue
+		}
+		plans := dbx.NewExp(
+			"plan_id IN (SELECT id FROM "+table.name+" WHERE organisation_id = {:organisation_id})",
+			dbx.Params{"organisation_id": organisationId},
+		)
+		if err := moveToOrganisation(dao, table.dailyPlans, organisationId, plans); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveToOrganisation assigns the records of the table matching where, that are not part of any
+// organisation, to the organisation.
+func moveToOrganisation(dao *daos.Dao, table string, organisationId string, where dbx.Expression) error {
+	_, err := dao.DB().Update(
+		table,
+		dbx.Params{"organisation_id": organisationId},
+		dbx.And(where, dbx.HashExp{"organisation_id": ""}),
+	).Execute()
+	if err != nil {
+		return fmt.Errorf("there was an error moving %s to organisation [%s]: %w", table, organisationId, err)
+	}
+	return nil
+}
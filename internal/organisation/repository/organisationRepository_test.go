@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"testing"
+
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	eventDomain "github.com/arosace/WellnessWaveApi/internal/event/domain"
+	"github.com/arosace/WellnessWaveApi/internal/organisation/domain"
+	plannerDomain "github.com/arosace/WellnessWaveApi/internal/planner/domain"
+	"github.com/arosace/WellnessWaveApi/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinWithSharedCareTeams(t *testing.T) {
+	dao := testutils.NewTestApp(t).Dao()
+
+	organisations := testutils.NewCollection(t, dao, domain.TableName, testutils.TextFields("name")...)
+	accounts := testutils.NewCollection(t, dao, accountDomain.TableName, testutils.TextFields("role", "organisation_id", "organisation_role")...)
+	careTeams := testutils.NewCollection(t, dao, accountDomain.CareTeamTableName, testutils.TextFields("patient_id", "specialist_id")...)
+	for _, table := range []string{plannerDomain.MEALS_TABLENAME, plannerDomain.EXERCISE_TABLENAME, eventDomain.AvailabilityTableName, eventDomain.AvailabilityExceptionsTableName} {
+		testutils.NewCollection(t, dao, table, testutils.TextFields("health_specialist_id", "organisation_id")...)
+	}
+	events := testutils.NewCollection(t, dao, eventDomain.TABLENAME, testutils.TextFields("health_specialist_id", "patient_id", "organisation_id")...)
+	for _, table := range []string{plannerDomain.PLANS_TABLENAME, plannerDomain.EXERCISE_PLAN_TABLENAME} {
+		testutils.NewCollection(t, dao, table, testutils.TextFields("health_specialist_id", "patient_id", "organisation_id")...)
+	}
+	for _, table := range []string{plannerDomain.DAILY_PLANS_TABLENAME, plannerDomain.DAILY_EXERCISE_PLANS_TABLENAME} {
+		testutils.NewCollection(t, dao, table, testutils.TextFields("plan_id", "organisation_id")...)
+	}
+
+	organisation := testutils.NewRecord(t, dao, organisations, map[string]any{"name": "Clinic"})
+	specialist := testutils.NewRecord(t, dao, accounts, map[string]any{"role": accountDomain.HealthSpecialistRole})
+	colleague := testutils.NewRecord(t, dao, accounts, map[string]any{"role": accountDomain.HealthSpecialistRole})
+	patient := testutils.NewRecord(t, dao, accounts, map[string]any{"role": accountDomain.PatientRole})
+	testutils.NewRecord(t, dao, careTeams, map[string]any{"patient_id": patient.Id, "specialist_id": specialist.Id})
+	shared := testutils.NewRecord(t, dao, careTeams, map[string]any{"patient_id": patient.Id, "specialist_id": colleague.Id})
+	event := testutils.NewRecord(t, dao, events, map[string]any{"health_specialist_id": specialist.Id, "patient_id": patient.Id})
+
+	organisationOf := func(collection string, id string) string {
+		record, err := dao.FindRecordById(collection, id)
+		assert.Nil(t, err)
+		return record.GetString("organisation_id")
+	}
+
+	repository := NewOrganisationRepository(dao)
+	err := repository.Join(nil, organisation.Id, specialist.Id, domain.AdminRole)
+	assert.EqualError(t, err, "shared_care_teams", "the colleague outside of the organisation would lose the patient")
+	assert.Equal(t, "", organisationOf(accountDomain.TableName, specialist.Id), "nothing moves when the join fails")
+	assert.Equal(t, "", organisationOf(accountDomain.TableName, patient.Id))
+	assert.Equal(t, "", organisationOf(eventDomain.TABLENAME, event.Id))
+
+	assert.Nil(t, dao.DeleteRecord(shared))
+	assert.Nil(t, repository.Join(nil, organisation.Id, specialist.Id, domain.AdminRole))
+	assert.Equal(t, organisation.Id, organisationOf(accountDomain.TableName, specialist.Id))
+	assert.Equal(t, organisation.Id, organisationOf(accountDomain.TableName, patient.Id))
+	assert.Equal(t, organisation.Id, organisationOf(eventDomain.TABLENAME, event.Id))
+	assert.Equal(t, "", organisationOf(accountDomain.TableName, colleague.Id))
+}
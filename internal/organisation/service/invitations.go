@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/organisation/domain"
+	"github.com/arosace/WellnessWaveApi/internal/organisation/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Invite asks a health specialist outside of any organisation to join this one. A new invitation
+// replaces the one still pending, if any.
+func (s *organisationService) Invite(ctx echo.Context, organisationId string, invitedBy string, body model.InvitationBody) (*models.Record, error) {
+	organisation, err := s.GetOrganisation(ctx, organisationId)
+	if err != nil {
+		return nil, err
+	}
+	specialist, err := s.findSpecialist(ctx, body.SpecialistID)
+	if err != nil {
+		return nil, err
+	}
+	if specialist.GetString("organisation_id") != "" {
+		return nil, errors.New("already_in_organisation")
+	}
+
+	role := body.Role
+	if role == "" {
+		role = domain.MemberRole
+	}
+	expiresAt, err := types.ParseDateTime(time.Now().Add(domain.InvitationDuration))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.invitationRepository.CancelPending(ctx, organisationId, specialist.Id); err != nil {
+		return nil, err
+	}
+	invitation, err := s.invitationRepository.Add(ctx, model.Invitation{
+		OrganisationID: organisationId,
+		SpecialistID:   specialist.Id,
+		InvitedBy:      invitedBy,
+		Role:           role,
+		Status:         domain.PendingStatus,
+		ExpiresAt:      expiresAt.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := utils.SendOrganisationInvitationEmail(s.mailer, specialist.GetString("username"), specialist.Email(), organisation.GetString("name"), invitation.Id); err != nil {
+		return nil, errors.New("Failed to send email: " + err.Error())
+	}
+	return invitation, nil
+}
+
+// GetPendingInvitations returns the invitations still waiting for an answer of the health specialist.
+func (s *organisationService) GetPendingInvitations(ctx echo.Context, specialistId string) ([]*models.Record, error) {
+	invitations, err := s.invitationRepository.FindPendingBySpecialistId(ctx, specialistId)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []*models.Record{}
+	for _, invitation := range invitations {
+		if !isExpired(invitation) {
+			pending = append(pending, invitation)
+		}
+	}
+	return pending, nil
+}
+
+// AcceptInvitation makes the health specialist a member of the organisation, which then owns the data
+// they manage.
+func (s *organisationService) AcceptInvitation(ctx echo.Context, specialistId string, invitationId string) (*models.Record, error) {
+	invitation, err := s.findPendingInvitation(ctx, specialistId, invitationId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.organisationRepository.Join(ctx, invitation.GetString("organisation_id"), specialistId, invitation.GetString("role")); err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+
+	if err := s.decide(ctx, invitation, domain.AcceptedStatus); err != nil {
+		log.Printf("Failed to record the acceptance of organisation invitation %s: %v", invitation.Id, err)
+	}
+	return invitation, nil
+}
+
+func (s *organisationService) DeclineInvitation(ctx echo.Context, specialistId string, invitationId string) (*models.Record, error) {
+	invitation, err := s.findPendingInvitation(ctx, specialistId, invitationId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decide(ctx, invitation, domain.DeclinedStatus); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (s *organisationService) decide(ctx echo.Context, invitation *models.Record, status string) error {
+	now, err := types.ParseDateTime(time.Now())
+	if err != nil {
+		return err
+	}
+	invitation.Set("status", status)
+	invitation.Set("decided_at", now.String())
+	_, err = s.invitationRepository.Update(ctx, invitation)
+	return err
+}
+
+// findPendingInvitation returns the invitation of the health specialist if it still waits for their answer.
+func (s *organisationService) findPendingInvitation(ctx echo.Context, specialistId string, invitationId string) (*models.Record, error) {
+	invitation, err := s.invitationRepository.FindByID(ctx, invitationId)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+	if invitation.GetString("specialist_id") != specialistId {
+		return nil, errors.New("not_found")
+	}
+	if invitation.GetString("status") != domain.PendingStatus {
+		return nil, errors.New("invitation_not_pending")
+	}
+	if isExpired(invitation) {
+		return nil, errors.New("invitation_expired")
+	}
+	return invitation, nil
+}
+
+func isExpired(invitation *models.Record) bool {
+	return invitation.GetDateTime("expires_at").Time().Before(time.Now())
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/arosace/WellnessWaveApi/internal/organisation/domain"
+	"github.com/arosace/WellnessWaveApi/internal/organisation/model"
+	"github.com/arosace/WellnessWaveApi/internal/organisation/repository"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+)
+
+// OrganisationService manages the organisations health specialists practise in and their members.
+type OrganisationService interface {
+	CreateOrganisation(ctx echo.Context, specialistId string, organisation model.Organisation) (*models.Record, error)
+	GetOrganisation(ctx echo.Context, id string) (*models.Record, error)
+	UpdateOrganisation(ctx echo.Context, id string, organisation model.Organisation) (*models.Record, error)
+	GetMembers(ctx echo.Context, id string) ([]model.Member, error)
+	Invite(ctx echo.Context, organisationId string, invitedBy string, body model.InvitationBody) (*models.Record, error)
+	GetPendingInvitations(ctx echo.Context, specialistId string) ([]*models.Record, error)
+	AcceptInvitation(ctx echo.Context, specialistId string, invitationId string) (*models.Record, error)
+	DeclineInvitation(ctx echo.Context, specialistId string, invitationId string) (*models.Record, error)
+}
+
+type organisationService struct {
+	organisationRepository repository.OrganisationRepository
+	invitationRepository   repository.InvitationRepository
+	mailer                 mailer.Mailer
+}
+
+func NewOrganisationService(
+	organisationRepo repository.OrganisationRepository,
+	invitationRepo repository.InvitationRepository,
+	mailClient mailer.Mailer,
+) OrganisationService {
+	return &organisationService{
+		organisationRepository: organisationRepo,
+		invitationRepository:   invitationRepo,
+		mailer:                 mailClient,
+	}
+}
+
+// CreateOrganisation creates the organisation with the health specialist as its first admin,
+// moving the data they manage into it.
+func (s *organisationService) CreateOrganisation(ctx echo.Context, specialistId string, organisation model.Organisation) (*models.Record, error) {
+	specialist, err := s.findSpecialist(ctx, specialistId)
+	if err != nil {
+		return nil, err
+	}
+	if specialist.GetString("organisation_id") != "" {
+		return nil, errors.New("already_in_organisation")
+	}
+
+	organisation.ID = ""
+	organisation.Name = strings.TrimSpace(organisation.Name)
+	record, err := s.organisationRepository.Add(ctx, organisation)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.organisationRepository.Join(ctx, record.Id, specialistId, domain.AdminRole); err != nil {
+		if err := s.organisationRepository.Remove(ctx, record); err != nil {
+			log.Printf("Failed to remove organisation %s its admin could not join: %v", record.Id, err)
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+func (s *organisationService) GetOrganisation(ctx echo.Context, id string) (*models.Record, error) {
+	organisation, err := s.organisationRepository.FindByID(ctx, id)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+	return organisation, nil
+}
+
+func (s *organisationService) UpdateOrganisation(ctx echo.Context, id string, organisation model.Organisation) (*models.Record, error) {
+	record, err := s.GetOrganisation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	record.Set("name", strings.TrimSpace(organisation.Name))
+	record.Set("require_two_factor", organisation.RequireTwoFactor)
+	return s.organisationRepository.Update(ctx, record)
+}
+
+// GetMembers lists the health specialists of the organisation, without any of their account details
+// but the ones colleagues need to reach them.
+func (s *organisationService) GetMembers(ctx echo.Context, id string) ([]model.Member, error) {
+	if _, err := s.GetOrganisation(ctx, id); err != nil {
+		return nil, err
+	}
+	records, err := s.organisationRepository.FindMembers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]model.Member, 0, len(records))
+	for _, record := range records {
+		members = append(members, model.Member{
+			ID:               record.Id,
+			Username:         record.GetString("username"),
+			Email:            record.Email(),
+			OrganisationRole: record.GetString("organisation_role"),
+		})
+	}
+	return members, nil
+}
+
+func (s *organisationService) findSpecialist(ctx echo.Context, id string) (*models.Record, error) {
+	specialist, err := s.organisationRepository.FindSpecialist(ctx, id)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("invalid_specialist")
+		}
+		return nil, err
+	}
+	return specialist, nil
+}
@@ -111,6 +111,20 @@ func (h *PlannerHandler) HandleGetMeal(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, res)
 }
 
+// HandleGetLibrary returns the meals and exercises the caller can build plans from.
+func (h *PlannerHandler) HandleGetLibrary(ctx echo.Context) error {
+	res := utils.GenericHttpResponse{}
+
+	library, err := h.plannerService.GetLibrary(ctx, utils.GetAuthAccountId(ctx))
+	if err != nil {
+		res.Error = fmt.Sprintf("There was an error retrieving the library: %s", err.Error())
+		return apis.NewBadRequestError(res.Error, nil)
+	}
+
+	res.Data = library
+	return ctx.JSON(http.StatusOK, res)
+}
+
 func (h *PlannerHandler) HandleGetMealPlan(ctx echo.Context) error {
 	res := utils.GenericHttpResponse{}
 
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"github.com/arosace/WellnessWaveApi/internal/planner/service"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+)
+
+// PlannerPolicies exposes planner lookups to the authorization policies of the planner routes.
+type PlannerPolicies struct {
+	plannerService service.PlannerService
+}
+
+func NewPlannerPolicies(plannerService service.PlannerService) *PlannerPolicies {
+	return &PlannerPolicies{plannerService: plannerService}
+}
+
+// MealAuthor resolves the health specialist who created the extracted meal.
+// It resolves to an empty string when the meal cannot be found, which denies access.
+func (p *PlannerPolicies) MealAuthor(mealId utils.ParamExtractor) utils.ParamExtractor {
+	return func(c echo.Context) string {
+		id := mealId(c)
+		if id == "" {
+			return ""
+		}
+		meal, err := p.plannerService.GetMealById(c, id)
+		if err != nil || meal == nil {
+			return ""
+		}
+		return meal.GetString("health_specialist_id")
+	}
+}
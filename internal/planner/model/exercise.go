@@ -11,7 +11,7 @@ type Exercise struct {
 	Reps               int    `json:"reps"`
 	Sets               int    `json:"sets"`
 	HealthSpecialistId string `json:"health_specialist_id"`
-	Description        string `json:"description"`
+	Description        string `json:"description" encrypted:"true"`
 	Type               string `json:"type"`
 }
 
@@ -0,0 +1,9 @@
+package model
+
+import "github.com/pocketbase/pocketbase/models"
+
+// Library holds the meals and exercises a health specialist can build plans from.
+type Library struct {
+	Meals     []*models.Record `json:"meals"`
+	Exercises []*models.Record `json:"exercises"`
+}
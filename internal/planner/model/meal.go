@@ -11,7 +11,7 @@ type Meal struct {
 	Ingredients        []string `json:"ingredients"`
 	HealthSpecialistId string   `json:"health_specialist_id"`
 	Cals               int      `json:"cals"`
-	Description        string   `json:"description"`
+	Description        string   `json:"description" encrypted:"true"`
 	Type               string   `json:"type"`
 }
 
@@ -13,7 +13,8 @@ import (
 )
 
 type PlannerRepo struct {
-	Dao *daos.Dao
+	Dao    *daos.Dao
+	Cipher *utils.FieldCipher
 }
 
 type PlannerRepository interface {
@@ -23,14 +24,17 @@ type PlannerRepository interface {
 	GetMealByNameAndHealthSpecialistId(echo.Context, string, string) (*models.Record, error)
 	GetMealById(echo.Context, string) (*models.Record, error)
 	GetMealsByHealthSpecialistId(echo.Context, string) ([]*models.Record, error)
+	GetMealsByOrganisationId(echo.Context, string) ([]*models.Record, error)
 	// Meal Plans
 	AddPlan(echo.Context, *model.Plan) (*models.Record, error)
 	GetPlanByPatientId(echo.Context, string) (*models.Record, error)
 	GetMealPlansByHealthSpecialistId(echo.Context, string) ([]*models.Record, error)
 	// Daily Plans
 	AddDailyPlan(echo.Context, *model.DailyPlan) (*models.Record, error)
+	GetDailyPlansByPlanId(echo.Context, string) ([]*models.Record, error)
 	// Meal Map
 	MapMealToPlan(echo.Context, model.MealMap) (*models.Record, error)
+	GetMealMapByPlanId(echo.Context, string) ([]*models.Record, error)
 
 	//##### EXERCISE PLANS #####
 	// Exercise
@@ -38,23 +42,30 @@ type PlannerRepository interface {
 	GetExerciseByNameAndHealthSpecialistId(echo.Context, string, string) (*models.Record, error)
 	GetExerciseById(echo.Context, string) (*models.Record, error)
 	GetExerciseByHealthSpecialistId(echo.Context, string) ([]*models.Record, error)
+	GetExercisesByOrganisationId(echo.Context, string) ([]*models.Record, error)
 	// Exercise Plans
 	AddExercisePlan(echo.Context, *model.ExercisePlan) (*models.Record, error)
 	GetExercisePlanByPatientId(echo.Context, string) (*models.Record, error)
 	GetExercisePlansByHealthSpecialistId(echo.Context, string) ([]*models.Record, error)
 	// Exercise Daily Plans
 	AddExerciseDailyPlan(echo.Context, *model.DailyExercisePlan) (*models.Record, error)
+	GetExerciseDailyPlansByPlanId(echo.Context, string) ([]*models.Record, error)
 	// Exercise Map
 	MapExerciseToPlan(echo.Context, model.ExerciseMap) (*models.Record, error)
+	GetExerciseMapByPlanId(echo.Context, string) ([]*models.Record, error)
 
 	//Transaction Queries
 	AddPlanInTransaction(echo.Context, *model.Plan) (*models.Record, error)
 	AddExercisePlanInTransaction(echo.Context, *model.ExercisePlan) (*models.Record, error)
+
+	// Encryption
+	Reencrypt(echo.Context) (int, error)
 }
 
-func NewPlannerRepository(dao *daos.Dao) *PlannerRepo {
+func NewPlannerRepository(dao *daos.Dao, encryptor utils.Encryption) *PlannerRepo {
 	return &PlannerRepo{
-		Dao: dao,
+		Dao:    dao,
+		Cipher: utils.NewFieldCipher(encryptor),
 	}
 }
 
@@ -66,7 +77,8 @@ func (r *PlannerRepo) AddMeal(ctx echo.Context, meal *model.Meal) (*models.Recor
 
 	record := models.NewRecord(collection)
 	utils.LoadFromStruct(record, &meal)
-	if err := r.Dao.SaveRecord(record); err != nil {
+	utils.StampTenant(ctx, record)
+	if err := r.save(record, model.Meal{}); err != nil {
 		return nil, fmt.Errorf("Failed to save meal: %w", err)
 	}
 
@@ -82,12 +94,15 @@ func (r *PlannerRepo) GetMealByNameAndHealthSpecialistId(ctx echo.Context, mealN
 
 	record, err := r.Dao.FindFirstRecordByFilter(
 		domain.MEALS_TABLENAME,
-		filter,
+		utils.TenantFilter(ctx, filter, params, "health_specialist_id"),
 		params,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("there was an error retrieving meal [%s] for specialist [%s]: %w", mealName, healthSpecialistId, err)
 	}
+	if err := r.Cipher.Open(record, model.Meal{}); err != nil {
+		return nil, err
+	}
 
 	return record, nil
 }
@@ -97,6 +112,12 @@ func (r *PlannerRepo) GetMealById(ctx echo.Context, mealId string) (*models.Reco
 	if err != nil {
 		return nil, fmt.Errorf("there was an error retrieving meal [%s]: %w", mealId, err)
 	}
+	if err := utils.CheckTenant(ctx, record, "health_specialist_id"); err != nil {
+		return nil, err
+	}
+	if err := r.Cipher.Open(record, model.Meal{}); err != nil {
+		return nil, err
+	}
 	return record, nil
 }
 
@@ -107,7 +128,7 @@ func (r *PlannerRepo) GetMealsByHealthSpecialistId(ctx echo.Context, healthSpeci
 	filter := "health_specialist_id = {:health_specialist_id}"
 	records, err := r.Dao.FindRecordsByFilter(
 		domain.MEALS_TABLENAME,
-		filter,
+		utils.TenantFilter(ctx, filter, params, "health_specialist_id"),
 		"name",
 		-1,
 		0,
@@ -116,8 +137,16 @@ func (r *PlannerRepo) GetMealsByHealthSpecialistId(ctx echo.Context, healthSpeci
 	if err != nil {
 		return nil, fmt.Errorf("there was an error retrieving meals for specialist [%s]: %w", healthSpecialistId, err)
 	}
+	return r.Cipher.OpenAll(records, model.Meal{}), nil
+}
 
-	return records, nil
+// GetMealsByOrganisationId returns the meals of every health specialist of the organisation, its shared library.
+func (r *PlannerRepo) GetMealsByOrganisationId(ctx echo.Context, organisationId string) ([]*models.Record, error) {
+	records, err := r.findByOrganisationId(ctx, domain.MEALS_TABLENAME, organisationId)
+	if err != nil {
+		return nil, err
+	}
+	return r.Cipher.OpenAll(records, model.Meal{}), nil
 }
 
 func (r *PlannerRepo) AddPlan(ctx echo.Context, plan *model.Plan) (*models.Record, error) {
@@ -128,6 +157,7 @@ func (r *PlannerRepo) AddPlan(ctx echo.Context, plan *model.Plan) (*models.Recor
 
 	record := models.NewRecord(collection)
 	utils.LoadFromStruct(record, &plan)
+	utils.StampTenant(ctx, record)
 	if err := r.Dao.SaveRecord(record); err != nil {
 		return nil, fmt.Errorf("Failed to save meal plan: %w", err)
 	}
@@ -136,12 +166,13 @@ func (r *PlannerRepo) AddPlan(ctx echo.Context, plan *model.Plan) (*models.Recor
 }
 
 func (r *PlannerRepo) GetPlanByPatientId(ctx echo.Context, patientId string) (*models.Record, error) {
+	params := dbx.Params{
+		"patient_id": patientId,
+	}
 	record, err := r.Dao.FindFirstRecordByFilter(
 		domain.PLANS_TABLENAME,
-		"patient_id = {:patient_id}",
-		dbx.Params{
-			"patient_id": patientId,
-		},
+		utils.TenantFilter(ctx, "patient_id = {:patient_id}", params, "health_specialist_id", "patient_id"),
+		params,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("there was an error retrieving meal plan for patient [%s]: %w", patientId, err)
@@ -150,15 +181,16 @@ func (r *PlannerRepo) GetPlanByPatientId(ctx echo.Context, patientId string) (*m
 }
 
 func (r *PlannerRepo) GetMealPlansByHealthSpecialistId(ctx echo.Context, healthSpecialistId string) ([]*models.Record, error) {
+	params := dbx.Params{
+		"health_specialist_id": healthSpecialistId,
+	}
 	records, err := r.Dao.FindRecordsByFilter(
 		domain.PLANS_TABLENAME,
-		"health_specialist_id = {:health_specialist_id}",
+		utils.TenantFilter(ctx, "health_specialist_id = {:health_specialist_id}", params, "health_specialist_id", "patient_id"),
 		"",
 		-1,
 		0,
-		dbx.Params{
-			"health_specialist_id": healthSpecialistId,
-		},
+		params,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("there was an error retrieving meal plan for specialist [%s]: %w", healthSpecialistId, err)
@@ -174,6 +206,7 @@ func (r *PlannerRepo) AddDailyPlan(ctx echo.Context, dailyPlan *model.DailyPlan)
 
 	record := models.NewRecord(collection)
 	utils.LoadFromStruct(record, &dailyPlan)
+	utils.StampTenant(ctx, record)
 	if err := r.Dao.SaveRecord(record); err != nil {
 		return nil, fmt.Errorf("Failed to save daily meal plan: %w", err)
 	}
@@ -196,6 +229,15 @@ func (r *PlannerRepo) MapMealToPlan(ctx echo.Context, model model.MealMap) (*mod
 	return record, nil
 }
 
+func (r *PlannerRepo) GetDailyPlansByPlanId(ctx echo.Context, planId string) ([]*models.Record, error) {
+	return r.findByPlanId(ctx, domain.DAILY_PLANS_TABLENAME, "day_index", planId)
+}
+
+func (r *PlannerRepo) GetMealMapByPlanId(ctx echo.Context, planId string) ([]*models.Record, error) {
+	// maps carry no tenant, they are only reached through the plan they belong to
+	return r.findByPlanId(nil, domain.MEAL_MAP, "", planId)
+}
+
 func (r *PlannerRepo) AddPlanInTransaction(ctx echo.Context, plan *model.Plan) (*models.Record, error) {
 	return nil, r.Dao.RunInTransaction(func(txDao *daos.Dao) error {
 		oldDao := r.Dao
@@ -265,6 +307,28 @@ func (r *PlannerRepo) AddPlanInTransaction(ctx echo.Context, plan *model.Plan) (
 	})
 }
 
+// Reencrypt rewrites the encrypted fields of every meal and exercise with the active encryption key.
+func (r *PlannerRepo) Reencrypt(ctx echo.Context) (int, error) {
+	meals, err := r.Cipher.ReencryptCollection(r.Dao, domain.MEALS_TABLENAME, model.Meal{})
+	if err != nil {
+		return meals, err
+	}
+	exercises, err := r.Cipher.ReencryptCollection(r.Dao, domain.EXERCISE_TABLENAME, model.Exercise{})
+	return meals + exercises, err
+}
+
+// save encrypts the encrypted fields of the given model for storage and decrypts them again once saved,
+// so the returned record holds plain text. It uses r.Dao, which is the transaction dao inside transactions.
+func (r *PlannerRepo) save(record *models.Record, m interface{}) error {
+	if err := r.Cipher.Seal(record, m); err != nil {
+		return err
+	}
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return err
+	}
+	return r.Cipher.Open(record, m)
+}
+
 // ################## EXERCISE ##################
 
 func (r *PlannerRepo) AddExercise(ctx echo.Context, meal *model.Exercise) (*models.Record, error) {
@@ -275,7 +339,8 @@ func (r *PlannerRepo) AddExercise(ctx echo.Context, meal *model.Exercise) (*mode
 
 	record := models.NewRecord(collection)
 	utils.LoadFromStruct(record, &meal)
-	if err := r.Dao.SaveRecord(record); err != nil {
+	utils.StampTenant(ctx, record)
+	if err := r.save(record, model.Exercise{}); err != nil {
 		return nil, fmt.Errorf("Failed to save exercise: %w", err)
 	}
 
@@ -291,12 +356,15 @@ func (r *PlannerRepo) GetExerciseByNameAndHealthSpecialistId(ctx echo.Context, n
 
 	record, err := r.Dao.FindFirstRecordByFilter(
 		domain.EXERCISE_TABLENAME,
-		filter,
+		utils.TenantFilter(ctx, filter, params, "health_specialist_id"),
 		params,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("there was an error retrieving exercise [%s] for specialist [%s]: %w", name, healthSpecialistId, err)
 	}
+	if err := r.Cipher.Open(record, model.Exercise{}); err != nil {
+		return nil, err
+	}
 
 	return record, nil
 }
@@ -306,6 +374,12 @@ func (r *PlannerRepo) GetExerciseById(ctx echo.Context, mealId string) (*models.
 	if err != nil {
 		return nil, fmt.Errorf("there was an error retrieving exercise [%s]: %w", mealId, err)
 	}
+	if err := utils.CheckTenant(ctx, record, "health_specialist_id"); err != nil {
+		return nil, err
+	}
+	if err := r.Cipher.Open(record, model.Exercise{}); err != nil {
+		return nil, err
+	}
 	return record, nil
 }
 
@@ -315,8 +389,8 @@ func (r *PlannerRepo) GetExerciseByHealthSpecialistId(ctx echo.Context, healthSp
 	}
 	filter := "health_specialist_id = {:health_specialist_id}"
 	records, err := r.Dao.FindRecordsByFilter(
-		domain.MEALS_TABLENAME,
-		filter,
+		domain.EXERCISE_TABLENAME,
+		utils.TenantFilter(ctx, filter, params, "health_specialist_id"),
 		"name",
 		-1,
 		0,
@@ -325,8 +399,16 @@ func (r *PlannerRepo) GetExerciseByHealthSpecialistId(ctx echo.Context, healthSp
 	if err != nil {
 		return nil, fmt.Errorf("there was an error retrieving exercises for specialist [%s]: %w", healthSpecialistId, err)
 	}
+	return r.Cipher.OpenAll(records, model.Exercise{}), nil
+}
 
-	return records, nil
+// GetExercisesByOrganisationId returns the exercises of every health specialist of the organisation, its shared library.
+func (r *PlannerRepo) GetExercisesByOrganisationId(ctx echo.Context, organisationId string) ([]*models.Record, error) {
+	records, err := r.findByOrganisationId(ctx, domain.EXERCISE_TABLENAME, organisationId)
+	if err != nil {
+		return nil, err
+	}
+	return r.Cipher.OpenAll(records, model.Exercise{}), nil
 }
 
 func (r *PlannerRepo) AddExercisePlan(ctx echo.Context, plan *model.ExercisePlan) (*models.Record, error) {
@@ -337,6 +419,7 @@ func (r *PlannerRepo) AddExercisePlan(ctx echo.Context, plan *model.ExercisePlan
 
 	record := models.NewRecord(collection)
 	utils.LoadFromStruct(record, &plan)
+	utils.StampTenant(ctx, record)
 	if err := r.Dao.SaveRecord(record); err != nil {
 		return nil, fmt.Errorf("Failed to save exercise plan: %w", err)
 	}
@@ -345,12 +428,13 @@ func (r *PlannerRepo) AddExercisePlan(ctx echo.Context, plan *model.ExercisePlan
 }
 
 func (r *PlannerRepo) GetExercisePlanByPatientId(ctx echo.Context, patientId string) (*models.Record, error) {
+	params := dbx.Params{
+		"patient_id": patientId,
+	}
 	record, err := r.Dao.FindFirstRecordByFilter(
 		domain.EXERCISE_PLAN_TABLENAME,
-		"patient_id = {:patient_id}",
-		dbx.Params{
-			"patient_id": patientId,
-		},
+		utils.TenantFilter(ctx, "patient_id = {:patient_id}", params, "health_specialist_id", "patient_id"),
+		params,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("there was an error retrieving exercise plan for patient [%s]: %w", patientId, err)
@@ -359,15 +443,16 @@ func (r *PlannerRepo) GetExercisePlanByPatientId(ctx echo.Context, patientId str
 }
 
 func (r *PlannerRepo) GetExercisePlansByHealthSpecialistId(ctx echo.Context, healthSpecialistId string) ([]*models.Record, error) {
+	params := dbx.Params{
+		"health_specialist_id": healthSpecialistId,
+	}
 	records, err := r.Dao.FindRecordsByFilter(
 		domain.EXERCISE_PLAN_TABLENAME,
-		"health_specialist_id = {:health_specialist_id}",
+		utils.TenantFilter(ctx, "health_specialist_id = {:health_specialist_id}", params, "health_specialist_id", "patient_id"),
 		"",
 		-1,
 		0,
-		dbx.Params{
-			"health_specialist_id": healthSpecialistId,
-		},
+		params,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("there was an error retrieving exercise plan for specialist [%s]: %w", healthSpecialistId, err)
@@ -383,6 +468,7 @@ func (r *PlannerRepo) AddExerciseDailyPlan(ctx echo.Context, dailyPlan *model.Da
 
 	record := models.NewRecord(collection)
 	utils.LoadFromStruct(record, &dailyPlan)
+	utils.StampTenant(ctx, record)
 	if err := r.Dao.SaveRecord(record); err != nil {
 		return nil, fmt.Errorf("Failed to save daily exercise plan: %w", err)
 	}
@@ -405,6 +491,54 @@ func (r *PlannerRepo) MapExerciseToPlan(ctx echo.Context, model model.ExerciseMa
 	return record, nil
 }
 
+func (r *PlannerRepo) GetExerciseDailyPlansByPlanId(ctx echo.Context, planId string) ([]*models.Record, error) {
+	return r.findByPlanId(ctx, domain.DAILY_EXERCISE_PLANS_TABLENAME, "day_index", planId)
+}
+
+func (r *PlannerRepo) GetExerciseMapByPlanId(ctx echo.Context, planId string) ([]*models.Record, error) {
+	// maps carry no tenant, they are only reached through the plan they belong to
+	return r.findByPlanId(nil, domain.EXERCISE_MAP, "", planId)
+}
+
+// findByOrganisationId returns every record of the table belonging to the organisation.
+func (r *PlannerRepo) findByOrganisationId(ctx echo.Context, table string, organisationId string) ([]*models.Record, error) {
+	params := dbx.Params{
+		"organisation_id": organisationId,
+	}
+	records, err := r.Dao.FindRecordsByFilter(
+		table,
+		utils.TenantFilter(ctx, "organisation_id = {:organisation_id}", params, "health_specialist_id"),
+		"name",
+		-1,
+		0,
+		params,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving %s of organisation [%s]: %w", table, organisationId, err)
+	}
+	return records, nil
+}
+
+// findByPlanId returns every record of the table belonging to the given meal or exercise plan,
+// within the tenant of the request unless ctx is nil.
+func (r *PlannerRepo) findByPlanId(ctx echo.Context, table string, sort string, planId string) ([]*models.Record, error) {
+	params := dbx.Params{
+		"plan_id": planId,
+	}
+	records, err := r.Dao.FindRecordsByFilter(
+		table,
+		utils.TenantFilter(ctx, "plan_id = {:plan_id}", params, "health_specialist_id"),
+		sort,
+		-1,
+		0,
+		params,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving %s of plan [%s]: %w", table, planId, err)
+	}
+	return records, nil
+}
+
 func (r *PlannerRepo) AddExercisePlanInTransaction(ctx echo.Context, plan *model.ExercisePlan) (*models.Record, error) {
 	return nil, r.Dao.RunInTransaction(func(txDao *daos.Dao) error {
 		oldDao := r.Dao
@@ -14,6 +14,7 @@ type PlannerService interface {
 	AddMeal(echo.Context, *model.Meal) (*models.Record, error)
 	GetMealById(echo.Context, string) (*models.Record, error)
 	GetMealsByHealthSpecialistId(echo.Context, string) ([]*models.Record, error)
+	GetLibrary(echo.Context, string) (*model.Library, error)
 	AddPlan(echo.Context, *model.Plan) error
 	GetMealPlanByPatientId(echo.Context, string) (*models.Record, error)
 	GetMealPlansByHealthSpecialistId(echo.Context, string) ([]string, error)
@@ -58,6 +59,31 @@ func (s *plannerService) GetMealsByHealthSpecialistId(ctx echo.Context, healthSp
 	return m, nil
 }
 
+// GetLibrary returns the meals and exercises shared within the organisation of the health specialist,
+// or only their own ones when they are not part of any organisation.
+func (s *plannerService) GetLibrary(ctx echo.Context, healthSpecialistId string) (*model.Library, error) {
+	var library model.Library
+	var err error
+
+	if organisationId := utils.GetAuthOrganisationId(ctx); organisationId != "" {
+		if library.Meals, err = s.plannerRepository.GetMealsByOrganisationId(ctx, organisationId); err != nil {
+			return nil, err
+		}
+		if library.Exercises, err = s.plannerRepository.GetExercisesByOrganisationId(ctx, organisationId); err != nil {
+			return nil, err
+		}
+		return &library, nil
+	}
+
+	if library.Meals, err = s.plannerRepository.GetMealsByHealthSpecialistId(ctx, healthSpecialistId); err != nil {
+		return nil, err
+	}
+	if library.Exercises, err = s.plannerRepository.GetExerciseByHealthSpecialistId(ctx, healthSpecialistId); err != nil {
+		return nil, err
+	}
+	return &library, nil
+}
+
 func (s *plannerService) AddPlan(ctx echo.Context, plan *model.Plan) error {
 	_, err := s.plannerRepository.AddPlanInTransaction(ctx, plan)
 	if err != nil {
@@ -0,0 +1,4 @@
+package domain
+
+// ErasureSchedule is the cron expression of the job erasing the accounts whose deletion grace period is over.
+const ErasureSchedule = "0 * * * *"
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+const (
+	// ExportsTableName stores the data exports requested by patients, along with the generated document.
+	ExportsTableName = "data_exports"
+
+	ExportPendingStatus    = "pending"
+	ExportProcessingStatus = "processing"
+	ExportReadyStatus      = "ready"
+	ExportFailedStatus     = "failed"
+
+	// ExportSyncEventLimit is the number of events up to which an export is generated within the request,
+	// larger histories are generated in the background and the export has to be polled until ready.
+	ExportSyncEventLimit = 200
+	// ExportRetention is how long a generated export can be downloaded.
+	ExportRetention = 7 * 24 * time.Hour
+
+	JSONFormat = "json"
+	ZIPFormat  = "zip"
+)
+
+// ExportHiddenAccountFields are credentials left out of the account in exports.
+var ExportHiddenAccountFields = []string{"encrypted_password", "password_hash", "totp_secret", "totp_last_step"}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/arosace/WellnessWaveApi/internal/privacy/domain"
+	"github.com/arosace/WellnessWaveApi/internal/privacy/model"
+	"github.com/arosace/WellnessWaveApi/internal/privacy/service"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// PrivacyHandler handles HTTP requests for the data of patients, e.g. exports.
+type PrivacyHandler struct {
+	privacyService service.PrivacyService
+}
+
+func NewPrivacyHandler(privacyService service.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{privacyService: privacyService}
+}
+
+func (h *PrivacyHandler) HandleRequestExport(ctx echo.Context) error {
+	res := model.DataExportResponse{}
+
+	export, err := h.privacyService.RequestExport(ctx, ctx.PathParam("id"), utils.GetAuthAccountId(ctx))
+	if err != nil {
+		res.Error = fmt.Sprintf("failed_to_export_data: %v", err)
+		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+	}
+
+	res.Data = exportStatus(export)
+	if res.Data.Status == domain.ExportReadyStatus {
+		return ctx.JSON(http.StatusCreated, res)
+	}
+	return ctx.JSON(http.StatusAccepted, res)
+}
+
+func (h *PrivacyHandler) HandleGetExport(ctx echo.Context) error {
+	res := model.DataExportResponse{}
+
+	export, err := h.getExport(ctx)
+	if err != nil {
+		return err
+	}
+
+	res.Data = exportStatus(export)
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// HandleDownloadExport sends the export as JSON, or as a zip with a readable summary when format=zip.
+func (h *PrivacyHandler) HandleDownloadExport(ctx echo.Context) error {
+	format := ctx.QueryParam("format")
+	if format == "" {
+		format = domain.JSONFormat
+	}
+	if format != domain.JSONFormat && format != domain.ZIPFormat {
+		return apis.NewBadRequestError("invalid_data: format must be json or zip", nil)
+	}
+
+	export, err := h.getExport(ctx)
+	if err != nil {
+		return err
+	}
+	if export.GetString("status") != domain.ExportReadyStatus {
+		return apis.NewApiError(http.StatusConflict, "export_not_ready", nil)
+	}
+
+	filename := fmt.Sprintf("wellnesswave-export-%s.%s", export.GetDateTime("created").Time().Format(time.DateOnly), format)
+	ctx.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	document := export.GetString("document")
+	if format == domain.JSONFormat {
+		return ctx.Blob(http.StatusOK, "application/json", []byte(document))
+	}
+	archive, err := h.privacyService.Archive(document)
+	if err != nil {
+		return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("failed_to_archive_export: %v", err), nil)
+	}
+	return ctx.Blob(http.StatusOK, "application/zip", archive)
+}
+
+func (h *PrivacyHandler) getExport(ctx echo.Context) (*models.Record, error) {
+	export, err := h.privacyService.GetExport(ctx, ctx.PathParam("id"), ctx.PathParam("exportId"))
+	if err != nil {
+		switch err.Error() {
+		case "not_found":
+			return nil, apis.NewNotFoundError("export_not_found", nil)
+		case "export_expired":
+			return nil, apis.NewApiError(http.StatusGone, err.Error(), nil)
+		}
+		return nil, apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("failed_to_get_export: %v", err), nil)
+	}
+	return export, nil
+}
+
+func exportStatus(export *models.Record) *model.DataExportStatus {
+	return &model.DataExportStatus{
+		ID:        export.Id,
+		Status:    export.GetString("status"),
+		Created:   export.GetDateTime("created").String(),
+		ExpiresAt: export.GetDateTime("expires_at").String(),
+		Error:     export.GetString("error"),
+	}
+}
@@ -0,0 +1,36 @@
+package model
+
+// DataExport is a copy of their data requested by a patient. Document holds the generated export
+// document as JSON once the status is ready.
+type DataExport struct {
+	ID          string `json:"id,omitempty"`
+	AccountID   string `json:"account_id"`
+	RequestedBy string `json:"requested_by"`
+	Status      string `json:"status"`
+	Document    string `json:"document" encrypted:"true"`
+	Error       string `json:"error"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+// ExportDocument gathers everything stored about a patient.
+type ExportDocument struct {
+	GeneratedAt  string                   `json:"generated_at"`
+	Account      map[string]interface{}   `json:"account"`
+	CareTeam     []map[string]interface{} `json:"care_team"`
+	Events       []map[string]interface{} `json:"events"`
+	MealPlan     *PlanExport              `json:"meal_plan"`
+	ExercisePlan *PlanExport              `json:"exercise_plan"`
+}
+
+// PlanExport is a meal or exercise plan with its daily plans.
+type PlanExport struct {
+	Plan map[string]interface{} `json:"plan"`
+	Days []DayExport            `json:"days"`
+}
+
+// DayExport is a daily plan with the meals or exercises scheduled that day.
+type DayExport struct {
+	DayIndex  int                      `json:"day_index"`
+	Meals     []map[string]interface{} `json:"meals,omitempty"`
+	Exercises []map[string]interface{} `json:"exercises,omitempty"`
+}
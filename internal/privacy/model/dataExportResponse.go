@@ -0,0 +1,15 @@
+package model
+
+// DataExportStatus describes an export without its document.
+type DataExportStatus struct {
+	ID        string `json:"id"`
+	Status    string `json:"status"`
+	Created   string `json:"created"`
+	ExpiresAt string `json:"expires_at"`
+	Error     string `json:"error,omitempty"`
+}
+
+type DataExportResponse struct {
+	Data  *DataExportStatus `json:"data"`
+	Error string            `json:"error"`
+}
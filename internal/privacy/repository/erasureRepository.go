@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"fmt"
+
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	eventDomain "github.com/arosace/WellnessWaveApi/internal/event/domain"
+	organisationDomain "github.com/arosace/WellnessWaveApi/internal/organisation/domain"
+	plannerDomain "github.com/arosace/WellnessWaveApi/internal/planner/domain"
+	"github.com/arosace/WellnessWaveApi/internal/privacy/domain"
+	specialistDomain "github.com/arosace/WellnessWaveApi/internal/specialist/domain"
+	transferDomain "github.com/arosace/WellnessWaveApi/internal/transfer/domain"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+// ErasureRepository removes or anonymises the records of every subdomain that reference an account.
+type ErasureRepository interface {
+	Cascade(accountId string) error
+}
+
+type ErasureRepo struct {
+	Dao *daos.Dao
+}
+
+func NewErasureRepository(dao *daos.Dao) *ErasureRepo {
+	return &ErasureRepo{
+		Dao: dao,
+	}
+}
+
+// Cascade deletes the data of the account as a patient: its events, plans, transfers and credentials.
+// Records it created as a health specialist stay with the patients they belong to, with the
+// reference to the specialist cleared, and the account leaves every care team it is part of
+// along with its invitations to join organisations and the attach requests it made or received.
+// Its working hours and its directory profile, with its photo, are deleted.
+// Run it with the dao of the transaction deleting the account so both happen or neither does.
+func (r *ErasureRepo) Cascade(accountId string) error {
+	if err := r.delete(eventDomain.TABLENAME, dbx.HashExp{"patient_id": accountId}); err != nil {
+		return err
+	}
+	if err := r.deletePlans(plannerDomain.PLANS_TABLENAME, accountId, plannerDomain.DAILY_PLANS_TABLENAME, plannerDomain.MEAL_MAP); err != nil {
+		return err
+	}
+	if err := r.deletePlans(plannerDomain.EXERCISE_PLAN_TABLENAME, accountId, plannerDomain.DAILY_EXERCISE_PLANS_TABLENAME, plannerDomain.EXERCISE_MAP); err != nil {
+		return err
+	}
+	for _, table := range []string{accountDomain.RefreshTokensTableName, accountDomain.AccountTokensTableName, domain.ExportsTableName} {
+		if err := r.delete(table, dbx.HashExp{"account_id": accountId}); err != nil {
+			return err
+		}
+	}
+	if err := r.delete(transferDomain.TableName, dbx.HashExp{"patient_id": accountId}); err != nil {
+		return err
+	}
+	// transfers to or from the account can no longer take place
+	_, err := r.Dao.DB().Update(
+		transferDomain.TableName,
+		dbx.Params{"status": transferDomain.CancelledStatus},
+		dbx.And(
+			dbx.HashExp{"status": transferDomain.PendingStatus},
+			dbx.Or(dbx.HashExp{"from_specialist_id": accountId}, dbx.HashExp{"to_specialist_id": accountId}),
+		),
+	).Execute()
+	if err != nil {
+		return fmt.Errorf("there was an error cancelling the transfers of the account: %w", err)
+	}
+
+	for _, table := range []string{
+		eventDomain.TABLENAME,
+		plannerDomain.PLANS_TABLENAME,
+		plannerDomain.DAILY_PLANS_TABLENAME,
+		plannerDomain.MEALS_TABLENAME,
+		plannerDomain.EXERCISE_PLAN_TABLENAME,
+		plannerDomain.DAILY_EXERCISE_PLANS_TABLENAME,
+		plannerDomain.EXERCISE_TABLENAME,
+	} {
+		if err := r.clear(table, "health_specialist_id", accountId); err != nil {
+			return err
+		}
+	}
+	if err := r.clear(eventDomain.TABLENAME, "status_changed_by", accountId); err != nil {
+		return err
+	}
+	if err := r.delete(organisationDomain.InvitationsTableName, dbx.HashExp{"specialist_id": accountId}); err != nil {
+		return err
+	}
+	for _, table := range []string{eventDomain.AvailabilityTableName, eventDomain.AvailabilityExceptionsTableName} {
+		if err := r.delete(table, dbx.HashExp{"health_specialist_id": accountId}); err != nil {
+			return err
+		}
+	}
+	if err := r.delete(accountDomain.AttachRequestsTableName, dbx.Or(dbx.HashExp{"patient_id": accountId}, dbx.HashExp{"specialist_id": accountId})); err != nil {
+		return err
+	}
+	if err := r.deleteProfile(accountId); err != nil {
+		return err
+	}
+	return r.delete(accountDomain.CareTeamTableName, dbx.Or(dbx.HashExp{"patient_id": accountId}, dbx.HashExp{"specialist_id": accountId}))
+}
+
+// deletePlans deletes the plans of the patient with their daily plans and the rows mapping them to meals or exercises.
+func (r *ErasureRepo) deletePlans(plansTable string, patientId string, dailyPlansTable string, mapTable string) error {
+	var planIds []string
+	err := r.Dao.DB().
+		Select("id").
+		From(plansTable).
+		Where(dbx.HashExp{"patient_id": patientId}).
+		Column(&planIds)
+	if err != nil {
+		return fmt.Errorf("there was an error fetching the plans to erase from %s: %w", plansTable, err)
+	}
+	if len(planIds) == 0 {
+		return nil
+	}
+
+	ids := make([]interface{}, len(planIds))
+	for i, id := range planIds {
+		ids[i] = id
+	}
+	if err := r.delete(mapTable, dbx.In("plan_id", ids...)); err != nil {
+		return err
+	}
+	if err := r.delete(dailyPlansTable, dbx.In("plan_id", ids...)); err != nil {
+		return err
+	}
+	return r.delete(plansTable, dbx.In("id", ids...))
+}
+
+// deleteProfile deletes the directory profile of the account through the dao, so its photo is removed from the storage too.
+func (r *ErasureRepo) deleteProfile(accountId string) error {
+	profiles, err := r.Dao.FindRecordsByExpr(specialistDomain.ProfileTableName, dbx.HashExp{"account_id": accountId})
+	if err != nil {
+		return fmt.Errorf("there was an error fetching the profile to erase: %w", err)
+	}
+	for _, profile := range profiles {
+		if err := r.Dao.DeleteRecord(profile); err != nil {
+			return fmt.Errorf("there was an error erasing from %s: %w", specialistDomain.ProfileTableName, err)
+		}
+	}
+	return nil
+}
+
+func (r *ErasureRepo) delete(table string, where dbx.Expression) error {
+	if _, err := r.Dao.DB().Delete(table, where).Execute(); err != nil {
+		return fmt.Errorf("there was an error erasing from %s: %w", table, err)
+	}
+	return nil
+}
+
+func (r *ErasureRepo) clear(table string, field string, accountId string) error {
+	_, err := r.Dao.DB().Update(table, dbx.Params{field: ""}, dbx.HashExp{field: accountId}).Execute()
+	if err != nil {
+		return fmt.Errorf("there was an error anonymising %s: %w", table, err)
+	}
+	return nil
+}
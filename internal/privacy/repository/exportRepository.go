@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/arosace/WellnessWaveApi/internal/privacy/domain"
+	"github.com/arosace/WellnessWaveApi/internal/privacy/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// ExportRepository defines the interface for data export data access.
+type ExportRepository interface {
+	Add(echo.Context, model.DataExport) (*models.Record, error)
+	FindByID(echo.Context, string) (*models.Record, error)
+	Update(echo.Context, *models.Record) (*models.Record, error)
+	Reencrypt(echo.Context) (int, error)
+}
+
+type ExportRepo struct {
+	Dao    *daos.Dao
+	Cipher *utils.FieldCipher
+}
+
+func NewExportRepository(dao *daos.Dao, encryptor utils.Encryption) *ExportRepo {
+	return &ExportRepo{
+		Dao:    dao,
+		Cipher: utils.NewFieldCipher(encryptor),
+	}
+}
+
+func (r *ExportRepo) Add(ctx echo.Context, export model.DataExport) (*models.Record, error) {
+	collection, err := r.Dao.FindCollectionByNameOrId(domain.ExportsTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.NewRecord(collection)
+	utils.LoadFromStruct(record, &export)
+	if err := r.save(record); err != nil {
+		return nil, fmt.Errorf("Failed to save data export: %w", err)
+	}
+
+	return record, nil
+}
+
+func (r *ExportRepo) FindByID(ctx echo.Context, id string) (*models.Record, error) {
+	record, err := r.Dao.FindRecordById(domain.ExportsTableName, id)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving data export [%s]: %w", id, err)
+	}
+	if err := r.Cipher.Open(record, model.DataExport{}); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *ExportRepo) Update(ctx echo.Context, record *models.Record) (*models.Record, error) {
+	record.MarkAsNotNew()
+	if err := r.save(record); err != nil {
+		return nil, fmt.Errorf("there was an error updating data export: %w", err)
+	}
+	return record, nil
+}
+
+// Reencrypt rewrites the encrypted documents of every export with the active encryption key.
+func (r *ExportRepo) Reencrypt(ctx echo.Context) (int, error) {
+	return r.Cipher.ReencryptCollection(r.Dao, domain.ExportsTableName, model.DataExport{})
+}
+
+// save encrypts the document for storage and decrypts it again once saved, so the returned record holds plain text.
+func (r *ExportRepo) save(record *models.Record) error {
+	if err := r.Cipher.Seal(record, model.DataExport{}); err != nil {
+		return err
+	}
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return err
+	}
+	return r.Cipher.Open(record, model.DataExport{})
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	auditDomain "github.com/arosace/WellnessWaveApi/internal/audit/domain"
+	auditModel "github.com/arosace/WellnessWaveApi/internal/audit/model"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// EraseDueAccounts erases the deleted accounts whose grace period is over, together with their data,
+// and returns how many were erased. The audit log only keeps the id of an erased account.
+func (s *privacyService) EraseDueAccounts(ctx echo.Context) (int, error) {
+	now, err := types.ParseDateTime(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	accounts, err := s.accountRepository.FindDueForErasure(ctx, now.String())
+	if err != nil {
+		return 0, err
+	}
+
+	erased := 0
+	for _, account := range accounts {
+		if err := s.accountRepository.Delete(ctx, account); err != nil {
+			return erased, fmt.Errorf("Failed to erase account %s: %w", account.Id, err)
+		}
+		erased++
+
+		patientId := ""
+		if account.GetString("role") == accountDomain.PatientRole {
+			patientId = account.Id
+		}
+		err := s.auditService.Record(ctx, auditModel.AuditEntry{
+			Action:           auditDomain.AccountErasedAction,
+			Outcome:          auditDomain.SuccessOutcome,
+			TargetCollection: accountDomain.TableName,
+			TargetID:         account.Id,
+			PatientID:        patientId,
+			Details: map[string]interface{}{
+				"deleted_at": account.GetString("deleted_at"),
+			},
+		})
+		if err != nil {
+			return erased, err
+		}
+	}
+	return erased, nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+
+	"github.com/arosace/WellnessWaveApi/internal/privacy/model"
+)
+
+// summaryTemplate renders the export for people, data.json remains the complete machine readable copy.
+var summaryTemplate = template.Must(template.New("summary").Funcs(template.FuncMap{
+	"fields": sortedFields,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Your WellnessWave data</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>Your WellnessWave data</h1>
+<p>Generated on {{.GeneratedAt}}. The complete data is in data.json.</p>
+
+<h2>Account</h2>
+{{template "record" .Account}}
+
+<h2>Events ({{len .Events}})</h2>
+{{range .Events}}{{template "record" .}}{{else}}<p>No events.</p>{{end}}
+
+<h2>Meal plan</h2>
+{{with .MealPlan}}{{range .Days}}
+<h3>Day {{.DayIndex}}</h3>
+{{range .Meals}}{{template "record" .}}{{end}}
+{{end}}{{else}}<p>No meal plan.</p>{{end}}
+
+<h2>Exercise plan</h2>
+{{with .ExercisePlan}}{{range .Days}}
+<h3>Day {{.DayIndex}}</h3>
+{{range .Exercises}}{{template "record" .}}{{end}}
+{{end}}{{else}}<p>No exercise plan.</p>{{end}}
+</body>
+</html>
+{{define "record"}}<table>{{range fields .}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>{{end}}</table>{{end}}
+`))
+
+type field struct {
+	Name  string
+	Value interface{}
+}
+
+// sortedFields lists the fields of a record by name, so the summary is stable from one export to the next.
+func sortedFields(record map[string]interface{}) []field {
+	fields := make([]field, 0, len(record))
+	for name, value := range record {
+		fields = append(fields, field{Name: name, Value: value})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields
+}
+
+// archiveExport writes data.json and summary.html to a zip archive.
+func archiveExport(document string, export *model.ExportDocument) ([]byte, error) {
+	var summary bytes.Buffer
+	if err := summaryTemplate.Execute(&summary, export); err != nil {
+		return nil, fmt.Errorf("error rendering export summary: %w", err)
+	}
+
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"data.json", []byte(document)},
+		{"summary.html", summary.Bytes()},
+	}
+	for _, file := range files {
+		w, err := writer.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(file.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return archive.Bytes(), nil
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	accountRepository "github.com/arosace/WellnessWaveApi/internal/account/repository"
+	auditService "github.com/arosace/WellnessWaveApi/internal/audit/service"
+	eventRepository "github.com/arosace/WellnessWaveApi/internal/event/repository"
+	plannerRepository "github.com/arosace/WellnessWaveApi/internal/planner/repository"
+	"github.com/arosace/WellnessWaveApi/internal/privacy/domain"
+	"github.com/arosace/WellnessWaveApi/internal/privacy/model"
+	"github.com/arosace/WellnessWaveApi/internal/privacy/repository"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// PrivacyService implements the rights of patients over their data.
+type PrivacyService interface {
+	RequestExport(ctx echo.Context, accountId string, requestedBy string) (*models.Record, error)
+	GetExport(ctx echo.Context, accountId string, exportId string) (*models.Record, error)
+	Archive(document string) ([]byte, error)
+	EraseDueAccounts(ctx echo.Context) (int, error)
+}
+
+type privacyService struct {
+	accountRepository  accountRepository.AccountRepository
+	careTeamRepository accountRepository.CareTeamRepository
+	eventRepository    eventRepository.EventRepository
+	plannerRepository  plannerRepository.PlannerRepository
+	exportRepository   repository.ExportRepository
+	auditService       auditService.AuditService
+}
+
+func NewPrivacyService(
+	accountRepo accountRepository.AccountRepository,
+	careTeamRepo accountRepository.CareTeamRepository,
+	eventRepo eventRepository.EventRepository,
+	plannerRepo plannerRepository.PlannerRepository,
+	exportRepo repository.ExportRepository,
+	auditor auditService.AuditService,
+) PrivacyService {
+	return &privacyService{
+		accountRepository:  accountRepo,
+		careTeamRepository: careTeamRepo,
+		eventRepository:    eventRepo,
+		plannerRepository:  plannerRepo,
+		exportRepository:   exportRepo,
+		auditService:       auditor,
+	}
+}
+
+// RequestExport creates an export of the account data. Small histories are generated right away,
+// larger ones in the background, in which case the export is returned while still pending.
+func (s *privacyService) RequestExport(ctx echo.Context, accountId string, requestedBy string) (*models.Record, error) {
+	eventCount, err := s.eventRepository.CountByPatientId(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := types.ParseDateTime(time.Now().Add(domain.ExportRetention))
+	if err != nil {
+		return nil, err
+	}
+	export, err := s.exportRepository.Add(ctx, model.DataExport{
+		AccountID:   accountId,
+		RequestedBy: requestedBy,
+		Status:      domain.ExportPendingStatus,
+		ExpiresAt:   expiresAt.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if eventCount <= domain.ExportSyncEventLimit {
+		if err := s.generateExport(ctx, export); err != nil {
+			return nil, err
+		}
+		return export, nil
+	}
+
+	// the request context is gone once the response is sent, the repositories do not need it,
+	// and the export is copied so the response can be built while the copy is being updated
+	background := export.CleanCopy()
+	go func() {
+		if err := s.generateExport(nil, background); err != nil {
+			log.Printf("Failed to generate data export %s: %v", background.Id, err)
+		}
+	}()
+	return export, nil
+}
+
+// GetExport returns the export if it belongs to the account and has not expired.
+func (s *privacyService) GetExport(ctx echo.Context, accountId string, exportId string) (*models.Record, error) {
+	export, err := s.exportRepository.FindByID(ctx, exportId)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+	if export.GetString("account_id") != accountId {
+		return nil, errors.New("not_found")
+	}
+	if export.GetDateTime("expires_at").Time().Before(time.Now()) {
+		return nil, errors.New("export_expired")
+	}
+	return export, nil
+}
+
+// generateExport builds the document and stores it on the export, recording the failure on the export otherwise.
+func (s *privacyService) generateExport(ctx echo.Context, export *models.Record) error {
+	export.Set("status", domain.ExportProcessingStatus)
+	if _, err := s.exportRepository.Update(ctx, export); err != nil {
+		return err
+	}
+
+	document, err := s.buildDocument(ctx, export.GetString("account_id"))
+	if err == nil {
+		var data []byte
+		if data, err = json.Marshal(document); err == nil {
+			export.Set("document", string(data))
+			export.Set("status", domain.ExportReadyStatus)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to build data export %s: %v", export.Id, err)
+		export.Set("status", domain.ExportFailedStatus)
+		export.Set("error", "the export could not be generated, please request a new one")
+	}
+
+	if _, err := s.exportRepository.Update(ctx, export); err != nil {
+		return err
+	}
+	return nil
+}
+
+// buildDocument gathers the account, its events and its meal and exercise plans.
+func (s *privacyService) buildDocument(ctx echo.Context, accountId string) (*model.ExportDocument, error) {
+	account, err := s.accountRepository.FindByID(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+	accountData := account.PublicExport()
+	for _, field := range domain.ExportHiddenAccountFields {
+		delete(accountData, field)
+	}
+
+	careTeam, err := s.careTeamRepository.FindByPatientId(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.eventRepository.GetByPatientId(ctx, accountId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	mealPlan, err := s.exportMealPlan(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+	exercisePlan, err := s.exportExercisePlan(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ExportDocument{
+		GeneratedAt:  types.NowDateTime().String(),
+		Account:      accountData,
+		CareTeam:     exportRecords(careTeam),
+		Events:       exportRecords(events),
+		MealPlan:     mealPlan,
+		ExercisePlan: exercisePlan,
+	}, nil
+}
+
+func (s *privacyService) exportMealPlan(ctx echo.Context, accountId string) (*model.PlanExport, error) {
+	plan, err := s.plannerRepository.GetPlanByPatientId(ctx, accountId)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	dailyPlans, err := s.plannerRepository.GetDailyPlansByPlanId(ctx, plan.Id)
+	if err != nil {
+		return nil, err
+	}
+	mealMap, err := s.plannerRepository.GetMealMapByPlanId(ctx, plan.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	meals := map[string]map[string]interface{}{}
+	days := make([]model.DayExport, 0, len(dailyPlans))
+	for _, dailyPlan := range dailyPlans {
+		day := model.DayExport{DayIndex: dailyPlan.GetInt("day_index")}
+		for _, entry := range mealMap {
+			if entry.GetString("daily_plan_id") != dailyPlan.Id {
+				continue
+			}
+			mealId := entry.GetString("meal_id")
+			if _, ok := meals[mealId]; !ok {
+				meal, err := s.plannerRepository.GetMealById(ctx, mealId)
+				if err != nil {
+					return nil, err
+				}
+				meals[mealId] = meal.PublicExport()
+			}
+			day.Meals = append(day.Meals, meals[mealId])
+		}
+		days = append(days, day)
+	}
+
+	return &model.PlanExport{Plan: plan.PublicExport(), Days: days}, nil
+}
+
+func (s *privacyService) exportExercisePlan(ctx echo.Context, accountId string) (*model.PlanExport, error) {
+	plan, err := s.plannerRepository.GetExercisePlanByPatientId(ctx, accountId)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	dailyPlans, err := s.plannerRepository.GetExerciseDailyPlansByPlanId(ctx, plan.Id)
+	if err != nil {
+		return nil, err
+	}
+	exerciseMap, err := s.plannerRepository.GetExerciseMapByPlanId(ctx, plan.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	exercises := map[string]map[string]interface{}{}
+	days := make([]model.DayExport, 0, len(dailyPlans))
+	for _, dailyPlan := range dailyPlans {
+		day := model.DayExport{DayIndex: dailyPlan.GetInt("day_index")}
+		for _, entry := range exerciseMap {
+			if entry.GetString("daily_plan_id") != dailyPlan.Id {
+				continue
+			}
+			exerciseId := entry.GetString("exercise_id")
+			if _, ok := exercises[exerciseId]; !ok {
+				exercise, err := s.plannerRepository.GetExerciseById(ctx, exerciseId)
+				if err != nil {
+					return nil, err
+				}
+				exercises[exerciseId] = exercise.PublicExport()
+			}
+			day.Exercises = append(day.Exercises, exercises[exerciseId])
+		}
+		days = append(days, day)
+	}
+
+	return &model.PlanExport{Plan: plan.PublicExport(), Days: days}, nil
+}
+
+func exportRecords(records []*models.Record) []map[string]interface{} {
+	data := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		data = append(data, record.PublicExport())
+	}
+	return data
+}
+
+// Archive packs the export document in a zip along with a human readable summary of it.
+func (s *privacyService) Archive(document string) ([]byte, error) {
+	var export model.ExportDocument
+	if err := json.Unmarshal([]byte(document), &export); err != nil {
+		return nil, fmt.Errorf("invalid export document: %w", err)
+	}
+	return archiveExport(document, &export)
+}
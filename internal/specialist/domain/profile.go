@@ -0,0 +1,27 @@
+package domain
+
+const (
+	// ProfileTableName stores the public profiles of health specialists, one per specialist.
+	ProfileTableName = "specialist_profiles"
+	// PhotoField is the file field of the profile holding the photo of the specialist.
+	PhotoField = "photo"
+
+	DefaultDirectoryLimit = 20
+	MaxDirectoryLimit     = 100
+
+	// appointment types a health specialist can accept
+	InPersonAppointment = "in_person"
+	VideoAppointment    = "video"
+	PhoneAppointment    = "phone"
+)
+
+var AppointmentTypes = []string{InPersonAppointment, VideoAppointment, PhoneAppointment}
+
+func IsAppointmentType(appointmentType string) bool {
+	for _, t := range AppointmentTypes {
+		if t == appointmentType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/arosace/WellnessWaveApi/internal/specialist/domain"
+	"github.com/arosace/WellnessWaveApi/internal/specialist/model"
+	"github.com/arosace/WellnessWaveApi/internal/specialist/service"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+// ProfileHandler handles HTTP requests to the specialist directory and to the profiles listed in it.
+type ProfileHandler struct {
+	profileService service.ProfileService
+}
+
+func NewProfileHandler(profileService service.ProfileService) *ProfileHandler {
+	return &ProfileHandler{profileService: profileService}
+}
+
+func (h *ProfileHandler) HandleSearch(ctx echo.Context) error {
+	res := model.ProfileResponse{}
+
+	query := model.DirectoryQuery{
+		Specialty: ctx.QueryParam("specialty"),
+		Language:  ctx.QueryParam("language"),
+		Name:      ctx.QueryParam("name"),
+	}
+	var err error
+	if limit := ctx.QueryParam("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			res.Error = "invalid_data: limit"
+			return apis.NewBadRequestError(res.Error, nil)
+		}
+	}
+	if offset := ctx.QueryParam("offset"); offset != "" {
+		if query.Offset, err = strconv.Atoi(offset); err != nil {
+			res.Error = "invalid_data: offset"
+			return apis.NewBadRequestError(res.Error, nil)
+		}
+	}
+	if err := query.ValidateModel(); err != nil {
+		res.Error = err.Error()
+		return apis.NewBadRequestError(res.Error, nil)
+	}
+
+	entries, err := h.profileService.Search(ctx, query)
+	if err != nil {
+		return profileError(err, "Failed to search the specialist directory")
+	}
+
+	res.Data = entries
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *ProfileHandler) HandleGetProfile(ctx echo.Context) error {
+	res := model.ProfileResponse{}
+
+	entry, err := h.profileService.GetProfile(ctx, ctx.PathParam("id"))
+	if err != nil {
+		return profileError(err, "Failed to get specialist profile")
+	}
+
+	res.Data = entry
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *ProfileHandler) HandleGetOwnProfile(ctx echo.Context) error {
+	res := model.ProfileResponse{}
+
+	entry, err := h.profileService.GetOwnProfile(ctx, ctx.PathParam("id"))
+	if err != nil {
+		return profileError(err, "Failed to get specialist profile")
+	}
+
+	res.Data = entry
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *ProfileHandler) HandleUpdateProfile(ctx echo.Context) error {
+	res := model.ProfileResponse{}
+	var body model.Profile
+
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("wrong_data_type", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	entry, err := h.profileService.UpdateProfile(ctx, ctx.PathParam("id"), body)
+	if err != nil {
+		return profileError(err, "Failed to update specialist profile")
+	}
+
+	res.Data = entry
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *ProfileHandler) HandleSetPhoto(ctx echo.Context) error {
+	res := model.ProfileResponse{}
+
+	photo, err := ctx.FormFile(domain.PhotoField)
+	if err != nil {
+		return apis.NewBadRequestError("missing_photo", nil)
+	}
+
+	entry, err := h.profileService.SetPhoto(ctx, ctx.PathParam("id"), photo)
+	if err != nil {
+		return profileError(err, "Failed to set specialist photo")
+	}
+
+	res.Data = entry
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// profileError maps the errors of the profile service to their status code.
+func profileError(err error, message string) error {
+	switch err.Error() {
+	case "not_found":
+		return apis.NewNotFoundError(err.Error(), nil)
+	case "invalid_photo":
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+	return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("%s: %v", message, err), nil)
+}
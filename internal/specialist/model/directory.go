@@ -0,0 +1,32 @@
+package model
+
+import "errors"
+
+// DirectoryEntry is the public view of a health specialist, it holds no contact details.
+type DirectoryEntry struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Bio              string   `json:"bio"`
+	Specialties      []string `json:"specialties"`
+	Languages        []string `json:"languages"`
+	Credentials      []string `json:"credentials"`
+	AppointmentTypes []string `json:"appointment_types"`
+	PhotoURL         string   `json:"photo_url"`
+	Published        bool     `json:"published"`
+}
+
+// DirectoryQuery filters the published profiles by specialty, language and name.
+type DirectoryQuery struct {
+	Specialty string
+	Language  string
+	Name      string
+	Limit     int
+	Offset    int
+}
+
+func (q *DirectoryQuery) ValidateModel() error {
+	if q.Limit < 0 || q.Offset < 0 {
+		return errors.New("invalid_data: limit and offset must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/arosace/WellnessWaveApi/internal/specialist/domain"
+)
+
+// Profile is what a health specialist shares about themselves in the directory, it is only listed once published.
+type Profile struct {
+	ID               string   `json:"id,omitempty"`
+	AccountID        string   `json:"account_id"`
+	Bio              string   `json:"bio"`
+	Specialties      []string `json:"specialties"`
+	Languages        []string `json:"languages"`
+	Credentials      []string `json:"credentials"`
+	AppointmentTypes []string `json:"appointment_types"`
+	Published        bool     `json:"published"`
+}
+
+func (m *Profile) ValidateModel() error {
+	for _, appointmentType := range m.AppointmentTypes {
+		if !domain.IsAppointmentType(appointmentType) {
+			return errors.New("invalid_appointment_type")
+		}
+	}
+	return nil
+}
+
+// Normalize trims every value and lower cases specialties and languages, so the directory can match them exactly.
+func (m *Profile) Normalize() {
+	m.Bio = strings.TrimSpace(m.Bio)
+	m.Specialties = normalizeList(m.Specialties, true)
+	m.Languages = normalizeList(m.Languages, true)
+	m.Credentials = normalizeList(m.Credentials, false)
+	m.AppointmentTypes = normalizeList(m.AppointmentTypes, true)
+}
+
+// normalizeList trims the values of the list, dropping empty and duplicate ones.
+func normalizeList(values []string, lowerCase bool) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if lowerCase {
+			value = strings.ToLower(value)
+		}
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		normalized = append(normalized, value)
+	}
+	return normalized
+}
@@ -0,0 +1,6 @@
+package model
+
+type ProfileResponse struct {
+	Data  interface{} `json:"data"`
+	Error string      `json:"error_message"`
+}
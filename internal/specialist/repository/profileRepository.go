@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"fmt"
+	"mime/multipart"
+
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	"github.com/arosace/WellnessWaveApi/internal/specialist/domain"
+	"github.com/arosace/WellnessWaveApi/internal/specialist/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/forms"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+)
+
+// ProfileRepository defines the interface for specialist profile data access.
+type ProfileRepository interface {
+	FindByAccountId(echo.Context, string) (*models.Record, error)
+	Upsert(echo.Context, model.Profile) (*models.Record, error)
+	SetPhoto(echo.Context, *models.Record, *multipart.FileHeader) (*models.Record, error)
+	Search(echo.Context, model.DirectoryQuery) ([]*models.Record, error)
+	FindSpecialist(echo.Context, string) (*models.Record, error)
+	FindSpecialists(echo.Context, []string) ([]*models.Record, error)
+}
+
+type ProfileRepo struct {
+	App core.App
+	Dao *daos.Dao
+}
+
+func NewProfileRepository(app core.App, dao *daos.Dao) *ProfileRepo {
+	return &ProfileRepo{App: app, Dao: dao}
+}
+
+func (r *ProfileRepo) FindByAccountId(ctx echo.Context, accountId string) (*models.Record, error) {
+	record, err := r.Dao.FindFirstRecordByFilter(
+		domain.ProfileTableName,
+		"account_id = {:account_id}",
+		dbx.Params{"account_id": accountId},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving the profile of specialist [%s]: %w", accountId, err)
+	}
+	return record, nil
+}
+
+// Upsert creates the profile of the health specialist or replaces the one they have, keeping its photo.
+func (r *ProfileRepo) Upsert(ctx echo.Context, profile model.Profile) (*models.Record, error) {
+	record, err := r.FindByAccountId(ctx, profile.AccountID)
+	if err != nil {
+		if !utils.IsErrorNotFound(err) {
+			return nil, err
+		}
+		collection, err := r.Dao.FindCollectionByNameOrId(domain.ProfileTableName)
+		if err != nil {
+			return nil, err
+		}
+		record = models.NewRecord(collection)
+	}
+
+	profile.ID = record.Id
+	utils.LoadFromStruct(record, &profile)
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("Failed to save specialist profile: %w", err)
+	}
+	return record, nil
+}
+
+// SetPhoto uploads the photo of the profile, replacing the previous one. The file is validated against
+// the constraints of the photo field of the collection.
+func (r *ProfileRepo) SetPhoto(ctx echo.Context, record *models.Record, header *multipart.FileHeader) (*models.Record, error) {
+	file, err := filesystem.NewFileFromMultipart(header)
+	if err != nil {
+		return nil, err
+	}
+
+	form := forms.NewRecordUpsert(r.App, record)
+	form.SetDao(r.Dao)
+	if err := form.AddFiles(domain.PhotoField, file); err != nil {
+		return nil, err
+	}
+	if err := form.Submit(); err != nil {
+		return nil, fmt.Errorf("invalid_photo: %w", err)
+	}
+	return record, nil
+}
+
+// Search returns the published profiles of active health specialists matching the query, ordered by name.
+func (r *ProfileRepo) Search(ctx echo.Context, query model.DirectoryQuery) ([]*models.Record, error) {
+	profiles := domain.ProfileTableName
+	accounts := accountDomain.TableName
+
+	q := r.Dao.RecordQuery(profiles).
+		InnerJoin(accounts, dbx.NewExp(fmt.Sprintf("[[%s.id]] = [[%s.account_id]]", accounts, profiles))).
+		AndWhere(dbx.HashExp{
+			profiles + ".published":  true,
+			accounts + ".role":       accountDomain.HealthSpecialistRole,
+			accounts + ".deleted_at": "",
+		})
+	if query.Specialty != "" {
+		q = q.AndWhere(jsonContains(profiles+".specialties", "specialty", query.Specialty))
+	}
+	if query.Language != "" {
+		q = q.AndWhere(jsonContains(profiles+".languages", "language", query.Language))
+	}
+	if query.Name != "" {
+		q = q.AndWhere(dbx.Or(
+			dbx.Like(accounts+".first_name", query.Name),
+			dbx.Like(accounts+".last_name", query.Name),
+		))
+	}
+
+	records := []*models.Record{}
+	err := q.OrderBy(accounts+".last_name ASC", accounts+".first_name ASC").
+		Limit(int64(query.Limit)).
+		Offset(int64(query.Offset)).
+		All(&records)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error searching the specialist directory: %w", err)
+	}
+	return records, nil
+}
+
+// FindSpecialist returns the account of the health specialist, whichever organisation they belong to.
+func (r *ProfileRepo) FindSpecialist(ctx echo.Context, id string) (*models.Record, error) {
+	record, err := r.Dao.FindFirstRecordByFilter(
+		accountDomain.TableName,
+		"id = {:id} && role = {:role} && deleted_at = ''",
+		dbx.Params{"id": id, "role": accountDomain.HealthSpecialistRole},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving specialist [%s]: %w", id, err)
+	}
+	return record, nil
+}
+
+func (r *ProfileRepo) FindSpecialists(ctx echo.Context, ids []string) ([]*models.Record, error) {
+	records, err := r.Dao.FindRecordsByIds(accountDomain.TableName, ids)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving the specialists of the directory: %w", err)
+	}
+	return records, nil
+}
+
+// jsonContains matches the rows whose json array column holds the value.
+func jsonContains(column string, param string, value string) dbx.Expression {
+	return dbx.NewExp(
+		fmt.Sprintf("EXISTS (SELECT 1 FROM json_each([[%s]]) WHERE json_each.value = {:%s})", column, param),
+		dbx.Params{param: value},
+	)
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"errors"
+	"mime/multipart"
+	"strings"
+
+	"github.com/arosace/WellnessWaveApi/internal/specialist/domain"
+	"github.com/arosace/WellnessWaveApi/internal/specialist/model"
+	"github.com/arosace/WellnessWaveApi/internal/specialist/repository"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// ProfileService manages the profiles health specialists publish in the directory patients search.
+type ProfileService interface {
+	GetProfile(ctx echo.Context, specialistId string) (*model.DirectoryEntry, error)
+	GetOwnProfile(ctx echo.Context, specialistId string) (*model.DirectoryEntry, error)
+	UpdateProfile(ctx echo.Context, specialistId string, profile model.Profile) (*model.DirectoryEntry, error)
+	SetPhoto(ctx echo.Context, specialistId string, photo *multipart.FileHeader) (*model.DirectoryEntry, error)
+	Search(ctx echo.Context, query model.DirectoryQuery) ([]model.DirectoryEntry, error)
+}
+
+type profileService struct {
+	profileRepository repository.ProfileRepository
+}
+
+func NewProfileService(profileRepo repository.ProfileRepository) ProfileService {
+	return &profileService{
+		profileRepository: profileRepo,
+	}
+}
+
+// GetProfile returns the profile of the health specialist as listed in the directory, unpublished
+// profiles read as not found.
+func (s *profileService) GetProfile(ctx echo.Context, specialistId string) (*model.DirectoryEntry, error) {
+	entry, err := s.GetOwnProfile(ctx, specialistId)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.Published {
+		return nil, errors.New("not_found")
+	}
+	return entry, nil
+}
+
+// GetOwnProfile returns the profile of the health specialist, whether it is published or not.
+func (s *profileService) GetOwnProfile(ctx echo.Context, specialistId string) (*model.DirectoryEntry, error) {
+	specialist, err := s.findSpecialist(ctx, specialistId)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.findProfile(ctx, specialistId)
+	if err != nil {
+		return nil, err
+	}
+	return toEntry(profile, specialist), nil
+}
+
+func (s *profileService) UpdateProfile(ctx echo.Context, specialistId string, profile model.Profile) (*model.DirectoryEntry, error) {
+	specialist, err := s.findSpecialist(ctx, specialistId)
+	if err != nil {
+		return nil, err
+	}
+
+	profile.AccountID = specialistId
+	profile.Normalize()
+	record, err := s.profileRepository.Upsert(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	return toEntry(record, specialist), nil
+}
+
+// SetPhoto replaces the photo of an existing profile.
+func (s *profileService) SetPhoto(ctx echo.Context, specialistId string, photo *multipart.FileHeader) (*model.DirectoryEntry, error) {
+	specialist, err := s.findSpecialist(ctx, specialistId)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.findProfile(ctx, specialistId)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.profileRepository.SetPhoto(ctx, profile, photo)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid_photo") {
+			return nil, errors.New("invalid_photo")
+		}
+		return nil, err
+	}
+	return toEntry(record, specialist), nil
+}
+
+// Search lists the published profiles matching the query, a page of DefaultDirectoryLimit entries
+// unless a limit is given, never more than MaxDirectoryLimit.
+func (s *profileService) Search(ctx echo.Context, query model.DirectoryQuery) ([]model.DirectoryEntry, error) {
+	query.Specialty = strings.ToLower(strings.TrimSpace(query.Specialty))
+	query.Language = strings.ToLower(strings.TrimSpace(query.Language))
+	query.Name = strings.TrimSpace(query.Name)
+	if query.Limit == 0 {
+		query.Limit = domain.DefaultDirectoryLimit
+	}
+	if query.Limit > domain.MaxDirectoryLimit {
+		query.Limit = domain.MaxDirectoryLimit
+	}
+
+	profiles, err := s.profileRepository.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(profiles) == 0 {
+		return []model.DirectoryEntry{}, nil
+	}
+
+	ids := make([]string, 0, len(profiles))
+	for _, profile := range profiles {
+		ids = append(ids, profile.GetString("account_id"))
+	}
+	specialists, err := s.profileRepository.FindSpecialists(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	specialistsById := make(map[string]*models.Record, len(specialists))
+	for _, specialist := range specialists {
+		specialistsById[specialist.Id] = specialist
+	}
+
+	entries := make([]model.DirectoryEntry, 0, len(profiles))
+	for _, profile := range profiles {
+		specialist, ok := specialistsById[profile.GetString("account_id")]
+		if !ok {
+			continue
+		}
+		entries = append(entries, *toEntry(profile, specialist))
+	}
+	return entries, nil
+}
+
+func (s *profileService) findSpecialist(ctx echo.Context, id string) (*models.Record, error) {
+	specialist, err := s.profileRepository.FindSpecialist(ctx, id)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+	return specialist, nil
+}
+
+func (s *profileService) findProfile(ctx echo.Context, specialistId string) (*models.Record, error) {
+	profile, err := s.profileRepository.FindByAccountId(ctx, specialistId)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+	return profile, nil
+}
+
+// toEntry builds the public view of the profile, named after the account of the health specialist.
+func toEntry(profile *models.Record, specialist *models.Record) *model.DirectoryEntry {
+	entry := &model.DirectoryEntry{
+		ID:               specialist.Id,
+		Name:             strings.TrimSpace(specialist.GetString("first_name") + " " + specialist.GetString("last_name")),
+		Bio:              profile.GetString("bio"),
+		Specialties:      jsonList(profile, "specialties"),
+		Languages:        jsonList(profile, "languages"),
+		Credentials:      jsonList(profile, "credentials"),
+		AppointmentTypes: jsonList(profile, "appointment_types"),
+		Published:        profile.GetBool("published"),
+	}
+	if photo := profile.GetString(domain.PhotoField); photo != "" {
+		entry.PhotoURL = "/api/files/" + profile.BaseFilesPath() + "/" + photo
+	}
+	return entry
+}
+
+func jsonList(record *models.Record, field string) []string {
+	values := []string{}
+	if err := record.UnmarshalJSONField(field, &values); err != nil || values == nil {
+		return []string{}
+	}
+	return values
+}
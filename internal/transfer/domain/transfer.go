@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+const (
+	// TableName stores the requests to transfer a patient from one health specialist to another.
+	TableName = "patient_transfers"
+
+	PendingStatus   = "pending"
+	AcceptedStatus  = "accepted"
+	DeclinedStatus  = "declined"
+	CancelledStatus = "cancelled"
+
+	// TransferRequestDuration is how long the patient has to consent to a transfer.
+	TransferRequestDuration = 7 * 24 * time.Hour
+)
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	eventModel "github.com/arosace/WellnessWaveApi/internal/event/model"
+	"github.com/arosace/WellnessWaveApi/internal/transfer/model"
+	"github.com/arosace/WellnessWaveApi/internal/transfer/service"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+// TransferHandler handles HTTP requests to detach and transfer patients.
+type TransferHandler struct {
+	transferService service.TransferService
+}
+
+func NewTransferHandler(transferService service.TransferService) *TransferHandler {
+	return &TransferHandler{transferService: transferService}
+}
+
+// HandleDetach removes the specialist given by specialistId from the care team of the patient,
+// health specialists leave it out to detach themselves.
+func (h *TransferHandler) HandleDetach(ctx echo.Context) error {
+	specialistId := ctx.QueryParam("specialistId")
+	if specialistId == "" && utils.GetAuthRole(ctx) == accountDomain.HealthSpecialistRole {
+		specialistId = utils.GetAuthAccountId(ctx)
+	}
+	if specialistId == "" {
+		return apis.NewBadRequestError("missing_data: specialistId", nil)
+	}
+
+	if err := h.transferService.Detach(ctx, ctx.PathParam("id"), specialistId); err != nil {
+		return transferError(err, "Failed to detach account")
+	}
+	return ctx.NoContent(http.StatusOK)
+}
+
+func (h *TransferHandler) HandleRequestTransfer(ctx echo.Context) error {
+	res := model.TransferResponse{}
+	var body model.TransferBody
+
+	if err := ctx.Bind(&body); err != nil {
+		return apis.NewBadRequestError("wrong_data_type", nil)
+	}
+	if err := body.ValidateModel(); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	transfer, err := h.transferService.RequestTransfer(ctx, ctx.PathParam("id"), utils.GetAuthAccountId(ctx), body)
+	if err != nil {
+		return transferError(err, "Failed to request transfer")
+	}
+
+	res.Data = transfer
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *TransferHandler) HandleGetPendingTransfers(ctx echo.Context) error {
+	res := model.TransferResponse{}
+
+	transfers, err := h.transferService.GetPendingTransfers(ctx, ctx.PathParam("id"))
+	if err != nil {
+		res.Error = fmt.Sprintf("Failed to get pending transfers: %v", err)
+		return apis.NewApiError(http.StatusInternalServerError, res.Error, nil)
+	}
+
+	res.Data = transfers
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *TransferHandler) HandleAcceptTransfer(ctx echo.Context) error {
+	res := model.TransferResponse{}
+
+	transfer, err := h.transferService.AcceptTransfer(ctx, ctx.PathParam("id"), ctx.PathParam("transferId"))
+	if err != nil {
+		// the events handed over would overlap others, the transfer waits for them to be rescheduled
+		var conflict *eventModel.ConflictError
+		if errors.As(err, &conflict) {
+			res.Data = conflict.Conflicts
+			res.Error = conflict.Error()
+			return ctx.JSON(http.StatusConflict, res)
+		}
+		return transferError(err, "Failed to accept transfer")
+	}
+
+	res.Data = transfer
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h *TransferHandler) HandleDeclineTransfer(ctx echo.Context) error {
+	res := model.TransferResponse{}
+
+	transfer, err := h.transferService.DeclineTransfer(ctx, ctx.PathParam("id"), ctx.PathParam("transferId"))
+	if err != nil {
+		return transferError(err, "Failed to decline transfer")
+	}
+
+	res.Data = transfer
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// transferError maps the errors of the transfer service to their status code.
+func transferError(err error, message string) error {
+	switch err.Error() {
+	case "not_found":
+		return apis.NewNotFoundError(err.Error(), nil)
+	case "invalid_specialist":
+		return apis.NewBadRequestError(err.Error(), nil)
+	case "not_attached", "already_member", "cross_organisation_transfer", "transfer_not_pending", "transfer_outdated":
+		return apis.NewApiError(http.StatusConflict, err.Error(), nil)
+	case "transfer_expired":
+		return apis.NewApiError(http.StatusGone, err.Error(), nil)
+	}
+	return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("%s: %v", message, err), nil)
+}
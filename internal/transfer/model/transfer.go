@@ -0,0 +1,42 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PatientTransfer is the request of a health specialist to hand one of their patients over to another,
+// which only takes effect once the patient consents to it.
+type PatientTransfer struct {
+	ID               string `json:"id,omitempty"`
+	PatientID        string `json:"patient_id"`
+	FromSpecialistID string `json:"from_specialist_id"`
+	ToSpecialistID   string `json:"to_specialist_id"`
+	// ReassignEvents moves the upcoming events of the patient with the current specialist to the new one.
+	ReassignEvents bool `json:"reassign_events"`
+	// ReassignPlans moves the meal and exercise plans of the patient made by the current specialist to the new one.
+	ReassignPlans bool   `json:"reassign_plans"`
+	Status        string `json:"status"`
+	ExpiresAt     string `json:"expires_at"`
+	DecidedAt     string `json:"decided_at"`
+}
+
+type TransferBody struct {
+	ToSpecialistID string `json:"to_specialist_id"`
+	ReassignEvents bool   `json:"reassign_events"`
+	ReassignPlans  bool   `json:"reassign_plans"`
+}
+
+func (m *TransferBody) ValidateModel() error {
+	var missingData []string
+
+	if m.ToSpecialistID == "" {
+		missingData = append(missingData, "to_specialist_id")
+	}
+
+	if len(missingData) > 0 {
+		return fmt.Errorf("missing_data: %s", strings.Join(missingData, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,6 @@
+package model
+
+type TransferResponse struct {
+	Data  interface{} `json:"data"`
+	Error string      `json:"error_message"`
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	eventModel "github.com/arosace/WellnessWaveApi/internal/event/model"
+	eventRepository "github.com/arosace/WellnessWaveApi/internal/event/repository"
+	plannerDomain "github.com/arosace/WellnessWaveApi/internal/planner/domain"
+	"github.com/arosace/WellnessWaveApi/internal/transfer/domain"
+	"github.com/arosace/WellnessWaveApi/internal/transfer/model"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// TransferRepository defines the interface for patient transfer data access.
+type TransferRepository interface {
+	Add(echo.Context, model.PatientTransfer) (*models.Record, error)
+	FindByID(echo.Context, string) (*models.Record, error)
+	FindPendingByPatientId(echo.Context, string) ([]*models.Record, error)
+	Update(echo.Context, *models.Record) (*models.Record, error)
+	CancelPending(echo.Context, string, string) error
+	Complete(echo.Context, *models.Record, string) error
+}
+
+type TransferRepo struct {
+	Dao       *daos.Dao
+	Encryptor utils.Encryption
+}
+
+func NewTransferRepository(dao *daos.Dao, encryptor utils.Encryption) *TransferRepo {
+	return &TransferRepo{
+		Dao:       dao,
+		Encryptor: encryptor,
+	}
+}
+
+func (r *TransferRepo) Add(ctx echo.Context, transfer model.PatientTransfer) (*models.Record, error) {
+	collection, err := r.Dao.FindCollectionByNameOrId(domain.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.NewRecord(collection)
+	utils.LoadFromStruct(record, &transfer)
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("Failed to save patient transfer: %w", err)
+	}
+
+	return record, nil
+}
+
+func (r *TransferRepo) FindByID(ctx echo.Context, id string) (*models.Record, error) {
+	record, err := r.Dao.FindRecordById(domain.TableName, id)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving patient transfer [%s]: %w", id, err)
+	}
+	return record, nil
+}
+
+func (r *TransferRepo) FindPendingByPatientId(ctx echo.Context, patientId string) ([]*models.Record, error) {
+	records, err := r.Dao.FindRecordsByFilter(
+		domain.TableName,
+		"patient_id = {:patient_id} && status = {:status}",
+		"-created",
+		-1,
+		0,
+		dbx.Params{"patient_id": patientId, "status": domain.PendingStatus},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("there was an error retrieving pending transfers of patient [%s]: %w", patientId, err)
+	}
+	return records, nil
+}
+
+func (r *TransferRepo) Update(ctx echo.Context, record *models.Record) (*models.Record, error) {
+	record.MarkAsNotNew()
+	if err := r.Dao.SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("there was an error updating patient transfer: %w", err)
+	}
+	return record, nil
+}
+
+// CancelPending cancels the transfers requested by the specialist that are still waiting for the consent of the patient.
+func (r *TransferRepo) CancelPending(ctx echo.Context, patientId string, fromId string) error {
+	_, err := r.Dao.DB().Update(
+		domain.TableName,
+		dbx.Params{"status": domain.CancelledStatus},
+		dbx.HashExp{"patient_id": patientId, "from_specialist_id": fromId, "status": domain.PendingStatus},
+	).Execute()
+	if err != nil {
+		return fmt.Errorf("there was an error cancelling pending transfers of patient [%s]: %w", patientId, err)
+	}
+	return nil
+}
+
+// Complete hands the place of the previous specialist in the care team of the patient over to the new one and,
+// as requested by the transfer, the events after the given date and the plans of the previous specialist too,
+// in a single transaction. Nothing is saved if one of the events would overlap another event of the new
+// specialist or of the patient, Complete then fails with a ConflictError listing every overlap.
+func (r *TransferRepo) Complete(ctx echo.Context, transfer *models.Record, after string) error {
+	patientId := transfer.GetString("patient_id")
+	fromId := transfer.GetString("from_specialist_id")
+	toId := transfer.GetString("to_specialist_id")
+
+	return r.Dao.RunInTransaction(func(txDao *daos.Dao) error {
+		member, err := txDao.FindFirstRecordByFilter(
+			accountDomain.CareTeamTableName,
+			"patient_id = {:patient_id} && specialist_id = {:specialist_id}",
+			dbx.Params{"patient_id": patientId, "specialist_id": fromId},
+		)
+		if err != nil {
+			return err
+		}
+		member.Set("specialist_id", toId)
+		if err := txDao.SaveRecord(member); err != nil {
+			return fmt.Errorf("there was an error updating the care team of patient [%s]: %w", patientId, err)
+		}
+
+		if transfer.GetBool("reassign_events") {
+			events := eventRepository.NewEventRepository(txDao, r.Encryptor)
+			if err := reassignEvents(ctx, events, patientId, fromId, toId, after); err != nil {
+				return err
+			}
+		}
+
+		if transfer.GetBool("reassign_plans") {
+			if err := reassignPlans(txDao, plannerDomain.PLANS_TABLENAME, plannerDomain.DAILY_PLANS_TABLENAME, patientId, fromId, toId); err != nil {
+				return err
+			}
+			if err := reassignPlans(txDao, plannerDomain.EXERCISE_PLAN_TABLENAME, plannerDomain.DAILY_EXERCISE_PLANS_TABLENAME, patientId, fromId, toId); err != nil {
+				return err
+			}
+		}
+
+		transfer.MarkAsNotNew()
+		return txDao.SaveRecord(transfer)
+	})
+}
+
+// reassignEvents hands the events of the patient with one specialist taking place after the given date over to
+// another. Recurring events are split at their first occurrence after it, so that the occurrences that already
+// took place stay with the previous specialist. Every event is checked for overlaps before it is saved, the
+// overlaps of all of them being returned as a single ConflictError.
+func reassignEvents(ctx echo.Context, events eventRepository.EventRepository, patientId string, fromId string, toId string, after string) error {
+	from, err := types.ParseDateTime(after)
+	if err != nil {
+		return err
+	}
+	records, err := events.GetByPatientId(ctx, patientId, after)
+	if err != nil {
+		return fmt.Errorf("there was an error fetching the events of patient [%s] to reassign: %w", patientId, err)
+	}
+
+	conflicts := []eventModel.Conflict{}
+	for _, record := range records {
+		if record.GetString("health_specialist_id") != fromId {
+			continue
+		}
+		if record.GetString("rrule") != "" {
+			rule, err := utils.ParseRRule(record.GetString("rrule"))
+			if err != nil {
+				return err
+			}
+			next, found := rule.Next(record.GetDateTime("event_date").Time(), from.Time())
+			if !found {
+				continue
+			}
+			if record, err = events.SplitSeries(ctx, record, next); err != nil {
+				return err
+			}
+		}
+
+		record.Set("health_specialist_id", toId)
+		if _, err := events.Update(ctx, record, false); err != nil {
+			var conflict *eventModel.ConflictError
+			if !errors.As(err, &conflict) {
+				return err
+			}
+			conflicts = append(conflicts, conflict.Conflicts...)
+		}
+	}
+	if len(conflicts) > 0 {
+		return &eventModel.ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// reassignPlans hands the plans of the patient made by one specialist, and their daily plans, over to another.
+// The meals and exercises the plans are made of stay in the library of the specialist who created them.
+func reassignPlans(dao *daos.Dao, plansTable string, dailyPlansTable string, patientId string, fromId string, toId string) error {
+	var planIds []string
+	err := dao.DB().
+		Select("id").
+		From(plansTable).
+		Where(dbx.HashExp{"patient_id": patientId, "health_specialist_id": fromId}).
+		Column(&planIds)
+	if err != nil {
+		return fmt.Errorf("there was an error fetching the plans to reassign from %s: %w", plansTable, err)
+	}
+	if len(planIds) == 0 {
+		return nil
+	}
+
+	ids := make([]interface{}, len(planIds))
+	for i, id := range planIds {
+		ids[i] = id
+	}
+	if _, err := dao.DB().Update(dailyPlansTable, dbx.Params{"health_specialist_id": toId}, dbx.In("plan_id", ids...)).Execute(); err != nil {
+		return fmt.Errorf("there was an error reassigning %s: %w", dailyPlansTable, err)
+	}
+	if _, err := dao.DB().Update(plansTable, dbx.Params{"health_specialist_id": toId}, dbx.In("id", ids...)).Execute(); err != nil {
+		return fmt.Errorf("there was an error reassigning %s: %w", plansTable, err)
+	}
+	return nil
+}
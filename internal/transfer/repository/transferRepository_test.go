@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	eventDomain "github.com/arosace/WellnessWaveApi/internal/event/domain"
+	eventModel "github.com/arosace/WellnessWaveApi/internal/event/model"
+	"github.com/arosace/WellnessWaveApi/internal/transfer/domain"
+	"github.com/arosace/WellnessWaveApi/pkg/testutils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTransfer(t *testing.T) (*daos.Dao, *models.Collection, *models.Record) {
+	dao := testutils.NewTestApp(t).Dao()
+
+	careTeam := testutils.NewCollection(t, dao, accountDomain.CareTeamTableName, testutils.TextFields("patient_id", "specialist_id")...)
+	events := testutils.NewCollection(t, dao, eventDomain.TABLENAME, testutils.EventFields()...)
+	transfers := testutils.NewCollection(t, dao, domain.TableName, append(
+		testutils.TextFields("patient_id", "from_specialist_id", "to_specialist_id", "status"),
+		testutils.BoolField("reassign_events"),
+		testutils.BoolField("reassign_plans"),
+	)...)
+
+	testutils.NewRecord(t, dao, careTeam, map[string]any{"patient_id": "patient", "specialist_id": "from"})
+	transfer := testutils.NewRecord(t, dao, transfers, map[string]any{"patient_id": "patient", "from_specialist_id": "from", "to_specialist_id": "to",
+		"status": domain.PendingStatus, "reassign_events": true})
+	transfer.Set("status", domain.AcceptedStatus)
+	return dao, events, transfer
+}
+
+func newTestEvent(t *testing.T, dao *daos.Dao, events *models.Collection, specialistId string, patientId string, start string, end string, rrule string) *models.Record {
+	return testutils.NewRecord(t, dao, events, map[string]any{"health_specialist_id": specialistId, "patient_id": patientId,
+		"event_date": start, "end_date": end, "duration_minutes": 60, "rrule": rrule})
+}
+
+func TestCompleteReassignsUpcomingEvents(t *testing.T) {
+	dao, events, transfer := newTestTransfer(t)
+	past := newTestEvent(t, dao, events, "from", "patient", "2020-03-02 09:00:00", "2020-03-02 10:00:00", "")
+	upcoming := newTestEvent(t, dao, events, "from", "patient", "2030-03-02 09:00:00", "2030-03-02 10:00:00", "")
+	// Monday 2 March 2020, the first occurrence after the transfer is on Monday 6 January 2025
+	series := newTestEvent(t, dao, events, "from", "patient", "2020-03-02 09:00:00", "2020-03-02 10:00:00", "FREQ=WEEKLY")
+
+	repository := NewTransferRepository(dao, testutils.NewEncryptor(t))
+	assert.Nil(t, repository.Complete(nil, transfer, "2025-01-01 00:00:00.000Z"))
+
+	find := func(event *models.Record) *models.Record {
+		record, err := dao.FindRecordById(eventDomain.TABLENAME, event.Id)
+		assert.Nil(t, err)
+		return record
+	}
+	assert.Equal(t, "from", find(past).GetString("health_specialist_id"), "events that already took place stay with the previous specialist")
+	assert.Equal(t, "to", find(upcoming).GetString("health_specialist_id"))
+
+	previous := find(series)
+	assert.Equal(t, "from", previous.GetString("health_specialist_id"), "the occurrences that already took place stay with the previous specialist")
+	assert.Equal(t, "FREQ=WEEKLY;UNTIL=20250106T085959Z", previous.GetString("rrule"))
+	following, err := dao.FindFirstRecordByFilter(eventDomain.TABLENAME, "series_id = {:series_id}", dbx.Params{"series_id": series.Id})
+	assert.Nil(t, err)
+	assert.Equal(t, "to", following.GetString("health_specialist_id"), "the following occurrences move to the new specialist")
+	assert.Equal(t, "2025-01-06 09:00:00.000Z", following.GetString("event_date"))
+	assert.Equal(t, "FREQ=WEEKLY", following.GetString("rrule"))
+
+	member, err := dao.FindFirstRecordByData(accountDomain.CareTeamTableName, "patient_id", "patient")
+	assert.Nil(t, err)
+	assert.Equal(t, "to", member.GetString("specialist_id"))
+}
+
+func TestCompleteReportsConflicts(t *testing.T) {
+	dao, events, transfer := newTestTransfer(t)
+	upcoming := newTestEvent(t, dao, events, "from", "patient", "2030-03-02 09:00:00", "2030-03-02 10:00:00", "")
+	series := newTestEvent(t, dao, events, "from", "patient", "2020-03-02 09:00:00", "2020-03-02 10:00:00", "FREQ=WEEKLY")
+	busy := newTestEvent(t, dao, events, "to", "another patient", "2030-03-02 09:30:00", "2030-03-02 10:30:00", "")
+
+	repository := NewTransferRepository(dao, testutils.NewEncryptor(t))
+	err := repository.Complete(nil, transfer, "2025-01-01 00:00:00.000Z")
+	var conflict *eventModel.ConflictError
+	if assert.True(t, errors.As(err, &conflict)) {
+		assert.Len(t, conflict.Conflicts, 1)
+		assert.Equal(t, busy.Id, conflict.Conflicts[0].EventID)
+		assert.Equal(t, "health_specialist", conflict.Conflicts[0].Participant)
+	}
+
+	for _, event := range []*models.Record{upcoming, series} {
+		record, err := dao.FindRecordById(eventDomain.TABLENAME, event.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, "from", record.GetString("health_specialist_id"), "nothing is reassigned")
+		assert.Equal(t, event.GetString("rrule"), record.GetString("rrule"), "nor split")
+	}
+	member, err := dao.FindFirstRecordByData(accountDomain.CareTeamTableName, "patient_id", "patient")
+	assert.Nil(t, err)
+	assert.Equal(t, "from", member.GetString("specialist_id"))
+	stored, err := dao.FindRecordById(domain.TableName, transfer.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.PendingStatus, stored.GetString("status"), "the transfer waits for the conflicts to be resolved")
+}
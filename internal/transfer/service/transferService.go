@@ -0,0 +1,315 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	accountRepository "github.com/arosace/WellnessWaveApi/internal/account/repository"
+	"github.com/arosace/WellnessWaveApi/internal/transfer/domain"
+	"github.com/arosace/WellnessWaveApi/internal/transfer/model"
+	"github.com/arosace/WellnessWaveApi/internal/transfer/repository"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// TransferService moves patients between health specialists, with the consent of the patient.
+type TransferService interface {
+	Detach(ctx echo.Context, patientId string, specialistId string) error
+	RequestTransfer(ctx echo.Context, patientId string, fromId string, body model.TransferBody) (*models.Record, error)
+	GetPendingTransfers(ctx echo.Context, patientId string) ([]*models.Record, error)
+	AcceptTransfer(ctx echo.Context, patientId string, transferId string) (*models.Record, error)
+	DeclineTransfer(ctx echo.Context, patientId string, transferId string) (*models.Record, error)
+}
+
+type transferService struct {
+	accountRepository  accountRepository.AccountRepository
+	careTeamRepository accountRepository.CareTeamRepository
+	transferRepository repository.TransferRepository
+	mailer             mailer.Mailer
+}
+
+func NewTransferService(
+	accountRepo accountRepository.AccountRepository,
+	careTeamRepo accountRepository.CareTeamRepository,
+	transferRepo repository.TransferRepository,
+	mailClient mailer.Mailer,
+) TransferService {
+	return &transferService{
+		accountRepository:  accountRepo,
+		careTeamRepository: careTeamRepo,
+		transferRepository: transferRepo,
+		mailer:             mailClient,
+	}
+}
+
+// Detach removes the health specialist from the care team of the patient, cancelling the transfer they requested
+// if any. The events and plans they share are left untouched.
+func (s *transferService) Detach(ctx echo.Context, patientId string, specialistId string) error {
+	patient, err := s.findAccount(ctx, patientId)
+	if err != nil {
+		return err
+	}
+	member, err := s.findMember(ctx, patientId, specialistId)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return errors.New("not_attached")
+	}
+
+	if err := s.careTeamRepository.Remove(ctx, member); err != nil {
+		return err
+	}
+	if err := s.transferRepository.CancelPending(ctx, patientId, specialistId); err != nil {
+		return err
+	}
+
+	specialist, err := s.findAccount(ctx, specialistId)
+	if err != nil {
+		log.Printf("Failed to notify the detachment of patient %s: %v", patientId, err)
+		return nil
+	}
+	for _, account := range []*models.Record{patient, specialist} {
+		if err := utils.SendPatientDetachedEmail(s.mailer, account.GetString("username"), account.Email(), patient.GetString("username"), specialist.GetString("username")); err != nil {
+			log.Printf("Failed to notify the detachment of patient %s to account %s: %v", patientId, account.Id, err)
+		}
+	}
+	return nil
+}
+
+// RequestTransfer asks the patient to consent to another health specialist taking the place of the
+// requesting one in their care team. A new request replaces the one still pending, if any.
+func (s *transferService) RequestTransfer(ctx echo.Context, patientId string, fromId string, body model.TransferBody) (*models.Record, error) {
+	patient, err := s.findAccount(ctx, patientId)
+	if err != nil {
+		return nil, err
+	}
+	from, err := s.findAccount(ctx, fromId)
+	if err != nil {
+		return nil, err
+	}
+	if body.ToSpecialistID == fromId {
+		return nil, errors.New("invalid_specialist")
+	}
+	to, err := s.findSpecialist(patient, body.ToSpecialistID)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.findMember(ctx, patientId, to.Id)
+	if err != nil {
+		return nil, err
+	}
+	if member != nil {
+		return nil, errors.New("already_member")
+	}
+
+	expiresAt, err := types.ParseDateTime(time.Now().Add(domain.TransferRequestDuration))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.transferRepository.CancelPending(ctx, patientId, fromId); err != nil {
+		return nil, err
+	}
+	transfer, err := s.transferRepository.Add(ctx, model.PatientTransfer{
+		PatientID:        patientId,
+		FromSpecialistID: fromId,
+		ToSpecialistID:   to.Id,
+		ReassignEvents:   body.ReassignEvents,
+		ReassignPlans:    body.ReassignPlans,
+		Status:           domain.PendingStatus,
+		ExpiresAt:        expiresAt.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := utils.SendTransferRequestEmail(s.mailer, patient.GetString("username"), patient.Email(), from.GetString("username"), to.GetString("username"), transfer.Id); err != nil {
+		return nil, errors.New("Failed to send email: " + err.Error())
+	}
+	return transfer, nil
+}
+
+// GetPendingTransfers returns the transfers still waiting for the consent of the patient.
+func (s *transferService) GetPendingTransfers(ctx echo.Context, patientId string) ([]*models.Record, error) {
+	transfers, err := s.transferRepository.FindPendingByPatientId(ctx, patientId)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []*models.Record{}
+	for _, transfer := range transfers {
+		if !isExpired(transfer) {
+			pending = append(pending, transfer)
+		}
+	}
+	return pending, nil
+}
+
+// AcceptTransfer replaces the previous health specialist with the new one in the care team of the patient,
+// handing the upcoming events and the plans over to them if requested, and lets the patient and both specialists know.
+func (s *transferService) AcceptTransfer(ctx echo.Context, patientId string, transferId string) (*models.Record, error) {
+	transfer, err := s.findPendingTransfer(ctx, patientId, transferId)
+	if err != nil {
+		return nil, err
+	}
+	patient, err := s.findAccount(ctx, patientId)
+	if err != nil {
+		return nil, err
+	}
+	// the care team may have changed since the request was made
+	outdated, err := s.isOutdated(ctx, transfer)
+	if err != nil {
+		return nil, err
+	}
+	if outdated {
+		transfer.Set("status", domain.CancelledStatus)
+		if _, err := s.transferRepository.Update(ctx, transfer); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("transfer_outdated")
+	}
+	from, err := s.findAccount(ctx, transfer.GetString("from_specialist_id"))
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.findSpecialist(patient, transfer.GetString("to_specialist_id"))
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := types.ParseDateTime(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	transfer.Set("status", domain.AcceptedStatus)
+	transfer.Set("decided_at", now.String())
+	if err := s.transferRepository.Complete(ctx, transfer, now.String()); err != nil {
+		return nil, err
+	}
+
+	for _, account := range []*models.Record{patient, from, to} {
+		if err := utils.SendPatientTransferredEmail(s.mailer, account.GetString("username"), account.Email(), patient.GetString("username"), from.GetString("username"), to.GetString("username")); err != nil {
+			log.Printf("Failed to notify transfer %s to account %s: %v", transfer.Id, account.Id, err)
+		}
+	}
+	return transfer, nil
+}
+
+// DeclineTransfer refuses the transfer, the patient stays with their health specialist who is told so.
+func (s *transferService) DeclineTransfer(ctx echo.Context, patientId string, transferId string) (*models.Record, error) {
+	transfer, err := s.findPendingTransfer(ctx, patientId, transferId)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := types.ParseDateTime(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	transfer.Set("status", domain.DeclinedStatus)
+	transfer.Set("decided_at", now.String())
+	if _, err := s.transferRepository.Update(ctx, transfer); err != nil {
+		return nil, err
+	}
+
+	patient, err := s.findAccount(ctx, patientId)
+	if err != nil {
+		return nil, err
+	}
+	from, err := s.findAccount(ctx, transfer.GetString("from_specialist_id"))
+	if err == nil {
+		err = utils.SendTransferDeclinedEmail(s.mailer, from.GetString("username"), from.Email(), patient.GetString("username"))
+	}
+	if err != nil {
+		log.Printf("Failed to notify the decline of transfer %s: %v", transfer.Id, err)
+	}
+	return transfer, nil
+}
+
+// findPendingTransfer returns the transfer of the patient if it still waits for their consent.
+func (s *transferService) findPendingTransfer(ctx echo.Context, patientId string, transferId string) (*models.Record, error) {
+	transfer, err := s.transferRepository.FindByID(ctx, transferId)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+	if transfer.GetString("patient_id") != patientId {
+		return nil, errors.New("not_found")
+	}
+	if transfer.GetString("status") != domain.PendingStatus {
+		return nil, errors.New("transfer_not_pending")
+	}
+	if isExpired(transfer) {
+		return nil, errors.New("transfer_expired")
+	}
+	return transfer, nil
+}
+
+// isOutdated reports whether the previous specialist left the care team of the patient or the new one joined it.
+func (s *transferService) isOutdated(ctx echo.Context, transfer *models.Record) (bool, error) {
+	patientId := transfer.GetString("patient_id")
+	from, err := s.findMember(ctx, patientId, transfer.GetString("from_specialist_id"))
+	if err != nil {
+		return false, err
+	}
+	to, err := s.findMember(ctx, patientId, transfer.GetString("to_specialist_id"))
+	if err != nil {
+		return false, err
+	}
+	return from == nil || to != nil, nil
+}
+
+// findMember returns the membership of the specialist in the care team of the patient, or nil if they are not part of it.
+func (s *transferService) findMember(ctx echo.Context, patientId string, specialistId string) (*models.Record, error) {
+	member, err := s.careTeamRepository.FindMember(ctx, patientId, specialistId)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return member, nil
+}
+
+func (s *transferService) findAccount(ctx echo.Context, id string) (*models.Record, error) {
+	account, err := s.accountRepository.FindByID(ctx, id)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+	return account, nil
+}
+
+// findSpecialist returns the account if it is a health specialist of the tenant of the patient that can take
+// them on. Specialists outside of any organisation are not related to the patient yet, so they are looked up
+// whichever tenant they belong to before checking it. Transfers do not move the patient, their events and
+// plans to another organisation, so specialists of another organisation than the patient are refused.
+func (s *transferService) findSpecialist(patient *models.Record, id string) (*models.Record, error) {
+	account, err := s.accountRepository.FindByID(nil, id)
+	if err != nil {
+		if utils.IsErrorNotFound(err) {
+			return nil, errors.New("invalid_specialist")
+		}
+		return nil, err
+	}
+	if account.GetString("role") != accountDomain.HealthSpecialistRole || !account.GetDateTime("deleted_at").IsZero() {
+		return nil, errors.New("invalid_specialist")
+	}
+	if account.GetString(utils.TenantField) != patient.GetString(utils.TenantField) {
+		return nil, errors.New("cross_organisation_transfer")
+	}
+	return account, nil
+}
+
+func isExpired(transfer *models.Record) bool {
+	return transfer.GetDateTime("expires_at").Time().Before(time.Now())
+}
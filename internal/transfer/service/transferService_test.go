@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	accountDomain "github.com/arosace/WellnessWaveApi/internal/account/domain"
+	accountRepository "github.com/arosace/WellnessWaveApi/internal/account/repository"
+	"github.com/arosace/WellnessWaveApi/pkg/testutils"
+	"github.com/arosace/WellnessWaveApi/pkg/utils"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSpecialistOfAnotherOrganisation(t *testing.T) {
+	dao := testutils.NewTestApp(t).Dao()
+
+	accounts := testutils.NewCollection(t, dao, accountDomain.TableName,
+		append(testutils.TextFields("role", utils.TenantField), testutils.DateField("deleted_at"))...)
+	account := func(role string, organisationId string) *models.Record {
+		return testutils.NewRecord(t, dao, accounts, map[string]any{"role": role, utils.TenantField: organisationId})
+	}
+	patient := account(accountDomain.PatientRole, "clinic")
+	colleague := account(accountDomain.HealthSpecialistRole, "clinic")
+	outsider := account(accountDomain.HealthSpecialistRole, "")
+	other := account(accountDomain.HealthSpecialistRole, "hospital")
+
+	service := &transferService{accountRepository: accountRepository.NewAccountRepository(dao, testutils.NewEncryptor(t))}
+
+	found, err := service.findSpecialist(patient, colleague.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, colleague.Id, found.Id)
+
+	for _, specialist := range []*models.Record{outsider, other} {
+		_, err = service.findSpecialist(patient, specialist.Id)
+		assert.EqualError(t, err, "cross_organisation_transfer", "the patient would stay in their organisation")
+	}
+	_, err = service.findSpecialist(patient, patient.Id)
+	assert.EqualError(t, err, "invalid_specialist")
+}
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// aeadVersion is the first byte of every AES-GCM ciphertext, a new format gets a new version.
+const aeadVersion byte = 0x02
+
+// aeadKeySeparator separates the key id from the versioned ciphertext. Legacy CBC ciphertexts
+// use ':' or no prefix at all, neither of which can be confused with it.
+const aeadKeySeparator = "$"
+
+// macKeyLabel derives the keys of MACs from the secrets of the keyring, so that no key both encrypts and authenticates.
+const macKeyLabel = "wellnesswave mac key"
+
+var errDecryptionFailed = errors.New("decryption failed")
+
+// AEADEncryptor encrypts with AES-GCM under the active key of the keyring. Ciphertexts are written as
+// <key id>$<base64(version | nonce | sealed text)>, and the version, key id and associated data are
+// authenticated, so any tampering makes decryption fail. Legacy CBC ciphertexts are still decrypted.
+type AEADEncryptor struct {
+	Keys   *Keyring
+	legacy *Encryptor
+}
+
+func NewAEADEncryptor(keys *Keyring) *AEADEncryptor {
+	return &AEADEncryptor{
+		Keys:   keys,
+		legacy: NewKeyringEncryptor(keys),
+	}
+}
+
+func (e *AEADEncryptor) Encrypt(plainText string) (string, error) {
+	return e.EncryptWithAssociatedData(plainText, "")
+}
+
+func (e *AEADEncryptor) Decrypt(encryptedText string) (string, error) {
+	return e.DecryptWithAssociatedData(encryptedText, "")
+}
+
+// EncryptWithAssociatedData encrypts the plain text, the same associated data must be given to decrypt it.
+func (e *AEADEncryptor) EncryptWithAssociatedData(plainText string, associatedData string) (string, error) {
+	keyId, key := e.Keys.Active()
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	blob := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(plainText)+aead.Overhead())
+	blob[0] = aeadVersion
+	nonce := blob[1:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	blob = aead.Seal(blob, nonce, []byte(plainText), aeadAdditionalData(keyId, associatedData))
+
+	return keyId + aeadKeySeparator + base64.StdEncoding.EncodeToString(blob), nil
+}
+
+// DecryptWithAssociatedData decrypts the ciphertext, every failure is reported with the same error.
+// Legacy CBC ciphertexts carry no associated data, it is ignored for them.
+func (e *AEADEncryptor) DecryptWithAssociatedData(encryptedText string, associatedData string) (string, error) {
+	keyId, payload, found := strings.Cut(encryptedText, aeadKeySeparator)
+	if !found {
+		plainText, err := e.legacy.Decrypt(encryptedText)
+		if err != nil {
+			return "", errDecryptionFailed
+		}
+		return plainText, nil
+	}
+
+	key, err := e.Keys.Key(keyId)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil || len(blob) < 1+aead.NonceSize()+aead.Overhead() || blob[0] != aeadVersion {
+		return "", errDecryptionFailed
+	}
+	nonce := blob[1 : 1+aead.NonceSize()]
+	plainText, err := aead.Open(nil, nonce, blob[1+aead.NonceSize():], aeadAdditionalData(keyId, associatedData))
+	if err != nil {
+		return "", errDecryptionFailed
+	}
+	return string(plainText), nil
+}
+
+func (e *AEADEncryptor) HashSHA256(text string, context string) string {
+	return hashSHA256(text, context)
+}
+
+// MAC authenticates the text for the context, e.g. the purpose and account of a recovery code, with the active key.
+// Unlike HashSHA256 it cannot be brute forced without the key, so it suits secrets too short to be hashed alone.
+// It is written as <key id>$<hex(hmac)>.
+func (e *AEADEncryptor) MAC(text string, context string) string {
+	keyId, key := e.Keys.Active()
+	return keyId + aeadKeySeparator + hex.EncodeToString(computeMAC(key, text, context))
+}
+
+// VerifyMAC reports whether the mac was computed for the text and context, with any key of the keyring.
+func (e *AEADEncryptor) VerifyMAC(text string, context string, mac string) bool {
+	keyId, sum, found := strings.Cut(mac, aeadKeySeparator)
+	if !found {
+		return false
+	}
+	key, err := e.Keys.Key(keyId)
+	if err != nil {
+		return false
+	}
+	expected, err := hex.DecodeString(sum)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, computeMAC(key, text, context))
+}
+
+func computeMAC(secret []byte, text string, context string) []byte {
+	derivation := hmac.New(sha256.New, secret)
+	derivation.Write([]byte(macKeyLabel))
+	mac := hmac.New(sha256.New, derivation.Sum(nil))
+	// the length of the context keeps it from running into the text
+	fmt.Fprintf(mac, "%d:%s", len(context), context)
+	mac.Write([]byte(text))
+	return mac.Sum(nil)
+}
+
+// NeedsReencrypt reports whether the ciphertext is a legacy one or was written with a retired key.
+func (e *AEADEncryptor) NeedsReencrypt(encryptedText string) bool {
+	keyId, _, found := strings.Cut(encryptedText, aeadKeySeparator)
+	return !found || keyId != e.Keys.ActiveID()
+}
+
+// IsEncrypted reports whether the value has the shape of a ciphertext written by AEADEncryptor.
+// It tells encrypted values apart from plain text stored before a field was encrypted.
+func (e *AEADEncryptor) IsEncrypted(value string) bool {
+	keyId, payload, found := strings.Cut(value, aeadKeySeparator)
+	if !found || keyId == "" || strings.ContainsAny(keyId, ":, ") {
+		return false
+	}
+	blob, err := base64.StdEncoding.DecodeString(payload)
+	return err == nil && len(blob) > 1 && blob[0] == aeadVersion
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// aeadAdditionalData authenticates the version and key id of the ciphertext along with the caller's data.
+func aeadAdditionalData(keyId string, associatedData string) []byte {
+	data := make([]byte, 0, 2+len(keyId)+len(associatedData))
+	data = append(data, aeadVersion)
+	data = append(data, keyId...)
+	data = append(data, 0)
+	return append(data, associatedData...)
+}
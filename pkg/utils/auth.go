@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"time"
@@ -8,11 +10,41 @@ import (
 	"github.com/dgrijalva/jwt-go"
 )
 
-var jwtSecret = []byte("your_secret_key")
+// jwtKeys signs and verifies every token issued by the api, it is set from the configuration at boot.
+// Tokens are signed with the active key and carry its id in the kid header.
+var jwtKeys *Keyring
 
-type PatientVerificationClaims struct {
+func SetJWTKeys(keys *Keyring) {
+	jwtKeys = keys
+}
+
+const (
+	AccessTokenUse      = "access"
+	AccessTokenDuration = 15 * time.Minute
+
+	// MFAChallengeTokenUse is handed out at log in to accounts with two factor authentication enabled,
+	// it can only be exchanged for a session together with a valid code.
+	MFAChallengeTokenUse = "mfa_challenge"
+	// MFAEnrollmentTokenUse is handed out at log in to accounts required to enable two factor
+	// authentication, it only gives access to the enrollment routes.
+	MFAEnrollmentTokenUse = "mfa_enrollment"
+	MFAChallengeDuration  = 5 * time.Minute
+)
+
+// AccessTokenClaims are the claims carried by the access token issued on log in, and by the
+// two factor challenge tokens, which differ by their token use.
+type AccessTokenClaims struct {
 	jwt.StandardClaims
-	CustomData map[string]interface{} `json:"custom_data"`
+	Tenant
+	Role     string `json:"role"`
+	TokenUse string `json:"token_use"`
+}
+
+// Tenant is the organisation an account belongs to and its role in it, both are empty for accounts
+// outside of any organisation.
+type Tenant struct {
+	OrganisationID   string `json:"organisation_id,omitempty"`
+	OrganisationRole string `json:"organisation_role,omitempty"`
 }
 
 func GenerateVerificationToken(email string) (string, error) {
@@ -20,35 +52,73 @@ func GenerateVerificationToken(email string) (string, error) {
 		Subject:   email,
 		ExpiresAt: time.Now().Add(24 * time.Hour).Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signJWT(claims)
 }
 
-func GeneratePatientVerificationToken(email string, oldPassword string, id string) (string, error) {
-	customData := map[string]interface{}{
-		"old_password": oldPassword,
-		"id":           id,
+func DecodeJWT(token string) (map[string]interface{}, error) {
+	claims := jwt.MapClaims{}
+	t, err := jwt.ParseWithClaims(token, claims, jwtKeyFunc)
+	if err != nil {
+		return nil, fmt.Errorf("error reading jwt: %w", err)
 	}
 
-	// Create the custom claims
-	claims := &PatientVerificationClaims{
+	if !t.Valid {
+		return nil, errors.New("JWT is not valid")
+	}
+
+	tokenMap := make(map[string]interface{})
+	for key, val := range claims {
+		tokenMap[key] = val
+	}
+
+	return tokenMap, nil
+}
+
+// GenerateAccessToken issues a short lived access token for the given account.
+func GenerateAccessToken(accountId string, role string, tenant Tenant) (string, time.Time, error) {
+	return generateAccountToken(accountId, role, tenant, AccessTokenUse, AccessTokenDuration)
+}
+
+// GenerateMFAChallengeToken issues a challenge token of the given use, MFAChallengeTokenUse or MFAEnrollmentTokenUse.
+func GenerateMFAChallengeToken(accountId string, role string, tenant Tenant, tokenUse string) (string, time.Time, error) {
+	return generateAccountToken(accountId, role, tenant, tokenUse, MFAChallengeDuration)
+}
+
+func generateAccountToken(accountId string, role string, tenant Tenant, tokenUse string, duration time.Duration) (string, time.Time, error) {
+	expiresAt := time.Now().Add(duration)
+	claims := &AccessTokenClaims{
 		StandardClaims: jwt.StandardClaims{
-			Subject:   email,
-			ExpiresAt: time.Now().Add(24 * time.Hour).Unix(),
+			Subject:   accountId,
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expiresAt.Unix(),
 		},
-		CustomData: customData,
+		Tenant:   tenant,
+		Role:     role,
+		TokenUse: tokenUse,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	signedToken, err := signJWT(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signedToken, expiresAt, nil
 }
 
-func DecodeJWT(token string) (map[string]interface{}, error) {
-	claims := jwt.MapClaims{}
-	t, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
-	})
+// DecodeAccessToken validates the signature and expiry of an access token and returns its claims.
+func DecodeAccessToken(token string) (*AccessTokenClaims, error) {
+	return DecodeAccountToken(token, AccessTokenUse)
+}
+
+// DecodeAccountToken validates the signature and expiry of a token issued to an account
+// and checks it has one of the given uses.
+func DecodeAccountToken(token string, tokenUses ...string) (*AccessTokenClaims, error) {
+	claims := &AccessTokenClaims{}
+	t, err := jwt.ParseWithClaims(token, claims, jwtKeyFunc)
 	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+			return nil, errors.New("token_expired")
+		}
 		return nil, fmt.Errorf("error reading jwt: %w", err)
 	}
 
@@ -56,10 +126,44 @@ func DecodeJWT(token string) (map[string]interface{}, error) {
 		return nil, errors.New("JWT is not valid")
 	}
 
-	tokenMap := make(map[string]interface{})
-	for key, val := range claims {
-		tokenMap[key] = val
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
 	}
+	for _, tokenUse := range tokenUses {
+		if claims.TokenUse == tokenUse {
+			return claims, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected token use %q", claims.TokenUse)
+}
 
-	return tokenMap, nil
+func signJWT(claims jwt.Claims) (string, error) {
+	if jwtKeys == nil {
+		return "", errors.New("jwt keys are not configured")
+	}
+	keyId, secret := jwtKeys.Active()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = keyId
+	return token.SignedString(secret)
+}
+
+// jwtKeyFunc picks the verification key from the kid header, tokens without one were signed with DefaultKeyID.
+func jwtKeyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	if jwtKeys == nil {
+		return nil, errors.New("jwt keys are not configured")
+	}
+	keyId, _ := token.Header["kid"].(string)
+	return jwtKeys.Key(keyId)
+}
+
+// GenerateOpaqueToken returns a random url safe token to be handed out to clients and stored hashed.
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
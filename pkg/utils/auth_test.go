@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWTKeyRotation(t *testing.T) {
+	oldKeys, err := NewKeyring(DefaultKeyID, map[string]string{DefaultKeyID: "k8Jd2nVq9ZpL4xWc7RtY1bHm6FsA3eGu"})
+	assert.Nil(t, err)
+	newKeys, err := NewKeyring("2024-06", map[string]string{
+		DefaultKeyID: "k8Jd2nVq9ZpL4xWc7RtY1bHm6FsA3eGu",
+		"2024-06":    "Zx8Cv6Bn4Mm2Lk0Jh9Gf7Ds5Aa3Pp1Oo",
+	})
+	assert.Nil(t, err)
+	defer SetJWTKeys(nil)
+
+	SetJWTKeys(oldKeys)
+	oldToken, _, err := GenerateAccessToken("account-id", "PATIENT", Tenant{})
+	assert.Nil(t, err)
+
+	SetJWTKeys(newKeys)
+	newToken, _, err := GenerateAccessToken("account-id", "PATIENT", Tenant{})
+	assert.Nil(t, err)
+
+	claims, err := DecodeAccessToken(oldToken)
+	assert.Nil(t, err, "tokens signed with a retired key should verify during the grace period")
+	assert.Equal(t, "account-id", claims.Subject)
+
+	SetJWTKeys(oldKeys)
+	_, err = DecodeAccessToken(newToken)
+	assert.NotNil(t, err, "tokens signed with an unknown key should be rejected")
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// Backoff tracks consecutive failures per key in memory. Once a key reaches the threshold, every
+// further failure blocks it for an exponentially growing delay. Failures are forgotten once the key
+// has been idle for the maximum delay.
+type Backoff struct {
+	threshold int
+	base      time.Duration
+	max       time.Duration
+	entries   map[string]*backoffEntry
+	lastSweep time.Time
+	mux       sync.Mutex
+}
+
+type backoffEntry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+func NewBackoff(threshold int, base time.Duration, max time.Duration) *Backoff {
+	return &Backoff{
+		threshold: threshold,
+		base:      base,
+		max:       max,
+		entries:   make(map[string]*backoffEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// RetryAfter returns how long the key is still blocked for, zero when it is not.
+func (b *Backoff) RetryAfter(key string) time.Duration {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	entry, ok := b.entries[key]
+	if !ok {
+		return 0
+	}
+	if wait := time.Until(entry.blockedUntil); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Failure records a failure for the key and blocks it when it is over the threshold.
+func (b *Backoff) Failure(key string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	now := time.Now()
+	b.sweep(now)
+
+	entry, ok := b.entries[key]
+	if !ok || now.Sub(entry.lastFailure) > b.max {
+		entry = &backoffEntry{}
+		b.entries[key] = entry
+	}
+	entry.failures++
+	entry.lastFailure = now
+	if delay := BackoffDelay(entry.failures, b.threshold, b.base, b.max); delay > 0 {
+		entry.blockedUntil = now.Add(delay)
+	}
+}
+
+// BackoffDelay returns the delay after the given number of consecutive failures: none below the
+// threshold, then base, doubled for every further failure and capped at max.
+func BackoffDelay(failures int, threshold int, base time.Duration, max time.Duration) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	exponent := failures - threshold
+	if exponent > 30 {
+		return max
+	}
+	delay := base << exponent
+	if delay > max || delay <= 0 {
+		return max
+	}
+	return delay
+}
+
+// sweep drops the idle keys once per max delay so the map does not grow unbounded.
+func (b *Backoff) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < b.max {
+		return
+	}
+	for key, entry := range b.entries {
+		if now.Sub(entry.lastFailure) > b.max && now.After(entry.blockedUntil) {
+			delete(b.entries, key)
+		}
+	}
+	b.lastSweep = now
+}
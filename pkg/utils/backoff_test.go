@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	t.Run("delay doubles after the threshold and is capped", func(t *testing.T) {
+		assert.Equal(t, time.Duration(0), BackoffDelay(4, 5, time.Minute, time.Hour))
+		assert.Equal(t, time.Minute, BackoffDelay(5, 5, time.Minute, time.Hour))
+		assert.Equal(t, 4*time.Minute, BackoffDelay(7, 5, time.Minute, time.Hour))
+		assert.Equal(t, time.Hour, BackoffDelay(12, 5, time.Minute, time.Hour))
+		assert.Equal(t, time.Hour, BackoffDelay(500, 5, time.Minute, time.Hour))
+	})
+
+	t.Run("key is blocked once over the threshold", func(t *testing.T) {
+		backoff := NewBackoff(3, time.Minute, time.Hour)
+		backoff.Failure("127.0.0.1")
+		backoff.Failure("127.0.0.1")
+		assert.Equal(t, time.Duration(0), backoff.RetryAfter("127.0.0.1"))
+
+		backoff.Failure("127.0.0.1")
+		assert.Greater(t, backoff.RetryAfter("127.0.0.1"), 59*time.Second)
+		assert.Equal(t, time.Duration(0), backoff.RetryAfter("10.0.0.1"), "other keys are not affected")
+	})
+}
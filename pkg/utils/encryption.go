@@ -9,17 +9,36 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
+	"strings"
 )
 
 type Encryption interface {
 	Encrypt(plainText string) (string, error)
 	Decrypt(encryptedText string) (string, error)
+	EncryptWithAssociatedData(plainText string, associatedData string) (string, error)
+	DecryptWithAssociatedData(encryptedText string, associatedData string) (string, error)
 	HashSHA256(plainText string, context string) string
+	MAC(plainText string, context string) string
+	VerifyMAC(plainText string, context string, mac string) bool
+	NeedsReencrypt(encryptedText string) bool
+	IsEncrypted(value string) bool
 }
 
+// FieldAssociatedData binds an encrypted value to the record and field it is stored in,
+// so that it cannot be copied to another record or field and still decrypt.
+func FieldAssociatedData(recordId string, field string) string {
+	return recordId + "." + field
+}
+
+// Encryptor is the legacy AES-CBC implementation, it does not authenticate ciphertexts and is only
+// kept to read values written before AEADEncryptor. It encrypts with the active key of Keys and
+// prefixes the ciphertext with its key id, e.g. "2024-06:<base64>". Ciphertexts without a prefix are
+// decrypted with DefaultKeyID. Without a keyring the Passphrase is used and ciphertexts carry no prefix.
 type Encryptor struct {
 	Passphrase string
+	Keys       *Keyring
 }
 
 func NewEncryptor(passphrase string) *Encryptor {
@@ -28,6 +47,12 @@ func NewEncryptor(passphrase string) *Encryptor {
 	}
 }
 
+func NewKeyringEncryptor(keys *Keyring) *Encryptor {
+	return &Encryptor{
+		Keys: keys,
+	}
+}
+
 // pad applies PKCS#7 padding to the plaintext.
 func pad(plaintext []byte, blockSize int) []byte {
 	padding := blockSize - len(plaintext)%blockSize
@@ -55,7 +80,63 @@ func unpad(plaintext []byte) ([]byte, error) {
 
 // encrypt encrypts plain text string using AES encryption algorithm.
 func (e *Encryptor) Encrypt(plainText string) (string, error) {
-	key := []byte(e.Passphrase)
+	keyId, key := e.activeKey()
+	encryptedText, err := encryptCBC(key, plainText)
+	if err != nil {
+		return "", err
+	}
+	if keyId == "" {
+		return encryptedText, nil
+	}
+	return keyId + ":" + encryptedText, nil
+}
+
+// decrypt decrypts the encrypted string to original string using the AES encryption algorithm.
+func (e *Encryptor) Decrypt(encryptedText string) (string, error) {
+	keyId, encryptedText := splitKeyId(encryptedText)
+	key, err := e.key(keyId)
+	if err != nil {
+		return "", err
+	}
+	return decryptCBC(key, encryptedText)
+}
+
+// NeedsReencrypt reports whether the ciphertext was written with a key other than the active one.
+func (e *Encryptor) NeedsReencrypt(encryptedText string) bool {
+	if e.Keys == nil {
+		return false
+	}
+	keyId, _ := splitKeyId(encryptedText)
+	return keyId != e.Keys.ActiveID()
+}
+
+func (e *Encryptor) activeKey() (string, []byte) {
+	if e.Keys == nil {
+		return "", []byte(e.Passphrase)
+	}
+	return e.Keys.Active()
+}
+
+func (e *Encryptor) key(keyId string) ([]byte, error) {
+	if e.Keys == nil {
+		if keyId != "" {
+			return nil, fmt.Errorf("cannot decrypt with key %q without a keyring", keyId)
+		}
+		return []byte(e.Passphrase), nil
+	}
+	return e.Keys.Key(keyId)
+}
+
+// splitKeyId separates the key id prefix from the ciphertext, ':' is not part of the base64 alphabet.
+func splitKeyId(encryptedText string) (string, string) {
+	keyId, cipherText, found := strings.Cut(encryptedText, ":")
+	if !found {
+		return "", encryptedText
+	}
+	return keyId, cipherText
+}
+
+func encryptCBC(key []byte, plainText string) (string, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
@@ -72,9 +153,7 @@ func (e *Encryptor) Encrypt(plainText string) (string, error) {
 	return base64.StdEncoding.EncodeToString(cipherText), nil
 }
 
-// decrypt decrypts the encrypted string to original string using the AES encryption algorithm.
-func (e *Encryptor) Decrypt(encryptedText string) (string, error) {
-	key := []byte(e.Passphrase)
+func decryptCBC(key []byte, encryptedText string) (string, error) {
 	cipherText, err := base64.StdEncoding.DecodeString(encryptedText)
 	if err != nil {
 		return "", err
@@ -86,6 +165,9 @@ func (e *Encryptor) Decrypt(encryptedText string) (string, error) {
 	if len(cipherText) < aes.BlockSize {
 		return "", errors.New("cipherText too short")
 	}
+	if len(cipherText)%aes.BlockSize != 0 {
+		return "", errors.New("cipherText is not a multiple of the block size")
+	}
 	iv := cipherText[:aes.BlockSize]
 	cipherText = cipherText[aes.BlockSize:]
 	mode := cipher.NewCBCDecrypter(block, iv)
@@ -99,6 +181,10 @@ func (e *Encryptor) Decrypt(encryptedText string) (string, error) {
 }
 
 func (e *Encryptor) HashSHA256(text string, context string) string {
+	return hashSHA256(text, context)
+}
+
+func hashSHA256(text string, context string) string {
 	hasher := sha256.New()
 	hasher.Write([]byte(text))
 	hashBytes := hasher.Sum([]byte(context))
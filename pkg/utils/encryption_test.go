@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"crypto/aes"
 	"encoding/base64"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -70,3 +72,172 @@ func TestEncryptionDecryption(t *testing.T) {
 		assert.Equal(t, hashedUUID1, hashedUUID2)
 	})
 }
+
+func TestEncryptionKeyRotation(t *testing.T) {
+	oldKeys, err := NewKeyring(DefaultKeyID, map[string]string{DefaultKeyID: passphrase})
+	assert.Nil(t, err)
+	newKeys, err := NewKeyring("2024-06", map[string]string{
+		DefaultKeyID: passphrase,
+		"2024-06":    "anotherpassphraseof32bytes!12345",
+	})
+	assert.Nil(t, err)
+
+	t.Run("ciphertext is prefixed with the active key id", func(t *testing.T) {
+		encryptedText, err := NewKeyringEncryptor(newKeys).Encrypt("Hello, World!")
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(encryptedText, "2024-06:"))
+	})
+
+	t.Run("retired keys still decrypt", func(t *testing.T) {
+		encryptedText, err := NewKeyringEncryptor(oldKeys).Encrypt("Hello, World!")
+		assert.Nil(t, err)
+
+		encryptor := NewKeyringEncryptor(newKeys)
+		assert.True(t, encryptor.NeedsReencrypt(encryptedText))
+		decryptedText, err := encryptor.Decrypt(encryptedText)
+		assert.Nil(t, err)
+		assert.Equal(t, "Hello, World!", decryptedText)
+	})
+
+	t.Run("ciphertext without key id is decrypted with the default key", func(t *testing.T) {
+		encryptedText, err := NewEncryptor(passphrase).Encrypt("Hello, World!")
+		assert.Nil(t, err)
+
+		encryptor := NewKeyringEncryptor(newKeys)
+		assert.True(t, encryptor.NeedsReencrypt(encryptedText))
+		decryptedText, err := encryptor.Decrypt(encryptedText)
+		assert.Nil(t, err)
+		assert.Equal(t, "Hello, World!", decryptedText)
+	})
+
+	t.Run("unknown key id fails", func(t *testing.T) {
+		encryptedText, err := NewKeyringEncryptor(newKeys).Encrypt("Hello, World!")
+		assert.Nil(t, err)
+
+		_, err = NewKeyringEncryptor(oldKeys).Decrypt(encryptedText)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestAEADEncryption(t *testing.T) {
+	keys, err := NewKeyring("2024-06", map[string]string{
+		DefaultKeyID: passphrase,
+		"2024-06":    "anotherpassphraseof32bytes!12345",
+	})
+	assert.Nil(t, err)
+	encryptor := NewAEADEncryptor(keys)
+	associatedData := FieldAssociatedData("record-id", "encrypted_password")
+
+	// tamper decodes the ciphertext, lets the caller modify it and encodes it back
+	tamper := func(encryptedText string, modify func(blob []byte) []byte) string {
+		keyId, payload, _ := strings.Cut(encryptedText, "$")
+		blob, err := base64.StdEncoding.DecodeString(payload)
+		assert.Nil(t, err)
+		return keyId + "$" + base64.StdEncoding.EncodeToString(modify(blob))
+	}
+
+	t.Run("successful encryption and decryption", func(t *testing.T) {
+		encryptedText, err := encryptor.EncryptWithAssociatedData("Hello, World!", associatedData)
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(encryptedText, "2024-06$"))
+		assert.False(t, encryptor.NeedsReencrypt(encryptedText))
+
+		decryptedText, err := encryptor.DecryptWithAssociatedData(encryptedText, associatedData)
+		assert.Nil(t, err)
+		assert.Equal(t, "Hello, World!", decryptedText)
+	})
+
+	t.Run("ciphertext starts with the version byte", func(t *testing.T) {
+		encryptedText, err := encryptor.Encrypt("Hello, World!")
+		assert.Nil(t, err)
+		tamper(encryptedText, func(blob []byte) []byte {
+			assert.Equal(t, aeadVersion, blob[0])
+			return blob
+		})
+	})
+
+	t.Run("error on tampered ciphertext", func(t *testing.T) {
+		encryptedText, err := encryptor.EncryptWithAssociatedData("Hello, World!", associatedData)
+		assert.Nil(t, err)
+
+		flipped := tamper(encryptedText, func(blob []byte) []byte {
+			blob[len(blob)-1] ^= 0x01
+			return blob
+		})
+		_, err = encryptor.DecryptWithAssociatedData(flipped, associatedData)
+		assert.NotNil(t, err, "should error on a modified ciphertext")
+
+		truncated := tamper(encryptedText, func(blob []byte) []byte {
+			return blob[:len(blob)-4]
+		})
+		_, err = encryptor.DecryptWithAssociatedData(truncated, associatedData)
+		assert.NotNil(t, err, "should error on a truncated ciphertext")
+
+		otherVersion := tamper(encryptedText, func(blob []byte) []byte {
+			blob[0] = 0x03
+			return blob
+		})
+		_, err = encryptor.DecryptWithAssociatedData(otherVersion, associatedData)
+		assert.NotNil(t, err, "should error on an unknown version")
+	})
+
+	t.Run("error on swapped key id", func(t *testing.T) {
+		encryptedText, err := encryptor.EncryptWithAssociatedData("Hello, World!", associatedData)
+		assert.Nil(t, err)
+
+		swapped := DefaultKeyID + strings.TrimPrefix(encryptedText, "2024-06")
+		_, err = encryptor.DecryptWithAssociatedData(swapped, associatedData)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("error on different associated data", func(t *testing.T) {
+		encryptedText, err := encryptor.EncryptWithAssociatedData("Hello, World!", associatedData)
+		assert.Nil(t, err)
+
+		_, err = encryptor.DecryptWithAssociatedData(encryptedText, FieldAssociatedData("other-record-id", "encrypted_password"))
+		assert.NotNil(t, err, "a value copied to another record should not decrypt")
+	})
+
+	t.Run("legacy ciphertexts are decrypted", func(t *testing.T) {
+		unprefixed, err := NewEncryptor(passphrase).Encrypt("Hello, World!")
+		assert.Nil(t, err)
+		prefixed, err := NewKeyringEncryptor(keys).Encrypt("Hello, World!")
+		assert.Nil(t, err)
+
+		for _, encryptedText := range []string{unprefixed, prefixed} {
+			assert.True(t, encryptor.NeedsReencrypt(encryptedText))
+			decryptedText, err := encryptor.DecryptWithAssociatedData(encryptedText, associatedData)
+			assert.Nil(t, err)
+			assert.Equal(t, "Hello, World!", decryptedText)
+		}
+	})
+
+	t.Run("macs are bound to their context and key", func(t *testing.T) {
+		mac := encryptor.MAC("ABCDE-FGHIJ", "recovery_code.account-id")
+		assert.True(t, strings.HasPrefix(mac, "2024-06$"))
+		assert.True(t, encryptor.VerifyMAC("ABCDE-FGHIJ", "recovery_code.account-id", mac))
+		assert.False(t, encryptor.VerifyMAC("ABCDE-FGHIK", "recovery_code.account-id", mac))
+		assert.False(t, encryptor.VerifyMAC("ABCDE-FGHIJ", "recovery_code.other-account-id", mac), "the context is part of the mac")
+		assert.False(t, encryptor.VerifyMAC("ABCDE-FGHIJ", "recovery_code.account-id", DefaultKeyID+strings.TrimPrefix(mac, "2024-06")))
+		assert.NotContains(t, mac, hashSHA256("ABCDE-FGHIJ", ""), "the text is not hashed alone")
+
+		rotated, err := NewKeyring(DefaultKeyID, map[string]string{
+			DefaultKeyID: passphrase,
+			"2024-06":    "anotherpassphraseof32bytes!12345",
+		})
+		assert.Nil(t, err)
+		assert.True(t, NewAEADEncryptor(rotated).VerifyMAC("ABCDE-FGHIJ", "recovery_code.account-id", mac), "macs of retired keys still verify")
+	})
+
+	t.Run("legacy padding errors are not reported", func(t *testing.T) {
+		encryptedText, err := NewEncryptor(passphrase).Encrypt("Hello, World!")
+		assert.Nil(t, err)
+		blob, err := base64.StdEncoding.DecodeString(encryptedText)
+		assert.Nil(t, err)
+		// flipping the last iv byte turns the padding of the single block into an invalid one
+		blob[aes.BlockSize-1] ^= 0x01
+
+		_, err = encryptor.Decrypt(base64.StdEncoding.EncodeToString(blob))
+		assert.EqualError(t, err, "decryption failed")
+	})
+}
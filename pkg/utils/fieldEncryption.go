@@ -0,0 +1,289 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// encryptedFieldsCache maps a model type to the record fields of its `encrypted:"true"` struct fields.
+var encryptedFieldsCache sync.Map
+
+// SealedFieldsField is the field of the collections holding encrypted fields that lists, comma separated,
+// the fields of the record whose value is currently sealed. It is what tells a sealed value from a plain
+// one, values that merely look encrypted are never trusted to be.
+const SealedFieldsField = "sealed_fields"
+
+// FieldCipher encrypts and decrypts the record fields backing the string struct fields of a model
+// tagged `encrypted:"true"`, e.g.
+//
+//	EventDescription string `json:"event_description" encrypted:"true"`
+//
+// Repositories seal records before saving them and open them after reading them, so that services and
+// handlers only ever see plain text. Each value is bound to its record id and field name.
+type FieldCipher struct {
+	encryptor Encryption
+}
+
+func NewFieldCipher(encryptor Encryption) *FieldCipher {
+	return &FieldCipher{
+		encryptor: encryptor,
+	}
+}
+
+// Seal encrypts the encrypted fields of the model in the record that are not sealed yet and lists them
+// in SealedFieldsField. Records that have not been saved yet are given their id now, since it is part
+// of the associated data.
+func (f *FieldCipher) Seal(record *models.Record, model interface{}) error {
+	fields := EncryptedFields(model)
+	if len(fields) == 0 {
+		return nil
+	}
+	if record.Collection().Schema.GetFieldByName(SealedFieldsField) == nil {
+		return fmt.Errorf("collection %s has no %s field", record.Collection().Name, SealedFieldsField)
+	}
+	if !record.HasId() {
+		record.RefreshId()
+	}
+
+	sealed := sealedFields(record)
+	for _, field := range fields {
+		value := record.GetString(field)
+		if value == "" {
+			delete(sealed, field)
+			continue
+		}
+		if sealed[field] {
+			continue
+		}
+		encryptedValue, err := f.encryptor.EncryptWithAssociatedData(value, FieldAssociatedData(record.Id, field))
+		if err != nil {
+			return fmt.Errorf("could not encrypt %s: %w", field, err)
+		}
+		record.Set(field, encryptedValue)
+		sealed[field] = true
+	}
+	setSealedFields(record, fields, sealed)
+	return nil
+}
+
+// Open decrypts the sealed fields of the model in the record, which are no longer listed as sealed
+// once decrypted. Values stored before the field was marked as encrypted are returned as they are.
+func (f *FieldCipher) Open(record *models.Record, model interface{}) error {
+	fields := EncryptedFields(model)
+	if len(fields) == 0 {
+		return nil
+	}
+	sealed := sealedFields(record)
+	defer setSealedFields(record, fields, sealed)
+
+	for _, field := range fields {
+		value := record.GetString(field)
+		if !sealed[field] || value == "" {
+			delete(sealed, field)
+			continue
+		}
+		plainText, err := f.encryptor.DecryptWithAssociatedData(value, FieldAssociatedData(record.Id, field))
+		if err != nil {
+			return fmt.Errorf("could not decrypt %s of record %s: %w", field, record.Id, err)
+		}
+		record.Set(field, plainText)
+		delete(sealed, field)
+	}
+	return nil
+}
+
+// OpenAll opens the records and returns the ones that could be opened, a record that fails to decrypt
+// is logged and left out rather than failing the whole list.
+func (f *FieldCipher) OpenAll(records []*models.Record, model interface{}) []*models.Record {
+	opened := make([]*models.Record, 0, len(records))
+	for _, record := range records {
+		if err := f.Open(record, model); err != nil {
+			log.Printf("Leaving out record %s of %s: %v", record.Id, record.Collection().Name, err)
+			continue
+		}
+		opened = append(opened, record)
+	}
+	return opened
+}
+
+// Reencrypt rewrites the encrypted fields of the model in the record that are still plain text, or
+// were written with a retired key, with the active key. It reports whether the record changed.
+func (f *FieldCipher) Reencrypt(record *models.Record, model interface{}) (bool, error) {
+	fields := EncryptedFields(model)
+	sealed := sealedFields(record)
+	changed := false
+	for _, field := range fields {
+		value := record.GetString(field)
+		if value == "" {
+			continue
+		}
+
+		associatedData := FieldAssociatedData(record.Id, field)
+		if sealed[field] {
+			if !f.encryptor.NeedsReencrypt(value) {
+				continue
+			}
+			plainText, err := f.encryptor.DecryptWithAssociatedData(value, associatedData)
+			if err != nil {
+				return changed, fmt.Errorf("could not decrypt %s of record %s: %w", field, record.Id, err)
+			}
+			value = plainText
+		}
+
+		encryptedValue, err := f.encryptor.EncryptWithAssociatedData(value, associatedData)
+		if err != nil {
+			return changed, fmt.Errorf("could not encrypt %s of record %s: %w", field, record.Id, err)
+		}
+		record.Set(field, encryptedValue)
+		sealed[field] = true
+		changed = true
+	}
+	setSealedFields(record, fields, sealed)
+	return changed, nil
+}
+
+// ReencryptCollection runs Reencrypt over every record of the collection and returns the number of
+// records rewritten. Records are saved without triggering the model hooks, e.g. event notifications.
+func (f *FieldCipher) ReencryptCollection(dao *daos.Dao, collection string, model interface{}) (int, error) {
+	if len(EncryptedFields(model)) == 0 {
+		return 0, nil
+	}
+
+	records := []*models.Record{}
+	if err := dao.RecordQuery(collection).All(&records); err != nil {
+		return 0, fmt.Errorf("could not list %s: %w", collection, err)
+	}
+
+	reencrypted := 0
+	for _, record := range records {
+		marked := f.markSealed(record, model)
+		changed, err := f.Reencrypt(record, model)
+		if err != nil {
+			return reencrypted, err
+		}
+		if !marked && !changed {
+			continue
+		}
+		if err := dao.WithoutHooks().SaveRecord(record); err != nil {
+			return reencrypted, fmt.Errorf("could not save record %s of %s: %w", record.Id, collection, err)
+		}
+		reencrypted++
+	}
+	return reencrypted, nil
+}
+
+// BackfillSealedFields lists the values sealed before SealedFieldsField existed in it, for the records
+// of the collection that have none listed, and returns the number of records marked. Only the values that
+// decrypt with their record id and field name are listed, plain text stored before the field was marked as
+// encrypted is left for Reencrypt.
+func (f *FieldCipher) BackfillSealedFields(dao *daos.Dao, collection string, model interface{}) (int, error) {
+	if len(EncryptedFields(model)) == 0 {
+		return 0, nil
+	}
+
+	records := []*models.Record{}
+	err := dao.RecordQuery(collection).
+		AndWhere(dbx.HashExp{SealedFieldsField: ""}).
+		All(&records)
+	if err != nil {
+		return 0, fmt.Errorf("could not list %s: %w", collection, err)
+	}
+
+	marked := 0
+	for _, record := range records {
+		if !f.markSealed(record, model) {
+			continue
+		}
+		if err := dao.WithoutHooks().SaveRecord(record); err != nil {
+			return marked, fmt.Errorf("could not save record %s of %s: %w", record.Id, collection, err)
+		}
+		marked++
+	}
+	return marked, nil
+}
+
+// markSealed lists the values of a record with no sealed field listed that decrypt with their record id
+// and field name, it reports whether any was listed.
+func (f *FieldCipher) markSealed(record *models.Record, model interface{}) bool {
+	if record.GetString(SealedFieldsField) != "" {
+		return false
+	}
+
+	fields := EncryptedFields(model)
+	sealed := map[string]bool{}
+	for _, field := range fields {
+		value := record.GetString(field)
+		if value == "" {
+			continue
+		}
+		if _, err := f.encryptor.DecryptWithAssociatedData(value, FieldAssociatedData(record.Id, field)); err == nil {
+			sealed[field] = true
+		}
+	}
+	if len(sealed) == 0 {
+		return false
+	}
+	setSealedFields(record, fields, sealed)
+	return true
+}
+
+func sealedFields(record *models.Record) map[string]bool {
+	sealed := map[string]bool{}
+	for _, field := range strings.Split(record.GetString(SealedFieldsField), ",") {
+		if field != "" {
+			sealed[field] = true
+		}
+	}
+	return sealed
+}
+
+// setSealedFields lists the sealed fields in the order of the model, so that sealing a record again
+// does not change it.
+func setSealedFields(record *models.Record, fields []string, sealed map[string]bool) {
+	listed := []string{}
+	for _, field := range fields {
+		if sealed[field] {
+			listed = append(listed, field)
+		}
+	}
+	record.Set(SealedFieldsField, strings.Join(listed, ","))
+}
+
+// EncryptedFields returns the json names of the struct fields tagged `encrypted:"true"`.
+// The model can be a struct or a pointer to one.
+func EncryptedFields(model interface{}) []string {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if fields, ok := encryptedFieldsCache.Load(t); ok {
+		return fields.([]string)
+	}
+
+	fields := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if structField.Tag.Get("encrypted") != "true" || structField.Type.Kind() != reflect.String {
+			continue
+		}
+		name, _, _ := strings.Cut(structField.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, name)
+	}
+
+	encryptedFieldsCache.Store(t, fields)
+	return fields
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+type encryptedModel struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Notes string `json:"notes" encrypted:"true"`
+}
+
+func newEncryptedRecord(notes string) *models.Record {
+	collection := &models.Collection{
+		Name: "notes",
+		Schema: schema.NewSchema(
+			&schema.SchemaField{Name: "title", Type: schema.FieldTypeText},
+			&schema.SchemaField{Name: "notes", Type: schema.FieldTypeText},
+			&schema.SchemaField{Name: SealedFieldsField, Type: schema.FieldTypeText},
+		),
+	}
+	record := models.NewRecord(collection)
+	LoadFromStruct(record, &encryptedModel{Title: "Check up", Notes: notes})
+	return record
+}
+
+func TestFieldEncryption(t *testing.T) {
+	keys, err := NewKeyring(DefaultKeyID, map[string]string{DefaultKeyID: passphrase})
+	assert.Nil(t, err)
+	cipher := NewFieldCipher(NewAEADEncryptor(keys))
+
+	t.Run("only tagged fields are encrypted", func(t *testing.T) {
+		assert.Equal(t, []string{"notes"}, EncryptedFields(&encryptedModel{}))
+
+		record := newEncryptedRecord("blood pressure is high")
+		assert.Nil(t, cipher.Seal(record, encryptedModel{}))
+		assert.NotEmpty(t, record.Id, "the id is part of the associated data")
+		assert.Equal(t, "Check up", record.GetString("title"))
+		assert.NotEqual(t, "blood pressure is high", record.GetString("notes"))
+		assert.Equal(t, "notes", record.GetString(SealedFieldsField))
+
+		assert.Nil(t, cipher.Seal(record, encryptedModel{}), "sealing twice should not encrypt twice")
+		assert.Nil(t, cipher.Open(record, encryptedModel{}))
+		assert.Equal(t, "blood pressure is high", record.GetString("notes"))
+		assert.Empty(t, record.GetString(SealedFieldsField), "opened values are plain text again")
+	})
+
+	t.Run("plain text looking encrypted is encrypted", func(t *testing.T) {
+		sealed := newEncryptedRecord("blood pressure is high")
+		assert.Nil(t, cipher.Seal(sealed, encryptedModel{}))
+
+		record := newEncryptedRecord(sealed.GetString("notes"))
+		assert.Nil(t, cipher.Seal(record, encryptedModel{}))
+		assert.NotEqual(t, sealed.GetString("notes"), record.GetString("notes"))
+		assert.Nil(t, cipher.Open(record, encryptedModel{}))
+		assert.Equal(t, sealed.GetString("notes"), record.GetString("notes"))
+	})
+
+	t.Run("collections without sealed fields cannot be sealed", func(t *testing.T) {
+		record := models.NewRecord(&models.Collection{Name: "notes", Schema: schema.NewSchema(
+			&schema.SchemaField{Name: "notes", Type: schema.FieldTypeText},
+		)})
+		record.Set("notes", "blood pressure is high")
+		assert.NotNil(t, cipher.Seal(record, encryptedModel{}))
+		assert.Equal(t, "blood pressure is high", record.GetString("notes"))
+	})
+
+	t.Run("plain text stored before encryption is returned as is", func(t *testing.T) {
+		record := newEncryptedRecord("blood pressure is high")
+		assert.Nil(t, cipher.Open(record, encryptedModel{}))
+		assert.Equal(t, "blood pressure is high", record.GetString("notes"))
+	})
+
+	t.Run("value moved to another record fails to decrypt", func(t *testing.T) {
+		record := newEncryptedRecord("blood pressure is high")
+		assert.Nil(t, cipher.Seal(record, encryptedModel{}))
+
+		other := newEncryptedRecord("")
+		other.RefreshId()
+		other.Set("notes", record.GetString("notes"))
+		other.Set(SealedFieldsField, record.GetString(SealedFieldsField))
+		assert.NotNil(t, cipher.Open(other, encryptedModel{}))
+	})
+
+	t.Run("records failing to decrypt are left out of lists", func(t *testing.T) {
+		record := newEncryptedRecord("blood pressure is high")
+		assert.Nil(t, cipher.Seal(record, encryptedModel{}))
+		broken := newEncryptedRecord("")
+		broken.RefreshId()
+		broken.Set("notes", record.GetString("notes"))
+		broken.Set(SealedFieldsField, "notes")
+
+		opened := cipher.OpenAll([]*models.Record{broken, record}, encryptedModel{})
+		assert.Equal(t, []*models.Record{record}, opened)
+		assert.Equal(t, "blood pressure is high", record.GetString("notes"))
+	})
+
+	t.Run("values sealed before sealed fields were listed are marked", func(t *testing.T) {
+		record := newEncryptedRecord("blood pressure is high")
+		assert.Nil(t, cipher.Seal(record, encryptedModel{}))
+		record.Set(SealedFieldsField, "")
+		assert.True(t, cipher.markSealed(record, encryptedModel{}))
+		assert.Equal(t, "notes", record.GetString(SealedFieldsField))
+
+		plain := newEncryptedRecord("blood pressure is high")
+		plain.RefreshId()
+		assert.False(t, cipher.markSealed(plain, encryptedModel{}), "plain text does not decrypt")
+		assert.Empty(t, plain.GetString(SealedFieldsField))
+	})
+
+	t.Run("reencrypt encrypts plain text and skips current values", func(t *testing.T) {
+		record := newEncryptedRecord("blood pressure is high")
+		record.RefreshId()
+
+		changed, err := cipher.Reencrypt(record, encryptedModel{})
+		assert.Nil(t, err)
+		assert.True(t, changed)
+
+		changed, err = cipher.Reencrypt(record, encryptedModel{})
+		assert.Nil(t, err)
+		assert.False(t, changed)
+	})
+}
@@ -2,12 +2,48 @@ package utils
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
 )
 
+const (
+	AuthAccountIdKey = "authAccountId"
+	AuthRoleKey      = "authRole"
+	AuthTokenUseKey  = "authTokenUse"
+
+	AuthOrganisationIdKey   = "authOrganisationId"
+	AuthOrganisationRoleKey = "authOrganisationRole"
+)
+
+// clientIPExtractor tells the address of the client of a request, see SetTrustedProxies.
+var clientIPExtractor = echo.ExtractIPDirect()
+
+// SetTrustedProxies trusts the X-Forwarded-For header of the requests sent by the reverse proxies of the given
+// ranges. Without any, or for requests sent by anyone else, the client is the peer of the connection: the header
+// is set by the client and cannot be trusted to rate limit or audit requests.
+func SetTrustedProxies(ranges []*net.IPNet) {
+	if len(ranges) == 0 {
+		clientIPExtractor = echo.ExtractIPDirect()
+		return
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, ipRange := range ranges {
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	clientIPExtractor = echo.ExtractIPFromXFFHeader(options...)
+}
+
+// ClientIP returns the address of the client of the request. Unlike echo.Context.RealIP, which PocketBase
+// leaves to read the X-Forwarded-For header of any request, it only trusts the header set by a trusted proxy.
+func ClientIP(c echo.Context) string {
+	return clientIPExtractor(c.Request())
+}
+
 func GetHTTPVars(r *http.Request) map[string]string {
 	return mux.Vars(r)
 }
@@ -43,6 +79,84 @@ func EchoMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+// AuthMiddleware rejects requests without a valid access token and stores
+// the authenticated account id and role on the echo context.
+func AuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return authenticate(next, AccessTokenUse)
+}
+
+// EnrollmentAuthMiddleware works like AuthMiddleware but also accepts the enrollment token handed out
+// at log in to accounts that must enable two factor authentication before getting a session.
+func EnrollmentAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return authenticate(next, AccessTokenUse, MFAEnrollmentTokenUse)
+}
+
+func authenticate(next echo.HandlerFunc, tokenUses ...string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Request().Header.Get("Authorization")
+		token := strings.TrimPrefix(header, "Bearer ")
+		if header == "" || token == header {
+			return apis.NewUnauthorizedError("missing_access_token", nil)
+		}
+
+		claims, err := DecodeAccountToken(token, tokenUses...)
+		if err != nil {
+			if err.Error() == "token_expired" {
+				return apis.NewUnauthorizedError("access_token_expired", nil)
+			}
+			return apis.NewUnauthorizedError("invalid_access_token", nil)
+		}
+
+		tenant := claims.Tenant
+		if tenantResolver != nil {
+			if tenant, err = tenantResolver.Tenant(claims.Subject); err != nil {
+				if IsErrorNotFound(err) {
+					return apis.NewUnauthorizedError("invalid_access_token", nil)
+				}
+				return err
+			}
+		}
+
+		c.Set(AuthAccountIdKey, claims.Subject)
+		c.Set(AuthRoleKey, claims.Role)
+		c.Set(AuthTokenUseKey, claims.TokenUse)
+		c.Set(AuthOrganisationIdKey, tenant.OrganisationID)
+		c.Set(AuthOrganisationRoleKey, tenant.OrganisationRole)
+		return next(c)
+	}
+}
+
+// GetAuthAccountId returns the id of the account authenticated by AuthMiddleware.
+func GetAuthAccountId(c echo.Context) string {
+	id, _ := c.Get(AuthAccountIdKey).(string)
+	return id
+}
+
+// GetAuthRole returns the role of the account authenticated by AuthMiddleware.
+func GetAuthRole(c echo.Context) string {
+	role, _ := c.Get(AuthRoleKey).(string)
+	return role
+}
+
+// GetAuthTokenUse returns the use of the token the request was authenticated with.
+func GetAuthTokenUse(c echo.Context) string {
+	tokenUse, _ := c.Get(AuthTokenUseKey).(string)
+	return tokenUse
+}
+
+// GetAuthOrganisationId returns the organisation of the account authenticated by AuthMiddleware,
+// empty when it does not belong to any.
+func GetAuthOrganisationId(c echo.Context) string {
+	organisationId, _ := c.Get(AuthOrganisationIdKey).(string)
+	return organisationId
+}
+
+// GetAuthOrganisationRole returns the role of the authenticated account in its organisation.
+func GetAuthOrganisationRole(c echo.Context) string {
+	organisationRole, _ := c.Get(AuthOrganisationRoleKey).(string)
+	return organisationRole
+}
+
 func FormatResponse(w http.ResponseWriter, response interface{}, code int) {
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	w.Header().Set("Content-Type", "application/json")
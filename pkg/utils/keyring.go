@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultKeyID identifies the key of tokens and ciphertexts written before key ids were introduced,
+// they carry no key id and are verified or decrypted with this key.
+const DefaultKeyID = "default"
+
+// Keyring holds every key that may still be used to verify or decrypt data, identified by key id.
+// Only the active key is used to sign or encrypt, so a new key can be introduced while the
+// previous ones keep working for a grace period.
+type Keyring struct {
+	activeID string
+	keys     map[string][]byte
+}
+
+func NewKeyring(activeID string, keys map[string]string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("keyring needs at least one key")
+	}
+
+	keyring := &Keyring{
+		activeID: activeID,
+		keys:     make(map[string][]byte, len(keys)),
+	}
+	for id, secret := range keys {
+		if id == "" || strings.ContainsAny(id, ":$, ") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		if secret == "" {
+			return nil, fmt.Errorf("key %q is empty", id)
+		}
+		keyring.keys[id] = []byte(secret)
+	}
+
+	if _, ok := keyring.keys[activeID]; !ok {
+		return nil, fmt.Errorf("active key %q is not in the keyring", activeID)
+	}
+	return keyring, nil
+}
+
+// Active returns the id and secret of the key used to sign and encrypt.
+func (k *Keyring) Active() (string, []byte) {
+	return k.activeID, k.keys[k.activeID]
+}
+
+func (k *Keyring) ActiveID() string {
+	return k.activeID
+}
+
+// Key returns the secret of the given key id, an empty id refers to DefaultKeyID.
+func (k *Keyring) Key(id string) ([]byte, error) {
+	if id == "" {
+		id = DefaultKeyID
+	}
+	secret, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", id)
+	}
+	return secret, nil
+}
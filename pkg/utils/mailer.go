@@ -3,12 +3,35 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"html"
 	"net/mail"
+	"time"
 
 	"github.com/pocketbase/pocketbase/models"
 	"github.com/pocketbase/pocketbase/tools/mailer"
 )
 
+// MailSettings hold the frontend url used in email links and the sender of every email.
+type MailSettings struct {
+	FrontendURL   string
+	SenderAddress string
+	SenderName    string
+}
+
+// mailSettings is set from the configuration at boot.
+var mailSettings MailSettings
+
+func SetMailSettings(settings MailSettings) {
+	mailSettings = settings
+}
+
+func sender() mail.Address {
+	return mail.Address{
+		Name:    mailSettings.SenderName,
+		Address: mailSettings.SenderAddress,
+	}
+}
+
 var monthMap = map[int]string{
 	1:  "January",
 	2:  "February",
@@ -24,18 +47,16 @@ var monthMap = map[int]string{
 	12: "December",
 }
 
-func SendVerifyAccountHealthSpecialistEmail(mailClient mailer.Mailer, toName string, toEmail string) error {
+func SendVerifyAccountEmail(mailClient mailer.Mailer, toName string, toEmail string) error {
 	token, err := GenerateVerificationToken(toEmail)
 	if err != nil {
 		return errors.New("Failed to generate verification token")
 	}
 
-	verificationLink := "http://localhost:3000/confirmation/" + token
+	verificationLink := mailSettings.FrontendURL + "/confirmation/" + token
 
 	return mailClient.Send(&mailer.Message{
-		From: mail.Address{
-			Address: "hello@noreply.com",
-		},
+		From:    sender(),
 		To:      []mail.Address{{Name: toName, Address: toEmail}},
 		Subject: "Email Verification",
 		HTML: fmt.Sprintf(`
@@ -53,35 +74,252 @@ func SendVerifyAccountHealthSpecialistEmail(mailClient mailer.Mailer, toName str
 	})
 }
 
-func SendVerifyAccountPatientEmail(mailClient mailer.Mailer, toName string, toEmail string, oldPassword string, id string) error {
-	token, err := GeneratePatientVerificationToken(toEmail, oldPassword, id)
-	if err != nil {
-		return errors.New("Failed to generate verification token")
-	}
+func SendPatientInviteEmail(mailClient mailer.Mailer, toName string, toEmail string, token string) error {
+	inviteLink := mailSettings.FrontendURL + "/invite/" + token
 
-	verificationLink := "http://localhost:3000/patientConfirmation/" + token
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: "You have been invited to WellnessWave",
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>Your practitioner invited you to join them on WellnessWave.</p>
+			<p>Click on the button below to choose your password and activate your account. The link expires in 7 days and can only be used once.</p>
+			<p>
+			<a class="btn" href="%s" target="_blank" rel="noopener">Accept invitation</a>
+			</p>
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, inviteLink),
+	})
+}
+
+func SendPasswordResetEmail(mailClient mailer.Mailer, toName string, toEmail string, token string) error {
+	resetLink := mailSettings.FrontendURL + "/resetPassword/" + token
 
 	return mailClient.Send(&mailer.Message{
-		From: mail.Address{
-			Address: "hello@noreply.com",
-		},
+		From:    sender(),
 		To:      []mail.Address{{Name: toName, Address: toEmail}},
-		Subject: "Email Verification",
+		Subject: "Reset your password",
 		HTML: fmt.Sprintf(`
 			<p>Hello,</p>
-			<p>Thank you for joining us at WellnessWave.</p>
-			<p>Click on the button below to verify your email address.</p>
-			<p>This is your current randomly generated password </p>
-			<p>%s</p> 
-			<p>You will be asked to change it during the verification process</p>
+			<p>We received a request to reset the password of your WellnessWave account.</p>
+			<p>Click on the button below to choose a new password. The link expires in one hour and can only be used once.</p>
 			<p>
-			<a class="btn" href="%s" target="_blank" rel="noopener">Verify</a>
+			<a class="btn" href="%s" target="_blank" rel="noopener">Reset password</a>
 			</p>
+			<p>If you did not ask for a password reset you can safely ignore this email.</p>
 			<p>
 			Thanks,<br/>
 			WellnessWave team
 			</p>
-		`, oldPassword, verificationLink),
+		`, resetLink),
+	})
+}
+
+func SendAccountLockedEmail(mailClient mailer.Mailer, toName string, toEmail string, token string) error {
+	unlockLink := mailSettings.FrontendURL + "/unlock/" + token
+
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: "Your account has been locked",
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>Your WellnessWave account has been temporarily locked after several failed attempts to log in.</p>
+			<p>If these attempts were yours, click on the button below to unlock your account now. The link expires in 24 hours and can only be used once.</p>
+			<p>
+			<a class="btn" href="%s" target="_blank" rel="noopener">Unlock account</a>
+			</p>
+			<p>If you did not try to log in, someone may be guessing your password. We recommend you reset it.</p>
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, unlockLink),
+	})
+}
+
+func SendAccountDeletionEmail(mailClient mailer.Mailer, toName string, toEmail string, token string, eraseAfter time.Time) error {
+	cancelLink := mailSettings.FrontendURL + "/cancelDeletion/" + token
+
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: "Your account will be deleted",
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>We received a request to delete your WellnessWave account. You have been logged out of every device.</p>
+			<p>Your account and your health data will be erased for good on the %d of %s %d. Until then, click on the button below to keep your account.</p>
+			<p>
+			<a class="btn" href="%s" target="_blank" rel="noopener">Keep my account</a>
+			</p>
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, eraseAfter.Day(), monthMap[int(eraseAfter.Month())], eraseAfter.Year(), cancelLink),
+	})
+}
+
+// SendTransferRequestEmail asks the patient to consent to being transferred to another health specialist.
+func SendTransferRequestEmail(mailClient mailer.Mailer, toName string, toEmail string, fromName string, toSpecialistName string, transferId string) error {
+	transferLink := mailSettings.FrontendURL + "/transfers/" + transferId
+
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: "Your practitioner would like to transfer you",
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>%s would like %s to follow you from now on.</p>
+			<p>Click on the button below to accept or decline the transfer. Nothing changes until you accept it, the request expires in 7 days.</p>
+			<p>
+			<a class="btn" href="%s" target="_blank" rel="noopener">Review the transfer</a>
+			</p>
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, html.EscapeString(fromName), html.EscapeString(toSpecialistName), transferLink),
+	})
+}
+
+// SendTransferDeclinedEmail tells the health specialist who requested a transfer that the patient declined it.
+func SendTransferDeclinedEmail(mailClient mailer.Mailer, toName string, toEmail string, patientName string) error {
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: "Transfer declined",
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>%s declined to be transferred and stays your patient.</p>
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, html.EscapeString(patientName)),
+	})
+}
+
+// SendPatientTransferredEmail tells the patient and both health specialists that the transfer took effect.
+func SendPatientTransferredEmail(mailClient mailer.Mailer, toName string, toEmail string, patientName string, fromName string, toSpecialistName string) error {
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: "Patient transferred",
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>%s has been transferred from %s to %s.</p>
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, html.EscapeString(patientName), html.EscapeString(fromName), html.EscapeString(toSpecialistName)),
+	})
+}
+
+// SendPatientDetachedEmail tells the patient and the health specialist that they are no longer attached.
+func SendPatientDetachedEmail(mailClient mailer.Mailer, toName string, toEmail string, patientName string, specialistName string) error {
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: "Patient detached",
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>%s is no longer followed by %s on WellnessWave.</p>
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, html.EscapeString(patientName), html.EscapeString(specialistName)),
+	})
+}
+
+// SendOrganisationInvitationEmail asks a health specialist to join an organisation.
+func SendOrganisationInvitationEmail(mailClient mailer.Mailer, toName string, toEmail string, organisationName string, invitationId string) error {
+	invitationLink := mailSettings.FrontendURL + "/organisation-invitations/" + invitationId
+
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: "You are invited to join an organisation",
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>%s invited you to join their organisation on WellnessWave.</p>
+			<p>Once you accept, your patients, events, meals, exercises and plans are shared with the organisation and its admins can see your schedule. The invitation expires in 7 days.</p>
+			<p>
+			<a class="btn" href="%s" target="_blank" rel="noopener">Review the invitation</a>
+			</p>
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, html.EscapeString(organisationName), invitationLink),
+	})
+}
+
+// SendAttachRequestEmail asks a health specialist to follow a patient who requested them.
+func SendAttachRequestEmail(mailClient mailer.Mailer, toName string, toEmail string, patientName string, requestId string) error {
+	requestLink := mailSettings.FrontendURL + "/attach-requests/" + requestId
+
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: "A patient would like you to follow them",
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>%s would like you to follow them on WellnessWave.</p>
+			<p>Click on the button below to accept or decline the request, it expires in 14 days.</p>
+			<p>
+			<a class="btn" href="%s" target="_blank" rel="noopener">Review the request</a>
+			</p>
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, html.EscapeString(patientName), requestLink),
+	})
+}
+
+// SendAttachRequestDecidedEmail tells the patient whether the health specialist accepted to follow them.
+func SendAttachRequestDecidedEmail(mailClient mailer.Mailer, toName string, toEmail string, specialistName string, accepted bool) error {
+	subject, answer := "Request declined", "declined to follow you. You can request another specialist from the directory."
+	if accepted {
+		subject, answer = "Request accepted", "accepted to follow you and is now part of your care team."
+	}
+
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: subject,
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>%s %s</p>
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, html.EscapeString(specialistName), answer),
+	})
+}
+
+// SendAttachRequestCancelledEmail tells the health specialist that the patient withdrew their request.
+func SendAttachRequestCancelledEmail(mailClient mailer.Mailer, toName string, toEmail string, patientName string) error {
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: "Request withdrawn",
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>%s withdrew their request to be followed by you.</p>
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, html.EscapeString(patientName)),
 	})
 }
 
@@ -90,9 +328,7 @@ func SendEventEmailToPatient(mailClient mailer.Mailer, toName string, toEmail st
 	day, month, year, hour, minute := dateAndTime.Day(), dateAndTime.Month(), dateAndTime.Year(), dateAndTime.Hour(), dateAndTime.Minute()
 
 	return mailClient.Send(&mailer.Message{
-		From: mail.Address{
-			Address: "hello@noreply.com",
-		},
+		From:    sender(),
 		To:      []mail.Address{{Name: toName, Address: toEmail}},
 		Subject: "Event Reminder",
 		HTML: fmt.Sprintf(`
@@ -111,9 +347,7 @@ func SendRescheduleEventEmailToPatient(mailClient mailer.Mailer, toName string,
 	day, month, year, hour, minute := dateAndTime.Day(), dateAndTime.Month(), dateAndTime.Year(), dateAndTime.Hour(), dateAndTime.Minute()
 
 	return mailClient.Send(&mailer.Message{
-		From: mail.Address{
-			Address: "hello@noreply.com",
-		},
+		From:    sender(),
 		To:      []mail.Address{{Name: toName, Address: toEmail}},
 		Subject: "Event Reminder",
 		HTML: fmt.Sprintf(`
@@ -126,3 +360,87 @@ func SendRescheduleEventEmailToPatient(mailClient mailer.Mailer, toName string,
 		`, day, monthMap[int(month)], year, hour, minute),
 	})
 }
+
+// SendEventBookedEmail tells the health specialist that the patient booked an event, to confirm or decline if it needs their approval.
+func SendEventBookedEmail(mailClient mailer.Mailer, toName string, toEmail string, patientName string, eventRecord *models.Record, needsApproval bool) error {
+	subject, next := "Event booked", "It is now in your schedule."
+	if needsApproval {
+		subject, next = "Event requested", "Please confirm or decline it from your schedule."
+	}
+
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: subject,
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>%s booked an event with you on the %s.</p>
+			<p>%s</p>
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, html.EscapeString(patientName), eventDate(eventRecord), next),
+	})
+}
+
+// SendEventStatusEmail tells the patient that their practitioner confirmed, declined or cancelled the event,
+// or marked that they did not show up.
+func SendEventStatusEmail(mailClient mailer.Mailer, toName string, toEmail string, eventRecord *models.Record, status string) error {
+	var subject, change string
+	switch status {
+	case "confirmed":
+		subject, change = "Event confirmed", "Your practitioner confirmed your event on the %s. See you then!"
+	case "declined":
+		subject, change = "Event declined", "Your practitioner declined your event on the %s. You can book another slot from the app."
+	case "cancelled":
+		subject, change = "Event cancelled", "Your practitioner cancelled your event on the %s."
+	case "no_show":
+		subject, change = "Missed event", "Your practitioner noted that you missed your event on the %s."
+	default:
+		return fmt.Errorf("no email for event status %s", status)
+	}
+
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: subject,
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>%s</p>
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, fmt.Sprintf(change, eventDate(eventRecord))),
+	})
+}
+
+// SendEventCancelledByPatientEmail tells the health specialist that the patient cancelled the event, and whether it was late.
+func SendEventCancelledByPatientEmail(mailClient mailer.Mailer, toName string, toEmail string, patientName string, eventRecord *models.Record, late bool) error {
+	notice := ""
+	if late {
+		notice = "<p>It was cancelled within your cancellation notice and is flagged as a late cancellation.</p>"
+	}
+
+	return mailClient.Send(&mailer.Message{
+		From:    sender(),
+		To:      []mail.Address{{Name: toName, Address: toEmail}},
+		Subject: "Event cancelled",
+		HTML: fmt.Sprintf(`
+			<p>Hello,</p>
+			<p>%s cancelled their event with you on the %s.</p>
+			%s
+			<p>
+			Thanks,<br/>
+			WellnessWave team
+			</p>
+		`, html.EscapeString(patientName), eventDate(eventRecord), notice),
+	})
+}
+
+// eventDate formats the date of the event for emails, e.g. "3 of March 2025 at 09:30".
+func eventDate(eventRecord *models.Record) string {
+	dateAndTime := eventRecord.GetDateTime("event_date").Time()
+	return fmt.Sprintf("%d of %s %d at %02d:%02d", dateAndTime.Day(), monthMap[int(dateAndTime.Month())], dateAndTime.Year(), dateAndTime.Hour(), dateAndTime.Minute())
+}
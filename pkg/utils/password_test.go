@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordHashing(t *testing.T) {
+	t.Run("hash verifies the original password only", func(t *testing.T) {
+		hasher := NewPasswordHasher()
+		hash, err := hasher.Hash("Sup3r-secret!")
+		assert.Nil(t, err, "hashing should not error")
+		assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$"))
+
+		valid, err := hasher.Verify("Sup3r-secret!", hash)
+		assert.Nil(t, err)
+		assert.True(t, valid)
+
+		valid, err = hasher.Verify("wrong-password", hash)
+		assert.Nil(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("same password hashes differently each time", func(t *testing.T) {
+		hasher := NewPasswordHasher()
+		hash1, _ := hasher.Hash("Sup3r-secret!")
+		hash2, _ := hasher.Hash("Sup3r-secret!")
+		assert.NotEqual(t, hash1, hash2)
+	})
+
+	t.Run("hashes made with older parameters still verify but need a rehash", func(t *testing.T) {
+		oldHasher := &Argon2idHasher{Params: Argon2idParams{Memory: 32 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}}
+		hash, err := oldHasher.Hash("Sup3r-secret!")
+		assert.Nil(t, err)
+
+		hasher := NewPasswordHasher()
+		valid, err := hasher.Verify("Sup3r-secret!", hash)
+		assert.Nil(t, err)
+		assert.True(t, valid)
+		assert.True(t, hasher.NeedsRehash(hash))
+		assert.False(t, oldHasher.NeedsRehash(hash))
+	})
+
+	t.Run("malformed hashes are rejected", func(t *testing.T) {
+		_, err := NewPasswordHasher().Verify("Sup3r-secret!", "not-a-hash")
+		assert.NotNil(t, err)
+	})
+}
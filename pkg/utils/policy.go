@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+// Policy decides whether the authenticated caller may access a route.
+// Policies are evaluated by Authorize, after AuthMiddleware has run.
+type Policy func(c echo.Context) (bool, error)
+
+// ParamExtractor reads a single value out of the incoming request.
+type ParamExtractor func(c echo.Context) string
+
+func QueryParam(name string) ParamExtractor {
+	return func(c echo.Context) string {
+		return c.QueryParam(name)
+	}
+}
+
+func PathParam(name string) ParamExtractor {
+	return func(c echo.Context) string {
+		return c.PathParam(name)
+	}
+}
+
+// BodyField reads a top level field of the request body without consuming it,
+// so the handler can still bind the body afterwards.
+func BodyField(name string) ParamExtractor {
+	return func(c echo.Context) string {
+		value, ok := apis.RequestInfo(c).Data[name]
+		if !ok || value == nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}
+}
+
+// AuthAccount extracts the id of the account authenticated by AuthMiddleware.
+func AuthAccount() ParamExtractor {
+	return func(c echo.Context) string {
+		return GetAuthAccountId(c)
+	}
+}
+
+// HasRole allows callers authenticated with one of the given roles.
+func HasRole(roles ...string) Policy {
+	return func(c echo.Context) (bool, error) {
+		role := GetAuthRole(c)
+		for _, r := range roles {
+			if r == role {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// IsSelf allows callers whose account id matches the extracted value.
+func IsSelf(id ParamExtractor) Policy {
+	return func(c echo.Context) (bool, error) {
+		value := id(c)
+		return value != "" && value == GetAuthAccountId(c), nil
+	}
+}
+
+// InOrganisation allows the members of the extracted organisation, restricted to the given
+// organisation roles if any.
+func InOrganisation(organisationId ParamExtractor, organisationRoles ...string) Policy {
+	return func(c echo.Context) (bool, error) {
+		value := organisationId(c)
+		if value == "" || value != GetAuthOrganisationId(c) {
+			return false, nil
+		}
+		if len(organisationRoles) == 0 {
+			return true, nil
+		}
+		for _, role := range organisationRoles {
+			if role == GetAuthOrganisationRole(c) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// AllOf allows the request only if every policy allows it.
+func AllOf(policies ...Policy) Policy {
+	return func(c echo.Context) (bool, error) {
+		for _, p := range policies {
+			allowed, err := p(c)
+			if err != nil || !allowed {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// AnyOf allows the request as soon as one of the policies allows it.
+func AnyOf(policies ...Policy) Policy {
+	return func(c echo.Context) (bool, error) {
+		for _, p := range policies {
+			allowed, err := p(c)
+			if err != nil {
+				return false, err
+			}
+			if allowed {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// IfPresent applies the policy only when the extracted value is set. It is meant for routes
+// accepting alternative parameters, so that every parameter supplied by the caller is checked.
+func IfPresent(param ParamExtractor, policy Policy) Policy {
+	return func(c echo.Context) (bool, error) {
+		if param(c) == "" {
+			return true, nil
+		}
+		return policy(c)
+	}
+}
+
+// Authorize enforces the given policy on a route and answers 403 when the caller is denied.
+func Authorize(policy Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			allowed, err := policy(c)
+			if err != nil {
+				return apis.NewApiError(http.StatusInternalServerError, fmt.Sprintf("failed to evaluate access policy: %s", err.Error()), nil)
+			}
+			if !allowed {
+				return apis.NewForbiddenError("forbidden", nil)
+			}
+			return next(c)
+		}
+	}
+}
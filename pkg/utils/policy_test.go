@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPolicyContext(target string, accountId string, role string) echo.Context {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+	c.Set(AuthAccountIdKey, accountId)
+	c.Set(AuthRoleKey, role)
+	return c
+}
+
+func TestPolicies(t *testing.T) {
+	t.Run("is self allows only the matching account", func(t *testing.T) {
+		policy := IsSelf(QueryParam("patientId"))
+
+		allowed, err := policy(newPolicyContext("/?patientId=abc", "abc", "PATIENT"))
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+
+		allowed, err = policy(newPolicyContext("/?patientId=xyz", "abc", "PATIENT"))
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+
+		allowed, err = policy(newPolicyContext("/", "", "PATIENT"))
+		assert.Nil(t, err)
+		assert.False(t, allowed, "empty values should never match")
+	})
+
+	t.Run("has role checks the authenticated role", func(t *testing.T) {
+		allowed, _ := HasRole("HEALTH_SPECIALIST")(newPolicyContext("/", "abc", "HEALTH_SPECIALIST"))
+		assert.True(t, allowed)
+
+		allowed, _ = HasRole("HEALTH_SPECIALIST")(newPolicyContext("/", "abc", "PATIENT"))
+		assert.False(t, allowed)
+	})
+
+	t.Run("every supplied parameter must be authorized", func(t *testing.T) {
+		policy := AllOf(
+			IfPresent(QueryParam("healthSpecialistId"), IsSelf(QueryParam("healthSpecialistId"))),
+			IfPresent(QueryParam("patientId"), IsSelf(QueryParam("patientId"))),
+		)
+
+		allowed, _ := policy(newPolicyContext("/?healthSpecialistId=abc", "abc", "HEALTH_SPECIALIST"))
+		assert.True(t, allowed)
+
+		allowed, _ = policy(newPolicyContext("/?healthSpecialistId=abc&patientId=victim", "abc", "HEALTH_SPECIALIST"))
+		assert.False(t, allowed)
+	})
+
+	t.Run("in organisation allows only members with the given roles", func(t *testing.T) {
+		c := newPolicyContext("/?organisationId=org-1", "abc", "HEALTH_SPECIALIST")
+		c.Set(AuthOrganisationIdKey, "org-1")
+		c.Set(AuthOrganisationRoleKey, "member")
+
+		allowed, _ := InOrganisation(QueryParam("organisationId"))(c)
+		assert.True(t, allowed)
+
+		allowed, _ = InOrganisation(QueryParam("organisationId"), "admin")(c)
+		assert.False(t, allowed)
+
+		allowed, _ = InOrganisation(QueryParam("organisationId"))(newPolicyContext("/?organisationId=org-1", "abc", "HEALTH_SPECIALIST"))
+		assert.False(t, allowed)
+
+		allowed, _ = InOrganisation(QueryParam("organisationId"))(newPolicyContext("/", "abc", "HEALTH_SPECIALIST"))
+		assert.False(t, allowed, "accounts outside of any organisation should not match an empty id")
+	})
+
+	t.Run("authorize answers 403 when denied", func(t *testing.T) {
+		handler := Authorize(HasRole("HEALTH_SPECIALIST"))(func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+
+		err := handler(newPolicyContext("/", "abc", "PATIENT"))
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "Forbidden")
+	})
+}
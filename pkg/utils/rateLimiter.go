@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter is an in memory sliding window limiter allowing a number of hits per key within a window.
+type RateLimiter struct {
+	limit     int
+	window    time.Duration
+	hits      map[string][]time.Time
+	lastSweep time.Time
+	mux       sync.Mutex
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:     limit,
+		window:    window,
+		hits:      make(map[string][]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow records a hit for the key and reports whether it is still within the limit.
+func (l *RateLimiter) Allow(key string) bool {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	recent := l.recentHits(key, now)
+	if len(recent) >= l.limit {
+		l.hits[key] = recent
+		return false
+	}
+
+	l.hits[key] = append(recent, now)
+	return true
+}
+
+func (l *RateLimiter) recentHits(key string, now time.Time) []time.Time {
+	var recent []time.Time
+	for _, hit := range l.hits[key] {
+		if now.Sub(hit) < l.window {
+			recent = append(recent, hit)
+		}
+	}
+	return recent
+}
+
+// sweep drops the keys without recent hits once per window so the map does not grow unbounded.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	for key := range l.hits {
+		if len(l.recentHits(key, now)) == 0 {
+			delete(l.hits, key)
+		}
+	}
+	l.lastSweep = now
+}
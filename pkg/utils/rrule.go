@@ -0,0 +1,270 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies of the recurrence rules supported.
+const (
+	DailyFrequency   = "DAILY"
+	WeeklyFrequency  = "WEEKLY"
+	MonthlyFrequency = "MONTHLY"
+)
+
+// MaxRRuleIterations bounds the candidates generated for a rule, so a rule never expands forever.
+const MaxRRuleIterations = 10000
+
+const (
+	rruleUntilLayout = "20060102T150405Z"
+	rruleDateLayout  = "20060102"
+)
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// RRule is a recurrence rule as defined by RFC 5545, limited to daily, weekly and monthly frequencies
+// with an interval, a count or an until date and, for weekly rules, the days of the week.
+// Occurrences are computed in the location of the start of the series, weeks starting on Monday.
+type RRule struct {
+	Freq     string
+	Interval int
+	Count    int
+	Until    time.Time
+	ByDay    []time.Weekday
+}
+
+// ParseRRule parses a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10", with or without the "RRULE:" prefix.
+func ParseRRule(value string) (*RRule, error) {
+	rule := &RRule{Interval: 1}
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, errors.New("invalid_rrule: empty")
+	}
+
+	for _, part := range strings.Split(value, ";") {
+		name, val, found := strings.Cut(part, "=")
+		if !found || val == "" {
+			return nil, fmt.Errorf("invalid_rrule: %s", part)
+		}
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(val)
+			if rule.Freq != DailyFrequency && rule.Freq != WeeklyFrequency && rule.Freq != MonthlyFrequency {
+				return nil, fmt.Errorf("invalid_rrule: unsupported FREQ %s", val)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 {
+				return nil, errors.New("invalid_rrule: INTERVAL")
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return nil, errors.New("invalid_rrule: COUNT")
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseRRuleUntil(val)
+			if err != nil {
+				return nil, errors.New("invalid_rrule: UNTIL")
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(val), ",") {
+				weekday, ok := rruleWeekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("invalid_rrule: unsupported BYDAY %s", day)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "WKST":
+			if strings.ToUpper(val) != "MO" {
+				return nil, errors.New("invalid_rrule: unsupported WKST")
+			}
+		default:
+			return nil, fmt.Errorf("invalid_rrule: unsupported %s", name)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("invalid_rrule: missing FREQ")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, errors.New("invalid_rrule: COUNT and UNTIL")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != WeeklyFrequency {
+		return nil, errors.New("invalid_rrule: BYDAY is only supported for weekly rules")
+	}
+	sort.Slice(rule.ByDay, func(i, j int) bool { return mondayOffset(rule.ByDay[i]) < mondayOffset(rule.ByDay[j]) })
+	return rule, nil
+}
+
+func parseRRuleUntil(value string) (time.Time, error) {
+	if until, err := time.Parse(rruleUntilLayout, value); err == nil {
+		return until, nil
+	}
+	date, err := time.Parse(rruleDateLayout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// a date includes the whole day
+	return date.Add(24*time.Hour - time.Second), nil
+}
+
+// String formats the rule back, without the "RRULE:" prefix.
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(rruleUntilLayout))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, weekday := range r.ByDay {
+			days = append(days, strings.ToUpper(weekday.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between returns the occurrences of the series starting at dtstart that start from after, included,
+// to before, excluded, leaving out the excluded dates.
+func (r *RRule) Between(dtstart time.Time, after time.Time, before time.Time, exdates []time.Time) []time.Time {
+	occurrences := []time.Time{}
+	r.each(dtstart, func(occurrence time.Time) bool {
+		if !occurrence.Before(before) {
+			return false
+		}
+		if !occurrence.Before(after) && !isExcluded(occurrence, exdates) {
+			occurrences = append(occurrences, occurrence)
+		}
+		return true
+	})
+	return occurrences
+}
+
+// Includes reports whether the series starting at dtstart has an occurrence starting at the time,
+// excluded dates included.
+func (r *RRule) Includes(dtstart time.Time, at time.Time) bool {
+	found := false
+	r.each(dtstart, func(occurrence time.Time) bool {
+		found = occurrence.Equal(at)
+		return occurrence.Before(at)
+	})
+	return found
+}
+
+// Next returns the first occurrence of the series starting at dtstart that starts after the time, excluded
+// dates included, and false if the series ends before.
+func (r *RRule) Next(dtstart time.Time, after time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	r.each(dtstart, func(occurrence time.Time) bool {
+		next, found = occurrence, occurrence.After(after)
+		return !found
+	})
+	return next, found
+}
+
+// CountBefore returns how many occurrences of the series starting at dtstart start before the time,
+// excluded dates included as they still count towards COUNT.
+func (r *RRule) CountBefore(dtstart time.Time, at time.Time) int {
+	count := 0
+	r.each(dtstart, func(occurrence time.Time) bool {
+		if !occurrence.Before(at) {
+			return false
+		}
+		count++
+		return true
+	})
+	return count
+}
+
+// each calls fn with the occurrences of the series in order until fn returns false or the series ends.
+func (r *RRule) each(dtstart time.Time, fn func(time.Time) bool) {
+	emitted := 0
+	emit := func(occurrence time.Time) bool {
+		if r.Count > 0 && emitted >= r.Count {
+			return false
+		}
+		if !r.Until.IsZero() && occurrence.After(r.Until) {
+			return false
+		}
+		emitted++
+		return fn(occurrence)
+	}
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch r.Freq {
+	case DailyFrequency:
+		for i := 0; i < MaxRRuleIterations; i++ {
+			if !emit(dtstart.AddDate(0, 0, i*interval)) {
+				return
+			}
+		}
+	case WeeklyFrequency:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{dtstart.Weekday()}
+		}
+		weekStart := dtstart.AddDate(0, 0, -mondayOffset(dtstart.Weekday()))
+		for i := 0; i < MaxRRuleIterations; i++ {
+			week := weekStart.AddDate(0, 0, 7*i*interval)
+			for _, day := range days {
+				occurrence := week.AddDate(0, 0, mondayOffset(day))
+				if occurrence.Before(dtstart) {
+					continue
+				}
+				if !emit(occurrence) {
+					return
+				}
+			}
+		}
+	case MonthlyFrequency:
+		for i := 0; i < MaxRRuleIterations; i++ {
+			occurrence := dtstart.AddDate(0, i*interval, 0)
+			// months without the day of the month of the start have no occurrence
+			if occurrence.Day() != dtstart.Day() {
+				continue
+			}
+			if !emit(occurrence) {
+				return
+			}
+		}
+	}
+}
+
+func mondayOffset(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
+
+func isExcluded(occurrence time.Time, exdates []time.Time) bool {
+	for _, exdate := range exdates {
+		if occurrence.Equal(exdate) {
+			return true
+		}
+	}
+	return false
+}
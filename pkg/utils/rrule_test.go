@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRRule(t *testing.T) {
+	rule, err := ParseRRule("RRULE:FREQ=weekly;INTERVAL=2;BYDAY=TH,MO;COUNT=4")
+	assert.Nil(t, err)
+	assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=MO,TH", rule.String())
+
+	rule, err = ParseRRule("FREQ=DAILY;UNTIL=20250310")
+	assert.Nil(t, err)
+	assert.Equal(t, "FREQ=DAILY;UNTIL=20250310T235959Z", rule.String())
+
+	for _, value := range []string{"", "INTERVAL=2", "FREQ=HOURLY", "FREQ=DAILY;COUNT=0", "FREQ=DAILY;COUNT=2;UNTIL=20250310", "FREQ=MONTHLY;BYDAY=MO", "FREQ=WEEKLY;BYDAY=1MO", "FREQ=WEEKLY;BYSETPOS=1"} {
+		_, err := ParseRRule(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+func TestRRuleBetween(t *testing.T) {
+	// Monday 3 March 2025
+	start := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 9, 0, 0, 0, time.UTC) }
+
+	rule, _ := ParseRRule("FREQ=WEEKLY;BYDAY=MO,TH;COUNT=5")
+	assert.Equal(t, []time.Time{day(3), day(6), day(10), day(13), day(17)}, rule.Between(start, start, day(31), nil))
+	assert.Equal(t, []time.Time{day(10), day(17)}, rule.Between(start, day(7), day(31), []time.Time{day(13)}), "excluded dates still count towards COUNT")
+	assert.True(t, rule.Includes(start, day(13)))
+	assert.False(t, rule.Includes(start, day(20)))
+	assert.Equal(t, 3, rule.CountBefore(start, day(13)))
+	next, found := rule.Next(start, day(10))
+	assert.True(t, found)
+	assert.Equal(t, day(13), next, "the occurrence starting at the time is not after it")
+	_, found = rule.Next(start, day(17))
+	assert.False(t, found, "the series ended")
+
+	rule, _ = ParseRRule("FREQ=DAILY;INTERVAL=3;UNTIL=20250309T090000Z")
+	assert.Equal(t, []time.Time{day(3), day(6), day(9)}, rule.Between(start, start, day(31), nil))
+
+	rule, _ = ParseRRule("FREQ=MONTHLY;COUNT=3")
+	monthEnd := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, []time.Time{monthEnd, time.Date(2025, 3, 31, 9, 0, 0, 0, time.UTC), time.Date(2025, 5, 31, 9, 0, 0, 0, time.UTC)},
+		rule.Between(monthEnd, monthEnd, monthEnd.AddDate(1, 0, 0), nil), "months without the day are skipped")
+
+	rule, _ = ParseRRule("FREQ=WEEKLY")
+	assert.Len(t, rule.Between(start, start, start.AddDate(0, 0, 70), nil), 10, "rules without an end are bounded by the window")
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// TenantField is the field holding the organisation a record belongs to, it is empty for the
+// records of accounts outside of any organisation.
+const TenantField = "organisation_id"
+
+// AuthRelatedAccountsKey caches the accounts related to the authenticated account for the request.
+const AuthRelatedAccountsKey = "authRelatedAccounts"
+
+// TenantResolver looks up what scoping the requests of an account needs from the database.
+type TenantResolver interface {
+	// Tenant returns the current organisation of the account and its role in it, as the ones carried
+	// by access tokens issued before the account joined or left an organisation are outdated.
+	Tenant(accountId string) (Tenant, error)
+	// RelatedAccounts returns the accounts sharing a care team with the account.
+	RelatedAccounts(accountId string) ([]string, error)
+}
+
+// tenantResolver is set from the repositories at boot, without one the organisation of the access
+// token is trusted and accounts outside of any organisation only see their own records.
+var tenantResolver TenantResolver
+
+func SetTenantResolver(resolver TenantResolver) {
+	tenantResolver = resolver
+}
+
+// TenantScope returns the organisation the queries of the request are restricted to, and whether
+// they are restricted at all. Requests made on behalf of an authenticated account are always scoped,
+// public requests and background jobs, which have no context, are not.
+//
+// Accounts outside of any organisation do not share a tenant with each other: their requests are
+// restricted to the records without an organisation owned by them or by the accounts sharing a care
+// team with them, the owner fields of the helpers below telling which accounts own a record.
+func TenantScope(c echo.Context) (string, bool) {
+	if c == nil || GetAuthAccountId(c) == "" {
+		return "", false
+	}
+	return GetAuthOrganisationId(c), true
+}
+
+// TenantFilter restricts a record filter to the tenant of the request, adding the tenant to params.
+func TenantFilter(c echo.Context, filter string, params dbx.Params, owners ...string) string {
+	organisationId, scoped := TenantScope(c)
+	if !scoped {
+		return filter
+	}
+	params["tenant"] = organisationId
+	if organisationId != "" {
+		return fmt.Sprintf("(%s) && %s = {:tenant}", filter, TenantField)
+	}
+
+	conditions := []string{}
+	for i, accountId := range relatedAccounts(c) {
+		param := fmt.Sprintf("tenant_owner%d", i)
+		params[param] = accountId
+		for _, owner := range owners {
+			conditions = append(conditions, fmt.Sprintf("%s = {:%s}", owner, param))
+		}
+	}
+	if len(conditions) == 0 {
+		// without owners no record can be told to be the caller's
+		conditions = append(conditions, "id = ''")
+	}
+	return fmt.Sprintf("(%s) && %s = {:tenant} && (%s)", filter, TenantField, strings.Join(conditions, " || "))
+}
+
+// TenantExp returns the condition restricting the given organisation column, and the owner columns
+// for accounts outside of any organisation, to the tenant of the request, or nil when the request
+// is not scoped.
+func TenantExp(c echo.Context, column string, owners ...string) dbx.Expression {
+	organisationId, scoped := TenantScope(c)
+	if !scoped {
+		return nil
+	}
+	if organisationId != "" {
+		return dbx.HashExp{column: organisationId}
+	}
+
+	accountIds := []interface{}{}
+	for _, accountId := range relatedAccounts(c) {
+		accountIds = append(accountIds, accountId)
+	}
+	conditions := []dbx.Expression{}
+	for _, owner := range owners {
+		conditions = append(conditions, dbx.In(owner, accountIds...))
+	}
+	if len(conditions) == 0 {
+		// without owners no record can be told to be the caller's
+		conditions = append(conditions, dbx.NewExp("0 = 1"))
+	}
+	return dbx.And(dbx.HashExp{column: ""}, dbx.Or(conditions...))
+}
+
+// CheckTenant fails with a not found error when the record belongs to another tenant than the one
+// of the request, so that records of other organisations cannot even be told apart from missing ones.
+func CheckTenant(c echo.Context, record *models.Record, owners ...string) error {
+	organisationId, scoped := TenantScope(c)
+	if !scoped {
+		return nil
+	}
+	if record.GetString(TenantField) != organisationId || (organisationId == "" && !isOwnedByRelated(c, record, owners)) {
+		return fmt.Errorf("record [%s] is out of tenant: %w", record.Id, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// StampTenant assigns a new record to the tenant of the request.
+func StampTenant(c echo.Context, record *models.Record) {
+	if organisationId, scoped := TenantScope(c); scoped {
+		record.Set(TenantField, organisationId)
+	}
+}
+
+func isOwnedByRelated(c echo.Context, record *models.Record, owners []string) bool {
+	for _, accountId := range relatedAccounts(c) {
+		for _, owner := range owners {
+			if record.GetString(owner) == accountId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// relatedAccounts returns the authenticated account and the accounts sharing a care team with it,
+// resolved once per request. It falls back to the account alone if they cannot be resolved.
+func relatedAccounts(c echo.Context) []string {
+	if accountIds, ok := c.Get(AuthRelatedAccountsKey).([]string); ok {
+		return accountIds
+	}
+
+	accountId := GetAuthAccountId(c)
+	accountIds := []string{accountId}
+	if tenantResolver != nil {
+		related, err := tenantResolver.RelatedAccounts(accountId)
+		if err != nil {
+			log.Printf("Failed to resolve the accounts related to account %s: %v", accountId, err)
+		} else {
+			accountIds = append(accountIds, related...)
+		}
+	}
+	c.Set(AuthRelatedAccountsKey, accountIds)
+	return accountIds
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubTenantResolver struct {
+	tenants map[string]Tenant
+	related map[string][]string
+}
+
+func (r stubTenantResolver) Tenant(accountId string) (Tenant, error) {
+	tenant, ok := r.tenants[accountId]
+	if !ok {
+		return tenant, sql.ErrNoRows
+	}
+	return tenant, nil
+}
+
+func (r stubTenantResolver) RelatedAccounts(accountId string) ([]string, error) {
+	return r.related[accountId], nil
+}
+
+func TestTenantScope(t *testing.T) {
+	t.Run("authenticated requests are scoped to their organisation", func(t *testing.T) {
+		c := newPolicyContext("/", "abc", "HEALTH_SPECIALIST")
+		c.Set(AuthOrganisationIdKey, "org-1")
+
+		params := dbx.Params{"patient_id": "p1"}
+		filter := TenantFilter(c, "patient_id = {:patient_id}", params)
+		assert.Equal(t, "(patient_id = {:patient_id}) && organisation_id = {:tenant}", filter)
+		assert.Equal(t, "org-1", params["tenant"])
+	})
+
+	t.Run("accounts outside of any organisation are scoped to the records without one", func(t *testing.T) {
+		c := newPolicyContext("/", "abc", "PATIENT")
+
+		organisationId, scoped := TenantScope(c)
+		assert.True(t, scoped)
+		assert.Equal(t, "", organisationId)
+	})
+
+	t.Run("public requests and background jobs are not scoped", func(t *testing.T) {
+		_, scoped := TenantScope(newPolicyContext("/", "", ""))
+		assert.False(t, scoped)
+		_, scoped = TenantScope(nil)
+		assert.False(t, scoped)
+		assert.Nil(t, TenantExp(nil, TenantField))
+	})
+
+	t.Run("records of other tenants are reported as not found", func(t *testing.T) {
+		c := newPolicyContext("/", "abc", "HEALTH_SPECIALIST")
+		c.Set(AuthOrganisationIdKey, "org-1")
+
+		record := models.NewRecord(&models.Collection{})
+		record.Set(TenantField, "org-2")
+		err := CheckTenant(c, record)
+		assert.NotNil(t, err)
+		assert.True(t, IsErrorNotFound(err))
+
+		record.Set(TenantField, "org-1")
+		assert.Nil(t, CheckTenant(c, record))
+	})
+
+	t.Run("accounts outside of any organisation only see the records of their care teams", func(t *testing.T) {
+		SetTenantResolver(stubTenantResolver{related: map[string][]string{"abc": {"p1"}}})
+		defer SetTenantResolver(nil)
+		c := newPolicyContext("/", "abc", "HEALTH_SPECIALIST")
+
+		params := dbx.Params{}
+		filter := TenantFilter(c, "status = 'confirmed'", params, "health_specialist_id", "patient_id")
+		assert.Equal(t, "(status = 'confirmed') && organisation_id = {:tenant} && (health_specialist_id = {:tenant_owner0} || patient_id = {:tenant_owner0} || health_specialist_id = {:tenant_owner1} || patient_id = {:tenant_owner1})", filter)
+		assert.Equal(t, dbx.Params{"tenant": "", "tenant_owner0": "abc", "tenant_owner1": "p1"}, params)
+
+		record := models.NewRecord(&models.Collection{})
+		record.Set("patient_id", "p1")
+		assert.Nil(t, CheckTenant(c, record, "health_specialist_id", "patient_id"))
+		assert.True(t, IsErrorNotFound(CheckTenant(c, record, "health_specialist_id")), "records are only visible through their owner fields")
+
+		record.Set("patient_id", "p2")
+		assert.True(t, IsErrorNotFound(CheckTenant(c, record, "health_specialist_id", "patient_id")), "accounts outside of any organisation do not share a tenant")
+
+		record.Set("patient_id", "p1")
+		record.Set(TenantField, "org-1")
+		assert.True(t, IsErrorNotFound(CheckTenant(c, record, "health_specialist_id", "patient_id")))
+	})
+
+	t.Run("the organisation of the account is resolved on every request", func(t *testing.T) {
+		keys, err := NewKeyring(DefaultKeyID, map[string]string{DefaultKeyID: "k8Jd2nVq9ZpL4xWc7RtY1bHm6FsA3eGu"})
+		assert.Nil(t, err)
+		SetJWTKeys(keys)
+		defer SetJWTKeys(nil)
+		SetTenantResolver(stubTenantResolver{tenants: map[string]Tenant{"abc": {OrganisationID: "org-2", OrganisationRole: "member"}}})
+		defer SetTenantResolver(nil)
+
+		authenticated := func(accountId string) (echo.Context, error) {
+			token, _, err := GenerateAccessToken(accountId, "HEALTH_SPECIALIST", Tenant{OrganisationID: "org-1", OrganisationRole: "admin"})
+			assert.Nil(t, err)
+			c := newPolicyContext("/", "", "")
+			c.Request().Header.Set("Authorization", "Bearer "+token)
+			return c, AuthMiddleware(func(c echo.Context) error { return nil })(c)
+		}
+
+		c, err := authenticated("abc")
+		assert.Nil(t, err)
+		assert.Equal(t, "org-2", GetAuthOrganisationId(c), "the organisation of the token is outdated once the account changed organisation")
+		assert.Equal(t, "member", GetAuthOrganisationRole(c))
+
+		_, err = authenticated("deleted")
+		assert.NotNil(t, err, "tokens of accounts that no longer exist are rejected")
+	})
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 appendix B test secret, the expected codes are the last 6 digits of the SHA1 vectors
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	t.Run("matches the RFC 6238 test vectors", func(t *testing.T) {
+		vectors := map[int64]string{
+			59:         "287082",
+			1111111109: "081804",
+			1234567890: "005924",
+			2000000000: "279037",
+		}
+		for unix, expected := range vectors {
+			code, err := TOTPCode(secret, time.Unix(unix, 0))
+			assert.Nil(t, err)
+			assert.Equal(t, expected, code)
+		}
+	})
+
+	t.Run("accepts codes of the adjacent periods only", func(t *testing.T) {
+		now := time.Unix(1111111109, 0)
+		previous, err := TOTPCode(secret, now.Add(-TOTPPeriod))
+		assert.Nil(t, err)
+		tooOld, err := TOTPCode(secret, now.Add(-3*TOTPPeriod))
+		assert.Nil(t, err)
+
+		step, ok := ValidateTOTP(secret, previous, now)
+		assert.True(t, ok)
+		assert.Equal(t, now.Unix()/30-1, step)
+
+		_, ok = ValidateTOTP(secret, tooOld, now)
+		assert.False(t, ok)
+		_, ok = ValidateTOTP(secret, "12345", now)
+		assert.False(t, ok)
+	})
+
+	t.Run("recovery codes are unique and normalized", func(t *testing.T) {
+		codes, err := GenerateRecoveryCodes(10)
+		assert.Nil(t, err)
+		assert.Len(t, codes, 10)
+
+		seen := map[string]bool{}
+		for _, code := range codes {
+			assert.Len(t, code, 11)
+			assert.False(t, seen[code])
+			seen[code] = true
+		}
+		assert.Equal(t, "abcde12345", NormalizeRecoveryCode(" ABCDE-12345 "))
+	})
+}